@@ -0,0 +1,337 @@
+package main
+
+import (
+	"client/service"
+	"client/utils"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command 客户端子命令
+type command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(ctx *context, args []string) error
+}
+
+// context 子命令的运行环境
+type context struct {
+	settings settings
+	out      io.Writer
+	json     bool
+}
+
+var commands = []command{
+	{"machine-code", "machine-code [-short]", "print this machine's code and how it is derived", runMachineCode},
+	{"activation-request", "activation-request [-project P] [-module M] [-type T] [-users N] [-out FILE]", "create an activation request to send to the vendor", runActivationRequest},
+	{"install", "install [-system] [-replace] [-force] <file>", "check a license file and install it for this user or system-wide", runInstall},
+	{"list", "list", "list the installed licenses and show which one is used", runList},
+	{"verify", "verify [file]", "verify the installed license (default command)", runVerify},
+	{"diagnose", "diagnose [file]", "explain which license check passes or fails", runDiagnose},
+}
+
+// errInvalid 许可证无效，结果已经输出，只设置退出码
+var errInvalid = errors.New("license is not valid")
+
+// parseFlags 解析子命令参数，位置参数的数量必须在 [min, max] 之间
+func parseFlags(fs *flag.FlagSet, args []string, min int, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		return fmt.Errorf("%s: unexpected arguments %q", fs.Name(), fs.Args())
+	}
+	return nil
+}
+
+func (ctx *context) printJSON(v interface{}) error {
+	enc := json.NewEncoder(ctx.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (ctx *context) printTable(headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(ctx.out, 0, 4, 2, ' ', 0)
+	if len(headers) > 0 {
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+/*
+ * licensePath 确定要检查的许可文件：命令行指定的文件，否则为搜索到的最佳有效许可文件；
+ * 没有有效许可文件时返回第一个搜索到的文件，以便说明它无效的原因
+ * @return: string: 许可文件路径
+ *			error: 没有找到任何许可文件时返回错误对象；否则为 nil
+ */
+func (ctx *context) licensePath(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() > 0 {
+		return fs.Arg(0), nil
+	}
+	selected, candidates, err := service.FindLicense(ctx.settings.Product, ctx.settings.LicensePath)
+	switch {
+	case selected != nil:
+		return selected.Path, nil
+	case len(candidates) > 0:
+		return candidates[0].Path, nil
+	}
+	return "", err
+}
+
+func (ctx *context) diagnoseOptions() service.DiagnoseOptions {
+	return service.DiagnoseOptions{
+		Product:         ctx.settings.Product,
+		ServerURL:       ctx.settings.ServerURL,
+		CheckRevocation: ctx.settings.CheckRevocation,
+		AllowOffline:    ctx.settings.AllowOffline,
+	}
+}
+
+func runMachineCode(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("machine-code", flag.ContinueOnError)
+	short := fs.Bool("short", false, "print only the machine code")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	fp, err := utils.MachineFingerprint()
+	if err != nil {
+		return err
+	}
+	switch {
+	case *short:
+		_, err := fmt.Fprintln(ctx.out, fp.MachineCode)
+		return err
+	case ctx.json:
+		return ctx.printJSON(fp)
+	}
+
+	if err := ctx.printTable(nil, [][]string{
+		{"machine code", fp.MachineCode},
+		{"derived as", fmt.Sprintf("first 32 hex digits of %s(%q)", fp.Algorithm, fp.HardwareAddr)},
+		{"digest", fp.Digest},
+		{"interface", fp.Interface},
+		{"MAC address", fp.HardwareAddr},
+		{"hostname", fp.Hostname + " (not used)"},
+		{"platform", fp.OS + "/" + fp.Arch + " (not used)"},
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.out)
+	rows := make([][]string, 0, len(fp.Interfaces))
+	for _, ni := range fp.Interfaces {
+		note := ""
+		switch {
+		case ni.Selected:
+			note = "used for the machine code"
+		case !ni.Up:
+			note = "down"
+		case ni.Loopback:
+			note = "loopback"
+		case len(ni.IPv4) == 0:
+			note = "no IPv4 address"
+		default:
+			note = "not first"
+		}
+		rows = append(rows, []string{ni.Name, ni.HardwareAddr, strings.Join(ni.IPv4, ","), note})
+	}
+	return ctx.printTable([]string{"INTERFACE", "MAC", "IPV4", "NOTE"}, rows)
+}
+
+func runActivationRequest(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("activation-request", flag.ContinueOnError)
+	project := fs.String("project", "", "project to request a license for")
+	module := fs.String("module", "", "module to request a license for")
+	licenseType := fs.String("type", "", "license type to request")
+	users := fs.Uint("users", 0, "number of users to request")
+	out := fs.String("out", "", "write the request to this file instead of standard output")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	req, err := service.NewActivationRequest(*project, *module, *licenseType, *users)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	if *out == "" {
+		_, err := ctx.out.Write(content)
+		return err
+	}
+	if err := ioutil.WriteFile(*out, content, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "activation request written to %s, send it to your vendor\n", *out)
+	return nil
+}
+
+func runInstall(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	system := fs.Bool("system", false, "install to the system directory ("+service.SystemLicenseDir(ctx.settings.Product)+") instead of the user directory")
+	replace := fs.Bool("replace", false, "remove the other licenses in the target directory once the new one is in place")
+	force := fs.Bool("force", false, "install even if the license is not valid for this machine (cannot be combined with -replace)")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	// 配置了 client.license_path 时安装到该文件，否则以 <许可证ID>.license 安装到用户或系统目录
+	opts := service.InstallOptions{Path: ctx.settings.LicensePath, Replace: *replace, Force: *force, Product: ctx.settings.Product}
+	switch {
+	case opts.Path != "":
+	case *system:
+		opts.Dir = service.SystemLicenseDir(ctx.settings.Product)
+	default:
+		dir, err := service.UserLicenseDir(ctx.settings.Product)
+		if err != nil {
+			return err
+		}
+		opts.Dir = dir
+	}
+	target, info, err := service.InstallLicense(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	if ctx.json {
+		return ctx.printJSON(struct {
+			Path    string               `json:"path"`
+			License *service.LicenseInfo `json:"license,omitempty"`
+		}{target, info})
+	}
+	if info == nil {
+		_, err := fmt.Fprintf(ctx.out, "installed %s (not valid for this machine)\n", target)
+		return err
+	}
+	_, err = fmt.Fprintf(ctx.out, "installed license %s to %s, expires on %s\n", info.Id, target, info.Expiration)
+	return err
+}
+
+func runList(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	locations := service.LicenseLocations(ctx.settings.Product, ctx.settings.LicensePath)
+	candidates := service.DiscoverLicenses(ctx.settings.Product, ctx.settings.LicensePath, time.Now())
+	if ctx.json {
+		return ctx.printJSON(struct {
+			Locations []service.LicenseLocation  `json:"locations"`
+			Licenses  []service.LicenseCandidate `json:"licenses"`
+		}{locations, candidates})
+	}
+
+	fmt.Fprintln(ctx.out, "Search order:")
+	for _, loc := range locations {
+		fmt.Fprintf(ctx.out, "  %-8s %s\n", loc.Source, loc.Path)
+	}
+	fmt.Fprintln(ctx.out)
+	if len(candidates) == 0 {
+		_, err := fmt.Fprintln(ctx.out, "no license installed")
+		return err
+	}
+
+	rows := make([][]string, 0, len(candidates))
+	for _, c := range candidates {
+		mark, id, expiration, status := "", "", "", "valid"
+		if c.Selected {
+			mark = "*"
+		}
+		if c.License != nil {
+			id, expiration = c.License.Id, c.License.Expiration
+		}
+		if !c.Valid {
+			status = c.Problem
+		}
+		rows = append(rows, []string{mark, c.Source, c.Path, id, expiration, status})
+	}
+	return ctx.printTable([]string{"", "SOURCE", "PATH", "ID", "EXPIRATION", "STATUS"}, rows)
+}
+
+func runVerify(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	path, err := ctx.licensePath(fs)
+	if err != nil {
+		return err
+	}
+	report := service.Diagnose(path, ctx.diagnoseOptions())
+	if ctx.json {
+		if err := ctx.printJSON(report); err != nil {
+			return err
+		}
+	} else if failure := report.FirstFailure(); failure != nil {
+		fmt.Fprintf(ctx.out, "license %s is not valid: %s check failed: %s\n", report.LicensePath, failure.Name, failure.Detail)
+		fmt.Fprintln(ctx.out, "run the diagnose command for the full report")
+	} else {
+		licensee := ""
+		if report.License.Licensee != "" {
+			licensee = " (licensed to " + report.License.Licensee + ")"
+		}
+		fmt.Fprintf(ctx.out, "license %s%s is valid, expires on %s\n", report.License.Id, licensee, report.License.Expiration)
+	}
+	if !report.Valid {
+		return errInvalid
+	}
+	return nil
+}
+
+func runDiagnose(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("diagnose", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	path, err := ctx.licensePath(fs)
+	if err != nil {
+		return err
+	}
+	report := service.Diagnose(path, ctx.diagnoseOptions())
+	if ctx.json {
+		if err := ctx.printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		if fs.NArg() == 0 && ctx.settings.LicensePath == "" {
+			fmt.Fprintf(ctx.out, "license %s (%d found, see the list command)\n\n", path, len(service.DiscoverLicenses(ctx.settings.Product, "", time.Now())))
+		}
+		rows := make([][]string, 0, len(report.Checks))
+		for _, c := range report.Checks {
+			rows = append(rows, []string{c.Name, strings.ToUpper(c.Status), c.Detail})
+			if c.Hint != "" {
+				rows = append(rows, []string{"", "", "hint: " + c.Hint})
+			}
+		}
+		if err := ctx.printTable([]string{"CHECK", "RESULT", "DETAIL"}, rows); err != nil {
+			return err
+		}
+		if failure := report.FirstFailure(); failure != nil {
+			fmt.Fprintf(ctx.out, "\nlicense is not valid: the %s check failed\n", failure.Name)
+		} else {
+			fmt.Fprintln(ctx.out, "\nlicense is valid")
+		}
+	}
+	if !report.Valid {
+		return errInvalid
+	}
+	return nil
+}
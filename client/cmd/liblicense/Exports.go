@@ -0,0 +1,236 @@
+//go:build cgo
+
+package main
+
+/*
+#include <stddef.h>
+#include <stdlib.h>
+
+void license_set_error(const char *msg);
+*/
+import "C"
+
+import (
+	"client/logger"
+	"client/service"
+	"client/utils"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// 与 license.h 中的返回码一致
+const (
+	licenseOK              = 0
+	licenseErrArgument     = -1
+	licenseErrNotFound     = -2
+	licenseErrFormat       = -3
+	licenseErrMachine      = -4
+	licenseErrExpired      = -5
+	licenseErrNoLicense    = -6
+	licenseErrUnknownField = -7
+	licenseErrInternal     = -8
+	licenseErrProduct      = -9
+	licenseErrSignature    = -10
+)
+
+// defaultProduct 未设置 LICENSE_CLIENT_PRODUCT 时搜索许可文件使用的产品名，与客户端配置 client.product 的默认值相同
+const defaultProduct = "license-tool"
+
+// checkCodes 诊断检查项失败时对应的返回码
+var checkCodes = map[string]C.int{
+	"machine code":   licenseErrInternal,
+	"file":           licenseErrNotFound,
+	"format":         licenseErrFormat,
+	"decode":         licenseErrMachine,
+	"content":        licenseErrFormat,
+	"signature":      licenseErrSignature,
+	"product":        licenseErrProduct,
+	"signature code": licenseErrMachine,
+	"expiration":     licenseErrExpired,
+}
+
+var (
+	mutex    sync.RWMutex
+	verified *service.LicenseInfo // 最近一次校验通过的许可证
+
+	trustedKeysErr error // LICENSE_VERIFICATION_PUBLIC_KEYS 无效时的错误
+)
+
+func init() {
+	// 宿主程序不需要库的日志输出
+	logger.SetLogger(nil)
+
+	// 可信的签名公钥，与客户端配置 verification.public_keys 相同；无效时每次校验都失败，而不是静默跳过签名检查
+	if err := service.SetTrustedKeys(os.Getenv("LICENSE_VERIFICATION_PUBLIC_KEYS")); err != nil {
+		trustedKeysErr = err
+	}
+}
+
+// fail 记录当前线程的错误信息并返回错误码
+func fail(code C.int, format string, args ...interface{}) C.int {
+	msg := C.CString(fmt.Sprintf(format, args...))
+	defer C.free(unsafe.Pointer(msg))
+	C.license_set_error(msg)
+	return code
+}
+
+// succeed 清除当前线程的错误信息
+func succeed(result C.int) C.int {
+	empty := C.CString("")
+	defer C.free(unsafe.Pointer(empty))
+	C.license_set_error(empty)
+	return result
+}
+
+// guard 把 panic 转换为 LICENSE_ERR_INTERNAL，避免崩溃宿主进程
+func guard(result *C.int) {
+	if r := recover(); r != nil {
+		*result = fail(licenseErrInternal, "internal error: %v", r)
+	}
+}
+
+// copyOut 按 snprintf 的约定把 value 写入 buf
+func copyOut(value string, buf *C.char, size C.size_t) C.int {
+	if buf == nil && size > 0 {
+		return fail(licenseErrArgument, "buffer is NULL but size is %d", uint64(size))
+	}
+	if buf != nil && size > 0 {
+		dst := unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(size))
+		n := copy(dst[:len(dst)-1], value)
+		dst[n] = 0
+	}
+	return succeed(C.int(len(value)))
+}
+
+//export license_verify
+func license_verify(path *C.char) (result C.int) {
+	defer guard(&result)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	verified = nil
+
+	if trustedKeysErr != nil {
+		return fail(licenseErrSignature, "LICENSE_VERIFICATION_PUBLIC_KEYS: %v", trustedKeysErr)
+	}
+
+	licensePath := ""
+	if path != nil {
+		licensePath = C.GoString(path)
+	}
+	// 明确指定文件时只在设置了 LICENSE_CLIENT_PRODUCT 时检查产品
+	product := os.Getenv("LICENSE_CLIENT_PRODUCT")
+	if licensePath == "" {
+		if product == "" {
+			product = defaultProduct
+		}
+		selected, candidates, err := service.FindLicense(product, "")
+		switch {
+		case selected != nil:
+			licensePath = selected.Path
+		case len(candidates) > 0:
+			licensePath = candidates[0].Path
+		default:
+			return fail(licenseErrNotFound, "%v", err)
+		}
+	}
+
+	report := service.Diagnose(licensePath, service.DiagnoseOptions{Product: product})
+	if failure := report.FirstFailure(); failure != nil {
+		code, ok := checkCodes[failure.Name]
+		if !ok {
+			code = licenseErrInternal
+		}
+		return fail(code, "%s: %s check failed: %s", licensePath, failure.Name, failure.Detail)
+	}
+	verified = report.License
+	return succeed(licenseOK)
+}
+
+// licenseField 返回授权信息中与 license.h 中字段名对应的值
+func licenseField(info *service.LicenseInfo, name string) (string, error) {
+	switch name {
+	case "id":
+		return info.Id, nil
+	case "license":
+		return info.License, nil
+	case "date":
+		return info.Date, nil
+	case "signatureCode":
+		return info.SignatureCode, nil
+	case "type":
+		return info.Type, nil
+	case "expiration":
+		return info.Expiration, nil
+	case "usersNum":
+		return info.AllowedUsers, nil
+	case "project":
+		return info.Project, nil
+	case "module":
+		return info.Module, nil
+	case "licensee":
+		return info.Licensee, nil
+	}
+	return "", errors.New("unknown field " + name)
+}
+
+//export license_get_field
+func license_get_field(name *C.char, buf *C.char, size C.size_t) (result C.int) {
+	defer guard(&result)
+
+	if name == nil {
+		return fail(licenseErrArgument, "field name is NULL")
+	}
+	mutex.RLock()
+	info := verified
+	mutex.RUnlock()
+	if info == nil {
+		return fail(licenseErrNoLicense, "no license has been verified")
+	}
+
+	value, err := licenseField(info, C.GoString(name))
+	if err != nil {
+		return fail(licenseErrUnknownField, "%v", err)
+	}
+	return copyOut(value, buf, size)
+}
+
+//export license_has_feature
+func license_has_feature(feature *C.char) (result C.int) {
+	defer guard(&result)
+
+	if feature == nil {
+		return fail(licenseErrArgument, "feature is NULL")
+	}
+	mutex.RLock()
+	info := verified
+	mutex.RUnlock()
+	if info == nil {
+		return fail(licenseErrNoLicense, "no license has been verified")
+	}
+
+	want := strings.TrimSpace(C.GoString(feature))
+	for _, f := range strings.FieldsFunc(info.Module, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	}) {
+		if strings.EqualFold(f, want) {
+			return succeed(1)
+		}
+	}
+	return succeed(0)
+}
+
+//export license_machine_code
+func license_machine_code(buf *C.char, size C.size_t) (result C.int) {
+	defer guard(&result)
+
+	fp, err := utils.MachineFingerprint()
+	if err != nil {
+		return fail(licenseErrInternal, "%v", err)
+	}
+	return copyOut(fp.MachineCode, buf, size)
+}
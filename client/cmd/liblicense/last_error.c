@@ -0,0 +1,20 @@
+#include <string.h>
+
+#include "license.h"
+
+/* 每个线程保存自己的错误信息，互不干扰 */
+static __thread char last_error[1024];
+
+/* 供 Go 代码设置错误信息，不属于公开接口 */
+__attribute__((visibility("hidden"))) void license_set_error(const char *msg) {
+    strncpy(last_error, msg, sizeof(last_error) - 1);
+    last_error[sizeof(last_error) - 1] = '\0';
+}
+
+const char *license_last_error(void) {
+    return last_error;
+}
+
+int license_abi_version(void) {
+    return LICENSE_ABI_VERSION;
+}
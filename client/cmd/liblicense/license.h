@@ -0,0 +1,75 @@
+/*
+ * liblicense 许可证校验库的 C 接口
+ *
+ * 由 client/cmd/liblicense 以 -buildmode=c-shared 构建为 liblicense.so，与客户端命令行使用相同的校验逻辑。
+ * 函数可以在多个线程中调用；license_verify 校验通过的许可证在进程内共享，
+ * license_get_field 和 license_has_feature 读取的是最近一次校验通过的许可证。
+ * 错误信息按线程保存。
+ */
+
+#ifndef LIBLICENSE_LICENSE_H
+#define LIBLICENSE_LICENSE_H
+
+#include <stddef.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* ABI 版本，删除或修改已有函数时递增 */
+#define LICENSE_ABI_VERSION 1
+
+/* 返回码：0 表示成功，负数表示错误，具体原因见 license_last_error() */
+#define LICENSE_OK                 0
+#define LICENSE_ERR_ARGUMENT      -1 /* 参数无效 */
+#define LICENSE_ERR_NOT_FOUND     -2 /* 许可文件不存在或无法读取 */
+#define LICENSE_ERR_FORMAT        -3 /* 不是许可文件，或内容不完整 */
+#define LICENSE_ERR_MACHINE       -4 /* 许可证不属于本机 */
+#define LICENSE_ERR_EXPIRED       -5 /* 许可证已过期 */
+#define LICENSE_ERR_NO_LICENSE    -6 /* 还没有校验通过的许可证 */
+#define LICENSE_ERR_UNKNOWN_FIELD -7 /* 字段名无效 */
+#define LICENSE_ERR_INTERNAL      -8 /* 无法计算本机机器码等内部错误 */
+#define LICENSE_ERR_PRODUCT       -9 /* 许可证不是为该产品签发的（project 与产品名不同） */
+#define LICENSE_ERR_SIGNATURE    -10 /* 配置了可信公钥，但许可文件没有签名、签名无效或由不可信的密钥签名 */
+
+/* 返回库实现的 ABI 版本，调用方应确认与 LICENSE_ABI_VERSION 相同 */
+int license_abi_version(void);
+
+/*
+ * 校验许可文件：能用本机机器码解码、绑定到本机且未过期
+ * path 为 NULL 或空字符串时按客户端的规则搜索许可文件：环境变量 <PRODUCT>_LICENSE_PATH、
+ * $XDG_CONFIG_HOME/<product>/、/etc/<product>/，产品名取自环境变量 LICENSE_CLIENT_PRODUCT，默认为 license-tool；
+ * 许可证的 project 必须与产品名相同（不区分大小写），指定 path 时只在设置了 LICENSE_CLIENT_PRODUCT 时检查；
+ * 设置了环境变量 LICENSE_VERIFICATION_PUBLIC_KEYS（以逗号分隔的 base64 编码 Ed25519 公钥）或构建时写入了公钥时，
+ * 许可文件必须由其中某个公钥签名
+ * 返回 LICENSE_OK 或错误码；失败时清除之前校验通过的许可证
+ */
+int license_verify(const char *path);
+
+/*
+ * 把最近一次校验通过的许可证的字段值写入 buf（以 NUL 结尾，超出 size 时截断）
+ * 字段：id、license、date、signatureCode、type、expiration、usersNum、project、module、licensee
+ * （licensee 为被许可方名称，旧版服务端签发的许可文件中没有该字段，此时返回空字符串）
+ * 返回值的长度（不含 NUL，与 snprintf 相同，返回值 >= size 表示被截断），buf 为 NULL 且 size 为 0 时只返回长度；
+ * 失败时返回错误码
+ */
+int license_get_field(const char *name, char *buf, size_t size);
+
+/*
+ * 判断最近一次校验通过的许可证是否包含某项功能
+ * 许可文件中没有单独的功能列表，功能即 module 字段中以逗号、分号或空白分隔的各项，比较时不区分大小写
+ * 返回 1 表示包含，0 表示不包含，负数为错误码
+ */
+int license_has_feature(const char *feature);
+
+/* 把本机机器码写入 buf，返回值与 license_get_field 相同 */
+int license_machine_code(char *buf, size_t size);
+
+/* 返回当前线程最近一次调用失败的原因，成功调用后为空字符串；返回的指针在当前线程下一次调用前有效，不要释放 */
+const char *license_last_error(void);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* LIBLICENSE_LICENSE_H */
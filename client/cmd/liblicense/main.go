@@ -0,0 +1,9 @@
+/*
+ * liblicense 把客户端的许可证校验导出为 C 共享库，C 接口见 license.h
+ * 构建：go build -buildmode=c-shared -o liblicense.so ./cmd/liblicense，或在本目录执行 make
+ */
+
+package main
+
+// c-shared 模式要求 main 包，main 函数不会被调用
+func main() {}
@@ -0,0 +1,321 @@
+"""liblicense.so（客户端许可证校验库）的 Python 绑定
+
+共享库由 client/cmd/liblicense 通过 ``go build -buildmode=c-shared -o liblicense.so .``（或 ``make``）构建，
+按以下顺序查找：``path`` 参数、环境变量 ``LIBLICENSE_PATH``、本模块所在目录及其上级目录、系统库路径
+
+库中的 Go 运行时只在加载时读取一次环境变量（例如 ``LICENSE_CLIENT_PRODUCT``、``<PRODUCT>_LICENSE_PATH`` 和
+``LICENSE_VERIFICATION_PUBLIC_KEYS``），
+因此需要在第一次调用之前设置
+
+示例::
+
+    import liblicense
+
+    result = liblicense.verify()
+    if not result.valid:
+        raise SystemExit(result.error)
+    if liblicense.has_feature("reports"):
+        ...
+"""
+
+import ctypes
+import ctypes.util
+import datetime
+import os
+import re
+import threading
+from dataclasses import dataclass
+from typing import Optional, Tuple
+
+__all__ = [
+    "ABI_VERSION",
+    "License",
+    "VerifyResult",
+    "Verifier",
+    "LicenseError",
+    "ArgumentError",
+    "NotFoundError",
+    "FormatError",
+    "MachineMismatchError",
+    "ExpiredError",
+    "NoLicenseError",
+    "UnknownFieldError",
+    "InternalError",
+    "ProductMismatchError",
+    "SignatureError",
+    "machine_code",
+    "verify",
+    "require",
+    "has_feature",
+]
+
+# 必须与 license.h 中的 LICENSE_ABI_VERSION 相同
+ABI_VERSION = 1
+
+LICENSE_OK = 0
+LICENSE_ERR_ARGUMENT = -1
+LICENSE_ERR_NOT_FOUND = -2
+LICENSE_ERR_FORMAT = -3
+LICENSE_ERR_MACHINE = -4
+LICENSE_ERR_EXPIRED = -5
+LICENSE_ERR_NO_LICENSE = -6
+LICENSE_ERR_UNKNOWN_FIELD = -7
+LICENSE_ERR_INTERNAL = -8
+LICENSE_ERR_PRODUCT = -9
+LICENSE_ERR_SIGNATURE = -10
+
+
+class LicenseError(Exception):
+    """liblicense 调用失败，``code`` 为 LICENSE_ERR_* 返回码"""
+
+    code = None
+
+    def __init__(self, message, code=None):
+        super().__init__(message)
+        if code is not None:
+            self.code = code
+
+
+class ArgumentError(LicenseError):
+    code = LICENSE_ERR_ARGUMENT
+
+
+class NotFoundError(LicenseError):
+    code = LICENSE_ERR_NOT_FOUND
+
+
+class FormatError(LicenseError):
+    code = LICENSE_ERR_FORMAT
+
+
+class MachineMismatchError(LicenseError):
+    code = LICENSE_ERR_MACHINE
+
+
+class ExpiredError(LicenseError):
+    code = LICENSE_ERR_EXPIRED
+
+
+class NoLicenseError(LicenseError):
+    code = LICENSE_ERR_NO_LICENSE
+
+
+class UnknownFieldError(LicenseError):
+    code = LICENSE_ERR_UNKNOWN_FIELD
+
+
+class InternalError(LicenseError):
+    code = LICENSE_ERR_INTERNAL
+
+
+class ProductMismatchError(LicenseError):
+    code = LICENSE_ERR_PRODUCT
+
+
+class SignatureError(LicenseError):
+    code = LICENSE_ERR_SIGNATURE
+
+
+_ERRORS = {cls.code: cls for cls in (
+    ArgumentError, NotFoundError, FormatError, MachineMismatchError,
+    ExpiredError, NoLicenseError, UnknownFieldError, InternalError, ProductMismatchError,
+    SignatureError,
+)}
+
+_FIELDS = ("id", "license", "date", "signatureCode", "type", "expiration",
+           "usersNum", "project", "module", "licensee")
+
+
+@dataclass(frozen=True)
+class License:
+    """校验通过的许可证中的授权信息"""
+
+    id: str
+    license: str
+    issued: Optional[datetime.datetime]
+    signature_code: str
+    type: str
+    expiration: datetime.date
+    users: int
+    project: str
+    module: str
+    licensee: str = ""
+
+    @property
+    def features(self) -> Tuple[str, ...]:
+        """许可证的各个模块，每个模块即一项功能"""
+        return tuple(p for p in re.split(r"[,; \t]+", self.module) if p)
+
+    def days_left(self, today: Optional[datetime.date] = None) -> int:
+        """距过期日期的天数，过期日期当天为 0，已过期时为负数"""
+        today = today or datetime.datetime.now(datetime.timezone.utc).date()
+        return (self.expiration - today).days
+
+
+@dataclass(frozen=True)
+class VerifyResult:
+    """校验结果，``valid`` 为真时 ``license`` 为授权信息"""
+
+    valid: bool
+    code: int
+    error: str
+    license: Optional[License] = None
+
+    def __bool__(self):
+        return self.valid
+
+    def raise_for_error(self):
+        """许可证无效时抛出与 ``code`` 对应的 LicenseError 子类"""
+        if not self.valid:
+            raise _ERRORS.get(self.code, LicenseError)(self.error, self.code)
+
+
+def _candidates(path):
+    if path:
+        yield path
+    env = os.environ.get("LIBLICENSE_PATH")
+    if env:
+        yield env
+    here = os.path.dirname(os.path.abspath(__file__))
+    yield os.path.join(here, "liblicense.so")
+    yield os.path.join(os.path.dirname(here), "liblicense.so")
+    found = ctypes.util.find_library("license")
+    if found:
+        yield found
+
+
+class Verifier:
+    """已加载的共享库
+
+    库在进程内保存最近一次校验通过的许可证，``get_field`` 和 ``has_feature`` 读取的是任意线程最近一次成功的 ``verify``；
+    同一个对象内通过锁保证 verify 与随后读取字段之间不被打断
+    """
+
+    def __init__(self, path: Optional[str] = None):
+        errors = []
+        for candidate in _candidates(path):
+            if os.sep in candidate and not os.path.exists(candidate):
+                errors.append("%s: not found" % candidate)
+                continue
+            try:
+                self._lib = ctypes.CDLL(candidate)
+                self.path = candidate
+                break
+            except OSError as e:
+                errors.append("%s: %s" % (candidate, e))
+        else:
+            raise OSError("liblicense.so could not be loaded:\n  " + "\n  ".join(errors))
+
+        lib = self._lib
+        lib.license_abi_version.restype = ctypes.c_int
+        lib.license_abi_version.argtypes = []
+        lib.license_verify.restype = ctypes.c_int
+        lib.license_verify.argtypes = [ctypes.c_char_p]
+        lib.license_get_field.restype = ctypes.c_int
+        lib.license_get_field.argtypes = [ctypes.c_char_p, ctypes.c_char_p, ctypes.c_size_t]
+        lib.license_has_feature.restype = ctypes.c_int
+        lib.license_has_feature.argtypes = [ctypes.c_char_p]
+        lib.license_machine_code.restype = ctypes.c_int
+        lib.license_machine_code.argtypes = [ctypes.c_char_p, ctypes.c_size_t]
+        lib.license_last_error.restype = ctypes.c_char_p
+        lib.license_last_error.argtypes = []
+
+        version = lib.license_abi_version()
+        if version != ABI_VERSION:
+            raise OSError("%s has ABI version %d, expected %d" % (self.path, version, ABI_VERSION))
+        self._lock = threading.Lock()
+
+    def _error(self, code):
+        message = (self._lib.license_last_error() or b"").decode("utf-8", "replace")
+        return _ERRORS.get(code, LicenseError)(message, code)
+
+    def _read(self, call):
+        """调用 snprintf 风格的函数：先查询长度，再写入缓冲区"""
+        n = call(None, 0)
+        while n >= 0:
+            buf = ctypes.create_string_buffer(n + 1)
+            m = call(buf, n + 1)
+            if m <= n:
+                return buf.value.decode("utf-8")
+            n = m
+        raise self._error(n)
+
+    def machine_code(self) -> str:
+        """返回本机机器码，本机的许可证绑定到该机器码"""
+        return self._read(self._lib.license_machine_code)
+
+    def get_field(self, name: str) -> str:
+        """返回最近一次校验通过的许可证的原始字段值，字段名见 license.h"""
+        key = name.encode("utf-8")
+        return self._read(lambda buf, size: self._lib.license_get_field(key, buf, size))
+
+    def has_feature(self, feature: str) -> bool:
+        """判断最近一次校验通过的许可证是否包含某项功能（模块）"""
+        result = self._lib.license_has_feature(feature.encode("utf-8"))
+        if result < 0:
+            raise self._error(result)
+        return result == 1
+
+    def verify(self, path: Optional[str] = None) -> VerifyResult:
+        """校验许可文件，``path`` 为 None 时在标准位置搜索"""
+        with self._lock:
+            code = self._lib.license_verify(path.encode("utf-8") if path else None)
+            if code != LICENSE_OK:
+                return VerifyResult(valid=False, code=code, error=str(self._error(code)))
+            fields = {name: self.get_field(name) for name in _FIELDS}
+
+        issued = None
+        try:
+            issued = datetime.datetime.strptime(fields["date"], "%Y-%m-%d %H:%M:%S").replace(tzinfo=datetime.timezone.utc)
+        except ValueError:
+            pass
+        return VerifyResult(valid=True, code=LICENSE_OK, error="", license=License(
+            id=fields["id"],
+            license=fields["license"],
+            issued=issued,
+            signature_code=fields["signatureCode"],
+            type=fields["type"],
+            expiration=datetime.datetime.strptime(fields["expiration"], "%Y-%m-%d").date(),
+            users=int(fields["usersNum"] or 0),
+            project=fields["project"],
+            module=fields["module"],
+            licensee=fields["licensee"],
+        ))
+
+    def require(self, path: Optional[str] = None) -> License:
+        """与 verify 相同，但许可证无效时抛出对应的 LicenseError"""
+        result = self.verify(path)
+        result.raise_for_error()
+        return result.license
+
+
+_default = None
+_default_lock = threading.Lock()
+
+
+def _verifier():
+    global _default
+    with _default_lock:
+        if _default is None:
+            _default = Verifier()
+        return _default
+
+
+def machine_code() -> str:
+    """使用默认的库返回本机机器码"""
+    return _verifier().machine_code()
+
+
+def verify(path: Optional[str] = None) -> VerifyResult:
+    """使用默认的库校验许可证，见 Verifier.verify"""
+    return _verifier().verify(path)
+
+
+def require(path: Optional[str] = None) -> License:
+    """使用默认的库校验许可证，无效时抛出 LicenseError"""
+    return _verifier().require(path)
+
+
+def has_feature(feature: str) -> bool:
+    """使用默认的库判断最近一次校验通过的许可证是否包含某项功能"""
+    return _verifier().has_feature(feature)
@@ -0,0 +1,255 @@
+"""liblicense Python 绑定的测试
+
+从 LIBLICENSE_PATH 加载 liblicense.so，未设置时用 ``go build -buildmode=c-shared`` 构建到临时目录；
+测试使用的许可文件按服务端的格式为本机生成
+
+    LIBLICENSE_PATH=../liblicense.so python3 -m unittest discover -s .
+"""
+
+import base64
+import datetime
+import json
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+import threading
+import unittest
+
+HERE = os.path.dirname(os.path.abspath(__file__))
+sys.path.insert(0, HERE)
+
+import liblicense  # noqa: E402
+
+_tmp = None
+_lib_path = None
+
+
+def setUpModule():
+    global _tmp, _lib_path
+    _tmp = tempfile.mkdtemp(prefix="liblicense-test-")
+    _lib_path = os.environ.get("LIBLICENSE_PATH")
+    if _lib_path:
+        return
+    if shutil.which("go") is None:
+        raise unittest.SkipTest("LIBLICENSE_PATH is not set and go is not installed")
+    _lib_path = os.path.join(_tmp, "liblicense.so")
+    subprocess.run(["go", "build", "-buildmode=c-shared", "-o", _lib_path, "."],
+                   cwd=os.path.dirname(HERE), check=True)
+
+
+def tearDownModule():
+    if _tmp:
+        shutil.rmtree(_tmp, ignore_errors=True)
+
+
+def make_license(path, machine_code, expiration, module="", license_id="1234567890123456", users=5, licensee=None,
+                 project="project"):
+    """按服务端的方式生成许可文件：JSON 与机器码循环异或，补 0 到 4096 字节，再做 base64url 编码"""
+    authorized = {
+        "id": license_id,
+        "license": "L" * 72,
+        "date": "2026-01-02 03:04:05",
+        "signatureCode": machine_code,
+        "type": "standard",
+        "expiration": expiration.strftime("%Y-%m-%d"),
+        "usersNum": str(users),
+        "project": project,
+        "module": module,
+    }
+    if licensee is not None:
+        authorized["licensee"] = licensee
+    content = json.dumps({
+        "authorized": authorized,
+        "status": "OK",
+        "code": 200,
+    }, separators=(",", ":")).encode()
+    key = machine_code.encode()
+    obfuscated = bytes(b ^ key[i % len(key)] for i, b in enumerate(content)).ljust(4096, b"\0")
+    with open(path, "w") as f:
+        f.write(base64.urlsafe_b64encode(obfuscated).decode())
+    return path
+
+
+def today():
+    return datetime.datetime.now(datetime.timezone.utc).date()
+
+
+class VerifierTest(unittest.TestCase):
+
+    @classmethod
+    def setUpClass(cls):
+        cls.verifier = liblicense.Verifier(_lib_path)
+        cls.machine_code = cls.verifier.machine_code()
+        cls.dir = tempfile.mkdtemp(dir=_tmp)
+
+    def path(self, name):
+        return os.path.join(self.dir, name)
+
+    def test_machine_code(self):
+        self.assertEqual(len(self.machine_code), 32)
+        int(self.machine_code, 16)
+        self.assertEqual(self.verifier.machine_code(), self.machine_code)
+
+    def test_verify_valid(self):
+        expiration = today() + datetime.timedelta(days=30)
+        path = make_license(self.path("valid.license"), self.machine_code, expiration, module="reports, Export")
+
+        result = self.verifier.verify(path)
+        self.assertTrue(result)
+        self.assertEqual(result.code, liblicense.LICENSE_OK)
+        self.assertEqual(result.error, "")
+
+        lic = result.license
+        self.assertEqual(lic.id, "1234567890123456")
+        self.assertEqual(lic.signature_code, self.machine_code)
+        self.assertEqual(lic.expiration, expiration)
+        self.assertEqual(lic.days_left(), 30)
+        self.assertEqual(lic.users, 5)
+        self.assertEqual(lic.issued, datetime.datetime(2026, 1, 2, 3, 4, 5, tzinfo=datetime.timezone.utc))
+        self.assertEqual(lic.features, ("reports", "Export"))
+        self.assertEqual(self.verifier.require(path), lic)
+
+    def test_features(self):
+        path = make_license(self.path("features.license"), self.machine_code,
+                            today() + datetime.timedelta(days=1), module="reports;export")
+        self.verifier.require(path)
+        self.assertTrue(self.verifier.has_feature("reports"))
+        self.assertTrue(self.verifier.has_feature("EXPORT"))
+        self.assertFalse(self.verifier.has_feature("admin"))
+
+    def test_licensee(self):
+        path = make_license(self.path("licensee.license"), self.machine_code,
+                            today() + datetime.timedelta(days=1), licensee="Acme 株式会社")
+        self.assertEqual(self.verifier.require(path).licensee, "Acme 株式会社")
+        self.assertEqual(self.verifier.get_field("licensee"), "Acme 株式会社")
+
+        path = make_license(self.path("no-licensee.license"), self.machine_code, today() + datetime.timedelta(days=1))
+        self.assertEqual(self.verifier.require(path).licensee, "")
+
+    def test_long_field(self):
+        module = ",".join("feature%d" % i for i in range(200))
+        path = make_license(self.path("long.license"), self.machine_code, today(), module=module)
+        self.assertEqual(self.verifier.require(path).module, module)
+        self.assertTrue(self.verifier.has_feature("feature199"))
+
+    def test_expired(self):
+        path = make_license(self.path("expired.license"), self.machine_code, today() - datetime.timedelta(days=1))
+        result = self.verifier.verify(path)
+        self.assertFalse(result)
+        self.assertEqual(result.code, liblicense.LICENSE_ERR_EXPIRED)
+        self.assertIn("expired", result.error)
+        self.assertIsNone(result.license)
+        with self.assertRaises(liblicense.ExpiredError):
+            result.raise_for_error()
+
+    def test_other_machine(self):
+        other = "0123456789abcdef0123456789abcdef"
+        path = make_license(self.path("other.license"), other, today() + datetime.timedelta(days=30))
+        with self.assertRaises(liblicense.MachineMismatchError) as ctx:
+            self.verifier.require(path)
+        # 不从许可文件中推算签发时的机器码，只说明不是为本机签发
+        self.assertIn("not issued for this machine", str(ctx.exception))
+        self.assertNotIn(other, str(ctx.exception))
+
+        # 校验失败后不再保留之前校验通过的许可证
+        with self.assertRaises(liblicense.NoLicenseError):
+            self.verifier.has_feature("reports")
+
+    def test_missing_and_damaged(self):
+        with self.assertRaises(liblicense.NotFoundError):
+            self.verifier.require(self.path("missing.license"))
+
+        damaged = self.path("damaged.license")
+        with open(damaged, "w") as f:
+            f.write("not a license!")
+        with self.assertRaises(liblicense.FormatError):
+            self.verifier.require(damaged)
+
+    def test_unknown_field(self):
+        make_license(self.path("field.license"), self.machine_code, today())
+        self.verifier.require(self.path("field.license"))
+        with self.assertRaises(liblicense.UnknownFieldError):
+            self.verifier.get_field("nope")
+
+    def test_concurrent_verify(self):
+        good = make_license(self.path("good.license"), self.machine_code, today() + datetime.timedelta(days=3))
+        bad = self.path("missing.license")
+        errors = []
+
+        def run(path, valid):
+            for _ in range(20):
+                if bool(self.verifier.verify(path)) != valid:
+                    errors.append(path)
+
+        threads = [threading.Thread(target=run, args=(good, True)), threading.Thread(target=run, args=(bad, False))]
+        for t in threads:
+            t.start()
+        for t in threads:
+            t.join()
+        self.assertEqual(errors, [])
+
+
+def run_require(licenses, **env):
+    """在新的解释器中搜索 licenses 目录并校验，返回许可证ID或异常类名"""
+    env = dict(os.environ,
+               LIBLICENSE_PATH=_lib_path,
+               LICENSE_CLIENT_PRODUCT="py-test",
+               PY_TEST_LICENSE_PATH=licenses,
+               XDG_CONFIG_HOME=tempfile.mkdtemp(dir=_tmp),
+               **env)
+    script = ("import liblicense\n"
+              "try:\n"
+              "    print(liblicense.require().id)\n"
+              "except liblicense.LicenseError as e:\n"
+              "    print(type(e).__name__)\n")
+    return subprocess.run([sys.executable, "-c", script],
+                          cwd=HERE, env=env, check=True, capture_output=True, text=True).stdout.strip()
+
+
+class DiscoveryTest(unittest.TestCase):
+    """库只在加载时读取环境变量，因此在新的解释器中测试许可文件搜索"""
+
+    def test_picks_latest_valid_license(self):
+        machine_code = liblicense.Verifier(_lib_path).machine_code()
+        licenses = tempfile.mkdtemp(dir=_tmp)
+        make_license(os.path.join(licenses, "a.license"), machine_code, today() + datetime.timedelta(days=10),
+                     license_id="1111111111111111", project="py-test")
+        make_license(os.path.join(licenses, "b.license"), machine_code, today() + datetime.timedelta(days=90),
+                     license_id="2222222222222222", project="PY-TEST")
+        make_license(os.path.join(licenses, "c.license"), machine_code, today() - datetime.timedelta(days=1),
+                     license_id="3333333333333333", project="py-test")
+        # 其他产品的许可证即使过期日期最晚也不会被选中
+        make_license(os.path.join(licenses, "d.license"), machine_code, today() + datetime.timedelta(days=365),
+                     license_id="4444444444444444", project="other")
+        self.assertEqual(run_require(licenses), "2222222222222222")
+
+    def test_other_product(self):
+        machine_code = liblicense.Verifier(_lib_path).machine_code()
+        licenses = tempfile.mkdtemp(dir=_tmp)
+        make_license(os.path.join(licenses, "a.license"), machine_code, today() + datetime.timedelta(days=10),
+                     project="other")
+        self.assertEqual(run_require(licenses), "ProductMismatchError")
+
+
+class SignatureTest(unittest.TestCase):
+    """测试生成的许可文件没有签名，配置了可信公钥时必须被拒绝"""
+
+    def test_unsigned_rejected_with_trusted_key(self):
+        machine_code = liblicense.Verifier(_lib_path).machine_code()
+        licenses = tempfile.mkdtemp(dir=_tmp)
+        make_license(os.path.join(licenses, "a.license"), machine_code, today() + datetime.timedelta(days=10),
+                     license_id="1111111111111111", project="py-test")
+        self.assertEqual(run_require(licenses), "1111111111111111")
+
+        key = base64.b64encode(bytes(range(32))).decode()
+        self.assertEqual(run_require(licenses, LICENSE_VERIFICATION_PUBLIC_KEYS=key), "SignatureError")
+
+    def test_invalid_trusted_key(self):
+        licenses = tempfile.mkdtemp(dir=_tmp)
+        self.assertEqual(run_require(licenses, LICENSE_VERIFICATION_PUBLIC_KEYS="not-a-key"), "SignatureError")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,134 @@
+/*
+ * liblicense 的 C 测试程序
+ * 用法：license_test <本机的许可文件> [其他机器的许可文件]
+ * 本机的许可文件需要绑定到本机且未过期，例如 licensectl issue -machine-code $(client machine-code -short) -days 30 -module "reports, export" -out valid.license
+ */
+
+#include <pthread.h>
+#include <stdio.h>
+#include <string.h>
+
+#include "license.h"
+
+static int failures = 0;
+
+#define CHECK(cond)                                                                   \
+    do {                                                                              \
+        if (!(cond)) {                                                                \
+            fprintf(stderr, "%s:%d: check failed: %s (last error: %s)\n", __FILE__, \
+                    __LINE__, #cond, license_last_error());                           \
+            failures++;                                                               \
+        }                                                                             \
+    } while (0)
+
+static void test_machine_code(void) {
+    char code[64];
+    char small[8];
+
+    int n = license_machine_code(code, sizeof(code));
+    CHECK(n == 32);
+    CHECK(strlen(code) == 32);
+    CHECK(strcmp(license_last_error(), "") == 0);
+
+    /* 只查询长度 */
+    CHECK(license_machine_code(NULL, 0) == 32);
+
+    /* 截断时返回完整长度，缓冲区以 NUL 结尾 */
+    CHECK(license_machine_code(small, sizeof(small)) == 32);
+    CHECK(strlen(small) == sizeof(small) - 1);
+    CHECK(strncmp(small, code, sizeof(small) - 1) == 0);
+
+    CHECK(license_machine_code(NULL, 8) == LICENSE_ERR_ARGUMENT);
+}
+
+static void test_invalid_arguments(void) {
+    char buf[16];
+
+    CHECK(license_verify("/nonexistent/license.license") == LICENSE_ERR_NOT_FOUND);
+    CHECK(strlen(license_last_error()) > 0);
+    CHECK(license_get_field("id", buf, sizeof(buf)) == LICENSE_ERR_NO_LICENSE);
+    CHECK(license_has_feature("reports") == LICENSE_ERR_NO_LICENSE);
+    CHECK(license_get_field(NULL, buf, sizeof(buf)) == LICENSE_ERR_ARGUMENT);
+    CHECK(license_has_feature(NULL) == LICENSE_ERR_ARGUMENT);
+}
+
+static void test_valid(const char *path) {
+    char code[64];
+    char buf[128];
+    char module[256];
+
+    CHECK(license_verify(path) == LICENSE_OK);
+    CHECK(strcmp(license_last_error(), "") == 0);
+
+    CHECK(license_get_field("id", buf, sizeof(buf)) > 0);
+    printf("verified license %s", buf);
+    CHECK(license_get_field("expiration", buf, sizeof(buf)) == 10);
+    printf(", expires on %s\n", buf);
+
+    license_machine_code(code, sizeof(code));
+    CHECK(license_get_field("signatureCode", buf, sizeof(buf)) == 32);
+    CHECK(strcmp(buf, code) == 0);
+
+    CHECK(license_get_field("no-such-field", buf, sizeof(buf)) == LICENSE_ERR_UNKNOWN_FIELD);
+
+    /* 许可文件中的每个模块都是一项功能 */
+    CHECK(license_get_field("module", module, sizeof(module)) >= 0);
+    if (module[0] != '\0') {
+        char first[256];
+        size_t len = strcspn(module, ",; \t");
+        memcpy(first, module, len);
+        first[len] = '\0';
+        CHECK(license_has_feature(first) == 1);
+    }
+    CHECK(license_has_feature("no-such-feature") == 0);
+}
+
+static void test_foreign(const char *path) {
+    char buf[16];
+
+    CHECK(license_verify(path) == LICENSE_ERR_MACHINE);
+    CHECK(strstr(license_last_error(), "machine code") != NULL);
+    /* 校验失败后不再保留之前校验通过的许可证 */
+    CHECK(license_get_field("id", buf, sizeof(buf)) == LICENSE_ERR_NO_LICENSE);
+}
+
+/* 错误信息按线程保存：其他线程的失败不影响本线程 */
+static void *fail_in_thread(void *arg) {
+    (void)arg;
+    license_verify("/nonexistent/license.license");
+    return (void *)(strlen(license_last_error()) > 0 ? (void *)1 : NULL);
+}
+
+static void test_thread_errors(const char *path) {
+    pthread_t thread;
+    void *result = NULL;
+
+    CHECK(license_verify(path) == LICENSE_OK);
+    CHECK(pthread_create(&thread, NULL, fail_in_thread, NULL) == 0);
+    pthread_join(thread, &result);
+    CHECK(result != NULL);
+    CHECK(strcmp(license_last_error(), "") == 0);
+}
+
+int main(int argc, char **argv) {
+    if (argc < 2) {
+        fprintf(stderr, "usage: %s <license for this machine> [license for another machine]\n", argv[0]);
+        return 2;
+    }
+
+    CHECK(license_abi_version() == LICENSE_ABI_VERSION);
+    test_machine_code();
+    test_invalid_arguments();
+    test_valid(argv[1]);
+    if (argc > 2) {
+        test_foreign(argv[2]);
+    }
+    test_thread_errors(argv[1]);
+
+    if (failures > 0) {
+        fprintf(stderr, "%d check(s) failed\n", failures);
+        return 1;
+    }
+    printf("all checks passed\n");
+    return 0;
+}
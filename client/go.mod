@@ -1,3 +1,7 @@
 module client
 
 go 1.18
+
+require config v0.0.0
+
+replace config => ../config
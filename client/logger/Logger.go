@@ -0,0 +1,139 @@
+/*
+ * Package logger 定义客户端库使用的日志接口
+ * 宿主程序可以通过 SetLogger 注入自己的实现，将许可相关的日志输出到自己的日志系统中
+ */
+
+package logger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Logger 客户端库的日志接口
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Level 日志级别
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+/*
+ * ParseLevel 解析日志级别名称
+ * @params: s: debug、info、warn（warning）或 error，不区分大小写，空字符串视为 info
+ * @return: Level: 日志级别
+ *			error: 名称无效时返回错误对象；否则为 nil
+ */
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q", s)
+}
+
+var (
+	mutex   sync.RWMutex
+	current Logger = NewStdLogger(log.New(os.Stderr, "license: ", log.LstdFlags), LevelInfo)
+)
+
+// SetLogger 设置客户端库使用的日志实现，传入 nil 时关闭日志输出
+func SetLogger(l Logger) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if l == nil {
+		l = Nop{}
+	}
+	current = l
+}
+
+// Get 获取当前的日志实现
+func Get() Logger {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return current
+}
+
+// Debugf 输出调试日志
+func Debugf(format string, args ...interface{}) { Get().Debugf(format, args...) }
+
+// Infof 输出普通日志
+func Infof(format string, args ...interface{}) { Get().Infof(format, args...) }
+
+// Warnf 输出警告日志
+func Warnf(format string, args ...interface{}) { Get().Warnf(format, args...) }
+
+// Errorf 输出错误日志
+func Errorf(format string, args ...interface{}) { Get().Errorf(format, args...) }
+
+// StdLogger 基于标准库 log.Logger 的日志实现
+type StdLogger struct {
+	logger *log.Logger
+	level  Level
+}
+
+/*
+ * NewStdLogger 创建基于标准库 log.Logger 的日志实现
+ * @params: l: 标准库日志记录器
+ *			level: 最低输出级别
+ * @return: *StdLogger: 日志实现
+ */
+func NewStdLogger(l *log.Logger, level Level) *StdLogger {
+	return &StdLogger{logger: l, level: level}
+}
+
+func (s *StdLogger) output(level Level, prefix string, format string, args []interface{}) {
+	if level < s.level {
+		return
+	}
+	s.logger.Printf(prefix+format, args...)
+}
+
+// Debugf 输出调试日志
+func (s *StdLogger) Debugf(format string, args ...interface{}) {
+	s.output(LevelDebug, "DEBUG ", format, args)
+}
+
+// Infof 输出普通日志
+func (s *StdLogger) Infof(format string, args ...interface{}) {
+	s.output(LevelInfo, "INFO ", format, args)
+}
+
+// Warnf 输出警告日志
+func (s *StdLogger) Warnf(format string, args ...interface{}) {
+	s.output(LevelWarn, "WARN ", format, args)
+}
+
+// Errorf 输出错误日志
+func (s *StdLogger) Errorf(format string, args ...interface{}) {
+	s.output(LevelError, "ERROR ", format, args)
+}
+
+// Nop 丢弃全部日志的实现
+type Nop struct{}
+
+func (Nop) Debugf(string, ...interface{}) {}
+func (Nop) Infof(string, ...interface{})  {}
+func (Nop) Warnf(string, ...interface{})  {}
+func (Nop) Errorf(string, ...interface{}) {}
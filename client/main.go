@@ -1,11 +1,163 @@
 package main
 
 import (
+	"client/logger"
 	"client/service"
+	"config"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"os"
 )
 
+// envPrefix 覆盖配置的环境变量前缀
+const envPrefix = "LICENSE"
+
+// settings 客户端配置
+type settings struct {
+	LicensePath     string
+	Product         string
+	ServerURL       string
+	LogLevel        logger.Level
+	CheckRevocation bool
+	AllowOffline    bool
+	PublicKeys      string
+}
+
+// fileSettings 配置文件中的键，由 config.Bind 按标签绑定和校验
+type fileSettings struct {
+	Client struct {
+		LicensePath string `config:"license_path"`
+		Product     string `config:"product" default:"license-tool" min:"1"`
+		ServerURL   string `config:"server_url"`
+	} `config:"client"`
+
+	Log struct {
+		Level string `config:"level" default:"info"`
+	} `config:"log"`
+
+	Verification struct {
+		CheckRevocation bool   `config:"check_revocation" default:"false"`
+		AllowOffline    bool   `config:"allow_offline" default:"true"`
+		PublicKeys      string `config:"public_keys"`
+	} `config:"verification"`
+}
+
+/*
+ * loadSettings 从配置中读取客户端配置，缺省的键使用默认值
+ * 支持的键：client.license_path、client.product、client.server_url、log.level、
+ *	verification.check_revocation、verification.allow_offline、verification.public_keys
+ * @params: cfg: 配置
+ * @return: settings: 客户端配置
+ *			error: 存在无效值时返回包含全部问题（带文件名和行号）的错误对象；否则为 nil
+ */
+func loadSettings(cfg *config.Config) (settings, error) {
+	var f fileSettings
+	if err := cfg.Bind(&f); err != nil {
+		return settings{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	level, err := logger.ParseLevel(f.Log.Level)
+	if err != nil {
+		return settings{}, fmt.Errorf("invalid configuration: key log.level: %w", err)
+	}
+	if err := service.SetTrustedKeys(f.Verification.PublicKeys); err != nil {
+		return settings{}, fmt.Errorf("invalid configuration: key verification.public_keys: %w", err)
+	}
+
+	return settings{
+		LicensePath:     f.Client.LicensePath,
+		Product:         f.Client.Product,
+		ServerURL:       f.Client.ServerURL,
+		LogLevel:        level,
+		CheckRevocation: f.Verification.CheckRevocation,
+		AllowOffline:    f.Verification.AllowOffline,
+		PublicKeys:      f.Verification.PublicKeys,
+	}, nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: client [flags] [command] [command flags] [args]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-18s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(out, "\nCommand usage:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  client %s\n", c.Usage)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
 func main() {
 
-	fmt.Println(service.VerifyLicense("3209497350222647.license"))
+	// 每个配置键都可以通过同名参数（例如 -client.license_path）或环境变量（例如 LICENSE_CLIENT_LICENSE_PATH）覆盖，
+	// 优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
+	configPath := flag.String("config", os.Getenv(envPrefix+"_CONFIG"), "path to the configuration file in .ini, .json, .yaml or .toml format (default: config.ini next to the executable)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and where each value came from, then exit")
+	output := flag.String("o", "text", "output format: text or json")
+	overrides, err := config.NewOverrides(envPrefix, &fileSettings{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	overrides.RegisterFlags(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *output)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadDefault(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load configuration:", err)
+		os.Exit(1)
+	}
+	overrides.Apply(cfg)
+	if *printConfig {
+		if err := cfg.WriteEffective(os.Stdout, &fileSettings{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	st, err := loadSettings(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		return
+	}
+	logger.SetLogger(logger.NewStdLogger(log.New(os.Stderr, "license: ", log.LstdFlags), st.LogLevel))
+
+	// 未指定命令时校验已安装的许可证
+	name, args := "verify", []string(nil)
+	if flag.NArg() > 0 {
+		name, args = flag.Arg(0), flag.Args()[1:]
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].Name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	ctx := &context{settings: st, out: os.Stdout, json: *output == "json"}
+	if err := cmd.Run(ctx, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		if !errors.Is(err, errInvalid) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
 }
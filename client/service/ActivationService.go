@@ -0,0 +1,48 @@
+package service
+
+import (
+	"client/utils"
+	"time"
+)
+
+// ActivationRequest 发给供应商用于签发许可证的激活请求
+type ActivationRequest struct {
+	MachineCode  string    `json:"machineCode"`
+	Hostname     string    `json:"hostname"`
+	OS           string    `json:"os"`
+	Arch         string    `json:"arch"`
+	Interface    string    `json:"interface"`
+	Project      string    `json:"project,omitempty"`
+	Module       string    `json:"module,omitempty"`
+	Type         string    `json:"type,omitempty"`
+	AllowedUsers uint      `json:"usersNum,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+/* NewActivationRequest 使用本机机器码创建激活请求
+ * @params: project: 申请的项目，可为空
+ *			module: 申请的模块，可为空
+ *			licenseType: 申请的许可证类型，可为空
+ *			allowedUsers: 申请的用户数，为 0 时由供应商决定
+ * @return: *ActivationRequest: 激活请求
+ *			error: 无法计算机器码时返回错误对象；否则为 nil
+ */
+func NewActivationRequest(project string, module string, licenseType string, allowedUsers uint) (*ActivationRequest, error) {
+
+	fp, err := utils.MachineFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	return &ActivationRequest{
+		MachineCode:  fp.MachineCode,
+		Hostname:     fp.Hostname,
+		OS:           fp.OS,
+		Arch:         fp.Arch,
+		Interface:    fp.Interface,
+		Project:      project,
+		Module:       module,
+		Type:         licenseType,
+		AllowedUsers: allowedUsers,
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
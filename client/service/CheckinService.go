@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"client/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckinResult 服务器返回的签到结果
+type CheckinResult struct {
+	LicenseID      string    `json:"licenseId"`
+	LastSeen       time.Time `json:"lastSeen"`
+	SuspectedClone bool      `json:"suspectedClone"`
+}
+
+/* Checkin 向许可证服务器上报一次签到（心跳）
+ * @params: serverURL: 许可证服务器地址，例如 http://host:8080
+ *			info: 本机许可文件中的授权信息，其中的许可证令牌（license 字段）用于证明签到来自持有许可文件的客户端
+ *			appVersion: 当前程序版本
+ *			activeUsers: 当前活跃用户数
+ * @return: *CheckinResult: 服务器返回的签到结果
+ *			error: 请求失败或服务器返回错误时返回错误对象；否则为 nil
+ */
+func Checkin(serverURL string, info *LicenseInfo, appVersion string, activeUsers uint) (*CheckinResult, error) {
+
+	body, err := json.Marshal(map[string]interface{}{
+		"licenseId":   info.Id,
+		"license":     info.License,
+		"fingerprint": utils.MachineCode(),
+		"appVersion":  appVersion,
+		"activeUsers": activeUsers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(strings.TrimRight(serverURL, "/")+"/checkins", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("check-in failed: unexpected status %s", resp.Status)
+	}
+
+	var msg struct {
+		Usage CheckinResult `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg.Usage, nil
+}
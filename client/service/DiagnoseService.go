@@ -0,0 +1,205 @@
+package service
+
+import (
+	"client/utils"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// 检查结果
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+	CheckSkip = "skip"
+)
+
+// expiryWarning 距过期日期少于该天数时给出警告
+const expiryWarning = 30
+
+// Check 诊断报告中的一项检查
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"` // 检查失败时的处理建议
+}
+
+// DiagnoseOptions 诊断选项
+type DiagnoseOptions struct {
+	Product         string    // 产品名称，许可证的 project 必须与之相同；为空时跳过产品检查
+	ServerURL       string    // 许可证服务器地址，为空时跳过吊销检查
+	CheckRevocation bool      // 是否查询吊销列表
+	AllowOffline    bool      // 服务器不可达时吊销检查是否只给出警告
+	Now             time.Time // 计算过期状态使用的当前时间，为零值时使用当前时间
+}
+
+// Report 许可文件的诊断报告
+type Report struct {
+	LicensePath string             `json:"licensePath"`
+	Fingerprint *utils.Fingerprint `json:"fingerprint"`
+	License     *LicenseInfo       `json:"license,omitempty"`
+	Checks      []Check            `json:"checks"`
+	Valid       bool               `json:"valid"`
+}
+
+// FirstFailure 返回第一项失败的检查，全部通过时返回 nil
+func (r *Report) FirstFailure() *Check {
+	for i := range r.Checks {
+		if r.Checks[i].Status == CheckFail {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+/* Diagnose 逐项检查许可文件并说明哪一项未通过
+ * 依次检查：机器码、文件、格式、解码、授权信息、签名、产品、机器码匹配、过期日期和吊销状态；
+ * 某项失败后依赖它的检查记为 skip
+ * @params: licensePath: 许可文件路径
+ *			opts: 诊断选项
+ * @return: *Report: 诊断报告，Valid 表示全部检查通过
+ */
+func Diagnose(licensePath string, opts DiagnoseOptions) *Report {
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	r := &Report{LicensePath: licensePath}
+	failed := false
+	add := func(name, status, detail, hint string) {
+		if failed && status != CheckFail {
+			status, detail, hint = CheckSkip, "skipped because an earlier check failed", ""
+		}
+		r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: detail, Hint: hint})
+		if status == CheckFail {
+			failed = true
+		}
+	}
+	skipRest := func(names ...string) {
+		for _, name := range names {
+			add(name, CheckSkip, "", "")
+		}
+	}
+
+	fp, err := utils.MachineFingerprint()
+	r.Fingerprint = fp
+	if err != nil {
+		add("machine code", CheckFail, err.Error(), "the machine code is taken from the first active non-loopback interface with an IPv4 address; bring one up")
+		skipRest("file", "format", "decode", "content", "signature", "product", "signature code", "expiration", "revocation")
+		return r
+	}
+	add("machine code", CheckPass, fmt.Sprintf("%s (interface %s, MAC %s)", fp.MachineCode, fp.Interface, fp.HardwareAddr), "")
+
+	content, err := ioutil.ReadFile(licensePath)
+	switch {
+	case os.IsNotExist(err):
+		add("file", CheckFail, licensePath+" does not exist", "install the license with the install command or set client.license_path")
+	case err != nil:
+		add("file", CheckFail, err.Error(), "check the file permissions")
+	default:
+		add("file", CheckPass, fmt.Sprintf("%s (%d bytes)", licensePath, len(content)), "")
+	}
+	if failed {
+		skipRest("format", "decode", "content", "signature", "product", "signature code", "expiration", "revocation")
+		return r
+	}
+
+	encoded := strings.TrimSpace(string(content))
+	decoded, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		add("format", CheckFail, "not a license file: "+err.Error(), "the file was changed or truncated, download it again")
+		skipRest("decode", "content", "signature", "product", "signature code", "expiration", "revocation")
+		return r
+	}
+	add("format", CheckPass, fmt.Sprintf("%d bytes after base64 decoding", len(decoded)), "")
+
+	info, err := DecodeLicenseContent([]byte(encoded))
+	if err != nil {
+		add("decode", CheckFail, "the license was not issued for this machine (machine code "+fp.MachineCode+")",
+			"the license belongs to another machine, is damaged, or this machine's network interface changed; request a new license with the activation-request command")
+		skipRest("content", "signature", "product", "signature code", "expiration", "revocation")
+		return r
+	}
+	add("decode", CheckPass, "decoded with this machine's code", "")
+
+	r.License = info
+	if info.Id == "" || info.Expiration == "" {
+		add("content", CheckFail, "the authorized section is incomplete", "download the license again")
+	} else {
+		detail := fmt.Sprintf("license %s, type %q, project %q, module %q, %s users", info.Id, info.Type, info.Project, info.Module, info.AllowedUsers)
+		if info.Licensee != "" {
+			detail += fmt.Sprintf(", licensed to %q", info.Licensee)
+		}
+		add("content", CheckPass, detail, "")
+	}
+
+	switch err := CheckSignature(info); {
+	case !SignatureRequired():
+		add("signature", CheckSkip, "no trusted public key configured (verification.public_keys)", "")
+	case errors.Is(err, ErrUnsigned):
+		add("signature", CheckFail, err.Error(), "ask the vendor to renew the license so that it is signed")
+	case err != nil:
+		add("signature", CheckFail, err.Error(), "the license was modified or signed by another server; download it again or check verification.public_keys")
+	default:
+		add("signature", CheckPass, "signed by trusted key "+info.KeyID(), "")
+	}
+
+	switch {
+	case opts.Product == "":
+		add("product", CheckSkip, "no product configured", "")
+	case CheckProduct(info, opts.Product) != nil:
+		add("product", CheckFail, fmt.Sprintf("license was issued for %q, this product is %q", info.Project, opts.Product),
+			"install the license issued for this product, or check client.product")
+	default:
+		add("product", CheckPass, fmt.Sprintf("license was issued for %q", info.Project), "")
+	}
+
+	if info.SignatureCode != fp.MachineCode {
+		add("signature code", CheckFail, fmt.Sprintf("license is bound to %s, this machine is %s", info.SignatureCode, fp.MachineCode), "request a new license for this machine")
+	} else {
+		add("signature code", CheckPass, "license is bound to this machine", "")
+	}
+
+	exp, err := time.Parse("2006-01-02", info.Expiration)
+	if err != nil {
+		add("expiration", CheckFail, fmt.Sprintf("invalid expiration date %q", info.Expiration), "download the license again")
+	} else {
+		y, m, d := now.UTC().Date()
+		days := int(exp.Sub(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+		switch {
+		case days < 0:
+			add("expiration", CheckFail, fmt.Sprintf("expired on %s (%d days ago)", info.Expiration, -days), "ask the vendor to renew the license")
+		case days < expiryWarning:
+			add("expiration", CheckWarn, fmt.Sprintf("expires on %s (%d days left)", info.Expiration, days), "ask the vendor to renew the license soon")
+		default:
+			add("expiration", CheckPass, fmt.Sprintf("expires on %s (%d days left)", info.Expiration, days), "")
+		}
+	}
+
+	switch {
+	case !opts.CheckRevocation || opts.ServerURL == "":
+		add("revocation", CheckSkip, "not configured (verification.check_revocation and client.server_url)", "")
+	default:
+		revocation, err := IsLicenseRevoked(opts.ServerURL, info.Id)
+		switch {
+		case err != nil && opts.AllowOffline:
+			add("revocation", CheckWarn, "server unreachable, allowed offline: "+err.Error(), "")
+		case err != nil:
+			add("revocation", CheckFail, "server unreachable: "+err.Error(), "check client.server_url and the network, or set verification.allow_offline")
+		case revocation != nil:
+			add("revocation", CheckFail, fmt.Sprintf("license is %s: %s", revocation.Status, revocation.Reason), "contact the vendor")
+		default:
+			add("revocation", CheckPass, "not revoked", "")
+		}
+	}
+
+	r.Valid = r.FirstFailure() == nil
+	return r
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"client/utils"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 许可文件的来源，按优先级从高到低排列
+const (
+	SourceExplicit = "explicit" // 配置或命令行明确指定的文件
+	SourceEnv      = "env"      // 环境变量 <PRODUCT>_LICENSE_PATH
+	SourceUser     = "user"     // 用户配置目录，$XDG_CONFIG_HOME/<product>
+	SourceSystem   = "system"   // 系统目录，/etc/<product>
+)
+
+// licenseExt 许可文件的扩展名，目录中只搜索此类文件
+const licenseExt = ".license"
+
+// LicenseLocation 搜索许可文件的位置
+type LicenseLocation struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`
+	IsDir  bool   `json:"isDir"`
+}
+
+// LicenseCandidate 搜索到的许可文件及其校验结果
+type LicenseCandidate struct {
+	Path     string       `json:"path"`
+	Source   string       `json:"source"`
+	License  *LicenseInfo `json:"license,omitempty"`
+	Valid    bool         `json:"valid"`
+	Problem  string       `json:"problem,omitempty"`
+	Selected bool         `json:"selected"`
+}
+
+/* LicenseEnvName 返回产品对应的许可文件路径环境变量，例如 license-tool 对应 LICENSE_TOOL_LICENSE_PATH
+ * @params: product: 产品名称
+ * @return: string: 环境变量名
+ */
+func LicenseEnvName(product string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, product)
+	return name + "_LICENSE_PATH"
+}
+
+/* UserLicenseDir 返回当前用户的许可文件目录：$XDG_CONFIG_HOME/<product>，未设置时为 ~/.config/<product>
+ * （Windows 为 %AppData%\<product>，macOS 为 ~/Library/Application Support/<product>）
+ * @params: product: 产品名称
+ * @return: string: 目录路径
+ *			error: 无法确定用户目录时返回错误对象；否则为 nil
+ */
+func UserLicenseDir(product string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, product), nil
+}
+
+/* SystemLicenseDir 返回系统级许可文件目录：/etc/<product>，Windows 为 %ProgramData%\<product>
+ * @params: product: 产品名称
+ * @return: string: 目录路径
+ */
+func SystemLicenseDir(product string) string {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("ProgramData"); dir != "" {
+			return filepath.Join(dir, product)
+		}
+		return filepath.Join(`C:\ProgramData`, product)
+	}
+	return filepath.Join("/etc", product)
+}
+
+/* LicenseLocations 按优先级返回搜索许可文件的位置
+ * 指定了 explicit 时只使用该文件；否则依次为环境变量 <PRODUCT>_LICENSE_PATH 中以路径分隔符分隔的文件或目录、
+ * 用户目录和系统目录
+ * @params: product: 产品名称
+ *			explicit: 明确指定的许可文件，可为空
+ * @return: []LicenseLocation: 搜索位置
+ */
+func LicenseLocations(product string, explicit string) []LicenseLocation {
+
+	if explicit != "" {
+		return []LicenseLocation{{Path: explicit, Source: SourceExplicit}}
+	}
+
+	var locations []LicenseLocation
+	for _, path := range filepath.SplitList(os.Getenv(LicenseEnvName(product))) {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		locations = append(locations, LicenseLocation{Path: path, Source: SourceEnv, IsDir: err == nil && info.IsDir()})
+	}
+	if dir, err := UserLicenseDir(product); err == nil {
+		locations = append(locations, LicenseLocation{Path: dir, Source: SourceUser, IsDir: true})
+	}
+	return append(locations, LicenseLocation{Path: SystemLicenseDir(product), Source: SourceSystem, IsDir: true})
+}
+
+/* DiscoverLicenses 搜索全部许可文件并逐一校验，结果按搜索位置的优先级排列，最佳的有效许可文件标记为 Selected
+ * 有效指能用本机机器码解码、签名有效（配置了可信公钥时）、为该产品签发（project 与产品名称相同，不区分大小写）、绑定到本机且未过期；多个有效许可文件中选择过期日期最晚的，相同时选择优先级高的
+ * @params: product: 产品名称
+ *			explicit: 明确指定的许可文件，可为空
+ *			now: 判断是否过期使用的当前时间
+ * @return: []LicenseCandidate: 搜索到的许可文件
+ */
+func DiscoverLicenses(product string, explicit string, now time.Time) []LicenseCandidate {
+
+	machineCode := ""
+	if fp, err := utils.MachineFingerprint(); err == nil {
+		machineCode = fp.MachineCode
+	}
+
+	var candidates []LicenseCandidate
+	seen := make(map[string]bool)
+	add := func(path string, source string) {
+		if abs, err := filepath.Abs(path); err == nil {
+			if seen[abs] {
+				return
+			}
+			seen[abs] = true
+		}
+		c := LicenseCandidate{Path: path, Source: source}
+		c.License, c.Problem = evaluateLicense(path, product, machineCode, now)
+		c.Valid = c.Problem == ""
+		candidates = append(candidates, c)
+	}
+
+	for _, loc := range LicenseLocations(product, explicit) {
+		if !loc.IsDir {
+			if _, err := os.Stat(loc.Path); err == nil || loc.Source == SourceExplicit {
+				add(loc.Path, loc.Source)
+			}
+			continue
+		}
+		entries, err := ioutil.ReadDir(loc.Path)
+		if err != nil {
+			continue
+		}
+		var names []string
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), licenseExt) {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(filepath.Join(loc.Path, name), loc.Source)
+		}
+	}
+
+	best := -1
+	for i, c := range candidates {
+		if c.Valid && (best < 0 || c.License.Expiration > candidates[best].License.Expiration) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		candidates[best].Selected = true
+	}
+	return candidates
+}
+
+/* FindLicense 返回最佳的有效许可文件
+ * @params: product: 产品名称
+ *			explicit: 明确指定的许可文件，可为空
+ * @return: *LicenseCandidate: 最佳的有效许可文件
+ *			[]LicenseCandidate: 搜索到的全部许可文件
+ *			error: 没有有效的许可文件时返回说明每个文件问题的错误对象；否则为 nil
+ */
+func FindLicense(product string, explicit string) (*LicenseCandidate, []LicenseCandidate, error) {
+
+	candidates := DiscoverLicenses(product, explicit, time.Now())
+	for i := range candidates {
+		if candidates[i].Selected {
+			return &candidates[i], candidates, nil
+		}
+	}
+	if len(candidates) == 0 {
+		var searched []string
+		for _, loc := range LicenseLocations(product, explicit) {
+			searched = append(searched, loc.Path)
+		}
+		return nil, nil, fmt.Errorf("no license found, searched %s", strings.Join(searched, ", "))
+	}
+	problems := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		problems = append(problems, fmt.Sprintf("%s: %s", c.Path, c.Problem))
+	}
+	return nil, candidates, fmt.Errorf("no valid license found:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// evaluateLicense 校验单个许可文件，返回授权信息和问题描述，有效时问题为空
+func evaluateLicense(path string, product string, machineCode string, now time.Time) (*LicenseInfo, string) {
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err.Error()
+	}
+	return evaluateLicenseContent(content, product, machineCode, now)
+}
+
+// evaluateLicenseContent 校验许可文件内容，返回授权信息和问题描述，有效时问题为空
+func evaluateLicenseContent(content []byte, product string, machineCode string, now time.Time) (*LicenseInfo, string) {
+
+	if machineCode == "" {
+		return nil, "the machine code of this machine cannot be determined"
+	}
+	info, err := DecodeLicenseContent([]byte(strings.TrimSpace(string(content))))
+	if err != nil {
+		return nil, "not issued for this machine"
+	}
+	if err := CheckSignature(info); err != nil {
+		return info, err.Error()
+	}
+	if err := CheckProduct(info, product); err != nil {
+		return info, err.Error()
+	}
+	if info.SignatureCode != machineCode {
+		return info, "bound to machine code " + info.SignatureCode
+	}
+	if err := CheckExpiration(info, now); err != nil {
+		if errors.Is(err, ErrLicenseExpired) {
+			return info, "expired on " + info.Expiration
+		}
+		return info, err.Error()
+	}
+	return info, ""
+}
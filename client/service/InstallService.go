@@ -0,0 +1,106 @@
+package service
+
+import (
+	"client/logger"
+	"client/utils"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// InstallOptions 许可文件的安装选项
+type InstallOptions struct {
+	Path    string // 安装到该文件；为空时安装到 Dir 下的 <许可证ID>.license
+	Dir     string // 安装目录，通常为 UserLicenseDir 或 SystemLicenseDir
+	Replace bool   // 安装成功后删除同一目录中的其他许可文件
+	Force   bool   // 跳过校验，安装不属于本机的许可文件；不能与 Replace 一起用于无效的许可文件
+	Product string // 产品名称，即客户端配置 client.product，许可证必须为该产品签发
+}
+
+/* InstallLicense 校验许可文件（签名、机器码、产品和有效期）后以原子方式安装，同名文件被原子替换；
+ * Replace 时在新文件就位之后才删除其他许可文件，因此任何时刻都至少有一个许可文件可用
+ * @params: source: 待安装的许可文件
+ *			opts: 安装选项，目标目录不存在时自动创建
+ * @return: string: 安装后的文件路径
+ *			*LicenseInfo: 许可文件中的授权信息，Force 且无法解码时为 nil
+ *			error: 读取、校验或写入失败时返回错误对象；否则为 nil
+ */
+func InstallLicense(source string, opts InstallOptions) (string, *LicenseInfo, error) {
+
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return "", nil, err
+	}
+	content = []byte(strings.TrimSpace(string(content)))
+
+	// 与查找许可文件时的校验相同：签名、机器码、产品和有效期，无法确定机器码时不会 panic
+	machineCode := ""
+	if fp, err := utils.MachineFingerprint(); err == nil {
+		machineCode = fp.MachineCode
+	}
+	info, problem := evaluateLicenseContent(content, opts.Product, machineCode, time.Now())
+	if problem != "" {
+		if !opts.Force {
+			return "", nil, fmt.Errorf("%s is not valid for this machine: %s", source, problem)
+		}
+		// Force 只允许安装，不允许用无效的许可文件替换掉其他许可文件
+		if opts.Replace {
+			return "", nil, fmt.Errorf("%s is not valid for this machine (%s), refusing to replace the other licenses", source, problem)
+		}
+		info = nil
+	}
+	// 许可证ID用作文件名，不能包含路径分隔符或跳出安装目录
+	if info != nil && !safeFileName(info.Id) {
+		return "", nil, fmt.Errorf("%s has an invalid license id %q", source, info.Id)
+	}
+
+	target := opts.Path
+	if target == "" {
+		name := filepath.Base(source)
+		if info != nil {
+			name = info.Id + licenseExt
+		}
+		target = filepath.Join(opts.Dir, name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", nil, err
+	}
+	if err := writeFileAtomic(target, content); err != nil {
+		return "", nil, err
+	}
+	logger.Infof("license installed to %s", target)
+
+	if opts.Replace {
+		if err := removeOtherLicenses(filepath.Dir(target), target); err != nil {
+			return target, info, err
+		}
+	}
+	return target, info, nil
+}
+
+// safeFileName 判断 name 能否直接用作安装目录中的文件名
+func safeFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`) && filepath.Base(name) == name
+}
+
+// removeOtherLicenses 删除目录中除 keep 之外的许可文件
+func removeOtherLicenses(dir string, keep string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() || !strings.HasSuffix(e.Name(), licenseExt) || path == filepath.Clean(keep) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		logger.Infof("license %s replaced by %s", path, keep)
+	}
+	return nil
+}
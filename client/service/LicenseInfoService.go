@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bytes"
+	"client/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// ErrLicenseExpired 许可证已过期
+var ErrLicenseExpired = errors.New("license has expired")
+
+// ErrProductMismatch 许可证不是为该产品签发的
+var ErrProductMismatch = errors.New("license was not issued for this product")
+
+// LicenseInfo 许可文件中的授权信息
+type LicenseInfo struct {
+	Id            string `json:"id"`
+	License       string `json:"license"`
+	Date          string `json:"date"`
+	SignatureCode string `json:"signatureCode"`
+	Type          string `json:"type"`
+	Expiration    string `json:"expiration"`
+	AllowedUsers  string `json:"usersNum"`
+	Project       string `json:"project"`
+	Module        string `json:"module"`
+	// Licensee 被许可方名称，旧版服务端签发的许可文件中没有该字段
+	Licensee string `json:"licensee,omitempty"`
+
+	// signed 为许可文件中 authorized 对象的原始字节，keyID 和 signature 为其签名，由 CheckSignature 验证
+	signed    []byte
+	keyID     string
+	signature string
+}
+
+/* KeyID 返回签名许可文件的密钥ID，未签名时为空
+ * @return: string: 密钥ID
+ */
+func (info *LicenseInfo) KeyID() string {
+	return info.keyID
+}
+
+/* DecodeLicenseContent 使用本机机器码反混淆许可文件内容，并解析其中的授权信息
+ * @params: licenseContent: 许可文件的原始内容（混淆后的密文）
+ * @return: *LicenseInfo: 授权信息
+ *			error: 反混淆或解析失败时返回错误对象；否则为 nil
+ */
+func DecodeLicenseContent(licenseContent []byte) (*LicenseInfo, error) {
+
+	outputBytes, err := utils.DeobfuscationUtil(strings.TrimSpace(string(licenseContent)), utils.MachineCode())
+	if err != nil {
+		return nil, err
+	}
+
+	// 有效内容之后是随机填充字节，只解析第一个完整的 JSON 对象；签名覆盖 authorized 的原始字节，因此保留原始字节
+	var data struct {
+		Authorized json.RawMessage `json:"authorized"`
+		KeyID      string          `json:"keyId"`
+		Signature  string          `json:"signature"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(outputBytes)).Decode(&data); err != nil {
+		return nil, errors.New("license data not found, the license may belong to another machine")
+	}
+	var info LicenseInfo
+	if len(data.Authorized) == 0 || json.Unmarshal(data.Authorized, &info) != nil {
+		return nil, errors.New("failed to extract authorized object from license")
+	}
+	info.signed, info.keyID, info.signature = data.Authorized, data.KeyID, data.Signature
+	return &info, nil
+}
+
+/* LicenseID 读取许可文件并返回其中的许可证ID
+ * @params: licenseName: 许可文件名
+ * @return: string: 许可证ID
+ *			error: 读取或解析失败时返回错误对象；否则为 nil
+ */
+func LicenseID(licenseName string) (string, error) {
+
+	content, err := ioutil.ReadFile(licenseName)
+	if err != nil {
+		return "", err
+	}
+	info, err := DecodeLicenseContent(content)
+	if err != nil {
+		return "", err
+	}
+	return info.Id, nil
+}
+
+/* CheckExpiration 检查许可证在 now 所在的日期（UTC）是否仍然有效，过期日期当天仍然有效
+ * @params: info: 授权信息
+ *			now: 当前时间
+ * @return: error: 过期时返回包装了 ErrLicenseExpired 的错误对象，日期无效时返回错误对象；否则为 nil
+ */
+func CheckExpiration(info *LicenseInfo, now time.Time) error {
+
+	exp, err := time.Parse("2006-01-02", info.Expiration)
+	if err != nil {
+		return fmt.Errorf("invalid expiration date %q", info.Expiration)
+	}
+	y, m, d := now.UTC().Date()
+	if exp.Before(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)) {
+		return fmt.Errorf("%w: expired on %s", ErrLicenseExpired, info.Expiration)
+	}
+	return nil
+}
+
+/* CheckProduct 检查许可证是否为该产品签发：project 与产品名称相同，不区分大小写
+ * @params: info: 授权信息
+ *			product: 产品名称，即客户端配置 client.product
+ * @return: error: 不匹配时返回包装了 ErrProductMismatch 的错误对象；否则为 nil
+ */
+func CheckProduct(info *LicenseInfo, product string) error {
+
+	if !strings.EqualFold(info.Project, product) {
+		return fmt.Errorf("%w: issued for %q, this product is %q", ErrProductMismatch, info.Project, product)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"client/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RefreshEvent 后台刷新过程中发生的许可变更类型
+type RefreshEvent int
+
+const (
+	// RefreshUpdated 服务器返回了新的许可文件，本地文件已被替换
+	RefreshUpdated RefreshEvent = iota
+	// RefreshRevoked 服务器告知许可已被吊销（HTTP 410），本地文件保持不变，由宿主程序决定如何处理
+	RefreshRevoked
+)
+
+// LicenseTokenHeader 下载许可文件时携带许可证令牌的请求头，服务器据此确认请求方持有许可文件
+const LicenseTokenHeader = "X-License-Token"
+
+const (
+	defaultRefreshInterval = 24 * time.Hour
+	defaultMinBackoff      = 30 * time.Second
+	defaultMaxBackoff      = 6 * time.Hour
+)
+
+// LicenseRefresher 在后台定期从许可证服务器下载最新的许可文件，并以原子方式替换磁盘上的文件
+type LicenseRefresher struct {
+	URL         string        // 许可文件下载地址，例如 http://host:8080/licenses/{id}/file
+	LicensePath string        // 本地许可文件路径
+	Interval    time.Duration // 正常情况下的刷新间隔
+	MinBackoff  time.Duration // 离线时第一次重试的等待时间
+	MaxBackoff  time.Duration // 离线时指数退避的最大等待时间
+	Client      *http.Client  // 下载使用的 HTTP 客户端
+	Token       string        // 许可证令牌，随 X-License-Token 发送；为空时从本地许可文件读取
+
+	// Validate 在替换本地文件前校验下载到的内容，默认使用 VerifyLicenseContent
+	Validate func(content []byte) error
+	// OnChange 许可文件被更新或吊销时回调
+	OnChange func(event RefreshEvent, path string)
+	// OnError 刷新失败时回调
+	OnError func(err error)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+/*
+ * NewLicenseRefresher 创建许可刷新器
+ * @params: url: 许可文件下载地址
+ *			licensePath: 本地许可文件路径
+ *			interval: 刷新间隔，为 0 时使用默认值（24 小时）
+ * @return: *LicenseRefresher: 刷新器实例，调用 Start 后开始在后台运行
+ */
+func NewLicenseRefresher(url string, licensePath string, interval time.Duration) *LicenseRefresher {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	return &LicenseRefresher{
+		URL:         url,
+		LicensePath: licensePath,
+		Interval:    interval,
+		MinBackoff:  defaultMinBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Start 启动后台刷新，重复调用无效
+func (r *LicenseRefresher) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.loop(r.stop, r.done)
+}
+
+// Stop 停止后台刷新并等待正在进行的刷新结束
+func (r *LicenseRefresher) Stop() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (r *LicenseRefresher) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	backoff := time.Duration(0)
+	for {
+		wait := r.Interval
+		if _, err := r.Refresh(); err != nil {
+			logger.Warnf("license refresh from %s failed: %v", r.URL, err)
+			if r.OnError != nil {
+				r.OnError(err)
+			}
+			// 离线或服务器异常时按指数退避重试
+			if backoff == 0 {
+				backoff = r.MinBackoff
+			} else {
+				backoff *= 2
+			}
+			if backoff > r.MaxBackoff {
+				backoff = r.MaxBackoff
+			}
+			wait = backoff
+		} else {
+			backoff = 0
+		}
+
+		// 加入少量抖动，避免大量客户端同时请求服务器
+		if wait > 0 {
+			wait += time.Duration(rand.Int63n(int64(wait)/10 + 1))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+/*
+ * Refresh 立即执行一次刷新，本地文件存在时发送 If-None-Match，服务器返回 304 表示许可文件没有变化
+ * @return: bool: 本地许可文件是否被替换
+ *			error: 下载、校验或写入失败时返回错误；否则为 nil
+ */
+func (r *LicenseRefresher) Refresh() (bool, error) {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	current, err := ioutil.ReadFile(r.LicensePath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	// 服务端的 ETag 为许可文件内容的 SHA-256，本地文件未变化时服务器返回 304，不必重新下载
+	if current != nil {
+		req.Header.Set("If-None-Match", licenseETag(current))
+	}
+	// 服务器只向持有许可文件的客户端提供下载，许可证令牌取自本地许可文件
+	if token := r.token(current); token != "" {
+		req.Header.Set(LicenseTokenHeader, token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusGone:
+		logger.Warnf("license %s has been revoked by the server", r.LicensePath)
+		if r.OnChange != nil {
+			r.OnChange(RefreshRevoked, r.LicensePath)
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("license refresh failed: unexpected status %s", resp.Status)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	if string(current) == string(content) {
+		return false, nil
+	}
+
+	if err := r.validate(content); err != nil {
+		return false, fmt.Errorf("license refresh rejected: %w", err)
+	}
+
+	if err := writeFileAtomic(r.LicensePath, content); err != nil {
+		return false, err
+	}
+
+	logger.Infof("license %s updated from %s", r.LicensePath, r.URL)
+	if r.OnChange != nil {
+		r.OnChange(RefreshUpdated, r.LicensePath)
+	}
+	return true, nil
+}
+
+// token 返回下载时证明持有许可文件的令牌，Token 为空时使用本地许可文件中的许可证令牌
+func (r *LicenseRefresher) token(current []byte) string {
+	if r.Token != "" {
+		return r.Token
+	}
+	if current == nil {
+		return ""
+	}
+	info, err := DecodeLicenseContent(current)
+	if err != nil {
+		return ""
+	}
+	return info.License
+}
+
+// licenseETag 按服务端的方法计算许可文件内容的 ETag
+func licenseETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (r *LicenseRefresher) validate(content []byte) error {
+	if r.Validate != nil {
+		return r.Validate(content)
+	}
+	ok, err := VerifyLicenseContent(content)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("downloaded license is not valid for this machine")
+	}
+	return nil
+}
+
+/*
+ * writeFileAtomic 先写入同目录下的临时文件，再通过 rename 原子替换目标文件
+ * @params: path: 目标文件路径
+ *			content: 文件内容
+ * @return: error: 写入失败时返回错误；否则为 nil
+ */
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"bytes"
+	"client/logger"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// licenseServer 模拟许可证服务器的许可文件下载接口
+type licenseServer struct {
+	mutex    sync.Mutex
+	content  []byte
+	status   int // 不为 0 时直接返回该状态码
+	requests []*http.Request
+	times    []time.Time
+}
+
+func (s *licenseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = append(s.requests, r)
+	s.times = append(s.times, time.Now())
+	if s.status != 0 {
+		http.Error(w, http.StatusText(s.status), s.status)
+		return
+	}
+	etag := licenseETag(s.content)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write(s.content)
+}
+
+func (s *licenseServer) set(status int, content string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = status
+	if content != "" {
+		s.content = []byte(content)
+	}
+}
+
+func (s *licenseServer) requestTimes() []time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]time.Time(nil), s.times...)
+}
+
+func (s *licenseServer) lastRequest() *http.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+// refreshEvent 记录一次 OnChange 回调
+type refreshEvent struct {
+	event RefreshEvent
+	path  string
+}
+
+// newTestRefresher 创建指向模拟服务器的刷新器，校验只接受以 "LICENSE" 开头的内容
+func newTestRefresher(t *testing.T, server *licenseServer) (*LicenseRefresher, *[]refreshEvent) {
+	t.Helper()
+	previous := logger.Get()
+	logger.SetLogger(nil)
+	t.Cleanup(func() { logger.SetLogger(previous) })
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	var (
+		mutex  sync.Mutex
+		events []refreshEvent
+	)
+	r := NewLicenseRefresher(ts.URL+"/licenses/1234567890123456/file", filepath.Join(t.TempDir(), "app.license"), time.Hour)
+	r.Validate = func(content []byte) error {
+		if !bytes.HasPrefix(content, []byte("LICENSE")) {
+			return errors.New("not a license")
+		}
+		return nil
+	}
+	r.OnChange = func(event RefreshEvent, path string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, refreshEvent{event, path})
+	}
+	return r, &events
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestRefreshStatuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		local       string // 为空时本地没有许可文件
+		status      int
+		remote      string
+		wantUpdated bool
+		wantErr     bool
+		wantEvents  []RefreshEvent
+		wantLocal   string
+	}{
+		{name: "200 installs a new license", remote: "LICENSE v1",
+			wantUpdated: true, wantEvents: []RefreshEvent{RefreshUpdated}, wantLocal: "LICENSE v1"},
+		{name: "200 replaces a renewed license", local: "LICENSE v1", remote: "LICENSE v2",
+			wantUpdated: true, wantEvents: []RefreshEvent{RefreshUpdated}, wantLocal: "LICENSE v2"},
+		{name: "304 keeps the local license", local: "LICENSE v1", remote: "LICENSE v1",
+			wantLocal: "LICENSE v1"},
+		{name: "410 reports revocation and keeps the file", local: "LICENSE v1", status: http.StatusGone,
+			wantEvents: []RefreshEvent{RefreshRevoked}, wantLocal: "LICENSE v1"},
+		{name: "500 is an error", local: "LICENSE v1", status: http.StatusInternalServerError,
+			wantErr: true, wantLocal: "LICENSE v1"},
+		{name: "invalid download is rejected", local: "LICENSE v1", remote: "garbage",
+			wantErr: true, wantLocal: "LICENSE v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &licenseServer{}
+			server.set(tt.status, tt.remote)
+			r, events := newTestRefresher(t, server)
+			if tt.local != "" {
+				if err := ioutil.WriteFile(r.LicensePath, []byte(tt.local), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			updated, err := r.Refresh()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Refresh() error = %v, want error %v", err, tt.wantErr)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("Refresh() updated = %v, want %v", updated, tt.wantUpdated)
+			}
+
+			if len(*events) != len(tt.wantEvents) {
+				t.Fatalf("OnChange called %d times, want %d", len(*events), len(tt.wantEvents))
+			}
+			for i, e := range *events {
+				if e.event != tt.wantEvents[i] || e.path != r.LicensePath {
+					t.Errorf("OnChange(%v, %q), want (%v, %q)", e.event, e.path, tt.wantEvents[i], r.LicensePath)
+				}
+			}
+			if got := readFile(t, r.LicensePath); got != tt.wantLocal {
+				t.Errorf("local license = %q, want %q", got, tt.wantLocal)
+			}
+
+			wantETag := ""
+			if tt.local != "" {
+				wantETag = licenseETag([]byte(tt.local))
+			}
+			if got := server.lastRequest().Header.Get("If-None-Match"); got != wantETag {
+				t.Errorf("If-None-Match = %q, want %q", got, wantETag)
+			}
+		})
+	}
+}
+
+func TestRefreshReplacesAtomically(t *testing.T) {
+	server := &licenseServer{}
+	server.set(0, "LICENSE v2")
+	r, _ := newTestRefresher(t, server)
+	if err := ioutil.WriteFile(r.LicensePath, []byte("LICENSE v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 替换前打开的文件描述符仍然读到旧内容，说明新文件是 rename 到原路径，而不是原地改写
+	old, err := os.Open(r.LicensePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = old.Close()
+	}()
+
+	if _, err := r.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, r.LicensePath); got != "LICENSE v2" {
+		t.Errorf("local license = %q, want %q", got, "LICENSE v2")
+	}
+	content, err := ioutil.ReadAll(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "LICENSE v1" {
+		t.Errorf("previously opened file reads %q, want the old content", content)
+	}
+
+	info, err := os.Stat(r.LicensePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("license mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// 目录中只剩许可文件本身，没有遗留的临时文件
+	entries, err := ioutil.ReadDir(filepath.Dir(r.LicensePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory contains %v, want only the license file", names)
+	}
+}
+
+func TestRefreshWriteFailure(t *testing.T) {
+	server := &licenseServer{}
+	server.set(0, "LICENSE v2")
+	r, events := newTestRefresher(t, server)
+
+	// 许可文件所在的目录不存在时无法创建临时文件，刷新失败且不回调
+	r.LicensePath = filepath.Join(t.TempDir(), "missing", "app.license")
+	if _, err := r.Refresh(); err == nil {
+		t.Fatal("Refresh() succeeded without a writable directory")
+	}
+	if len(*events) != 0 {
+		t.Errorf("OnChange called %d times after a failed write", len(*events))
+	}
+}
+
+func TestRefresherBackoff(t *testing.T) {
+	server := &licenseServer{}
+	server.set(http.StatusServiceUnavailable, "LICENSE v1")
+	r, events := newTestRefresher(t, server)
+	r.MinBackoff = 20 * time.Millisecond
+	r.MaxBackoff = 80 * time.Millisecond
+
+	var (
+		mutex  sync.Mutex
+		errs   int
+		failed = make(chan struct{}, 16)
+	)
+	r.OnError = func(err error) {
+		mutex.Lock()
+		errs++
+		n := errs
+		mutex.Unlock()
+		if n == 5 {
+			// 第 5 次失败后服务器恢复，下一次重试成功后按正常间隔（1 小时）等待
+			server.set(0, "")
+		}
+		failed <- struct{}{}
+	}
+
+	r.Start()
+	defer r.Stop()
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-failed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d refresh attempts failed", i)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.requestTimes()) < 6 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	r.Stop()
+
+	times := server.requestTimes()
+	if len(times) != 6 {
+		t.Fatalf("server received %d requests, want 5 failures and 1 success", len(times))
+	}
+	// 重试间隔从 MinBackoff 开始加倍，不超过 MaxBackoff
+	for i, want := range []time.Duration{20, 40, 80, 80, 80} {
+		want *= time.Millisecond
+		if gap := times[i+1].Sub(times[i]); gap < want {
+			t.Errorf("retry %d after %v, want at least %v", i+1, gap, want)
+		}
+	}
+	if got := readFile(t, r.LicensePath); got != "LICENSE v1" {
+		t.Errorf("local license = %q after recovery", got)
+	}
+	if len(*events) != 1 || (*events)[0].event != RefreshUpdated {
+		t.Errorf("OnChange events = %v, want one update", *events)
+	}
+}
+
+func TestRefresherStop(t *testing.T) {
+	server := &licenseServer{}
+	server.set(0, "LICENSE v1")
+	r, _ := newTestRefresher(t, server)
+
+	r.Start()
+	r.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.requestTimes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.Stop()
+	r.Stop()
+
+	n := len(server.requestTimes())
+	time.Sleep(50 * time.Millisecond)
+	if n != 1 || len(server.requestTimes()) != n {
+		t.Errorf("server received %d requests, want exactly 1 before and none after Stop", len(server.requestTimes()))
+	}
+}
+
+func TestRefreshSendsLicenseToken(t *testing.T) {
+	server := &licenseServer{}
+	server.set(0, "LICENSE v1")
+	r, _ := newTestRefresher(t, server)
+	r.Token = "token-from-license-file"
+
+	if _, err := r.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.lastRequest().Header.Get(LicenseTokenHeader); got != r.Token {
+		t.Errorf("%s = %q, want %q", LicenseTokenHeader, got, r.Token)
+	}
+}
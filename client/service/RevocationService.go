@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Revocation 吊销列表中的一项，被暂停的许可证同样会出现在列表中
+type Revocation struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	Date   string `json:"date"`
+}
+
+/* FetchRevocations 从许可证服务器获取吊销列表
+ * @params: serverURL: 许可证服务器地址，例如 http://host:8080
+ * @return: []Revocation: 吊销列表
+ *			error: 请求失败或服务器返回错误时返回错误对象；否则为 nil
+ */
+func FetchRevocations(serverURL string) ([]Revocation, error) {
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(strings.TrimRight(serverURL, "/") + "/revocations")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch revocations failed: unexpected status %s", resp.Status)
+	}
+
+	var msg struct {
+		Revocations []Revocation `json:"revocations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, err
+	}
+	return msg.Revocations, nil
+}
+
+/* IsLicenseRevoked 检查许可证是否出现在服务器的吊销列表中
+ * @params: serverURL: 许可证服务器地址
+ *			licenseID: 许可证ID
+ * @return: *Revocation: 许可证被吊销或暂停时返回对应的吊销项；否则为 nil
+ *			error: 获取吊销列表失败时返回错误对象；否则为 nil
+ */
+func IsLicenseRevoked(serverURL string, licenseID string) (*Revocation, error) {
+
+	revocations, err := FetchRevocations(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revocations {
+		if revocations[i].Id == licenseID {
+			return &revocations[i], nil
+		}
+	}
+	return nil, nil
+}
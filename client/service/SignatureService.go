@@ -0,0 +1,103 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnsigned 配置了可信公钥，但许可文件没有签名
+var ErrUnsigned = errors.New("license is not signed")
+
+// ErrBadSignature 许可文件的签名无效或由未知的密钥签名
+var ErrBadSignature = errors.New("license signature is not valid")
+
+// embeddedPublicKeys 构建时写入的可信公钥，以逗号分隔的 base64 编码 Ed25519 公钥，例如
+// go build -ldflags "-X client/service.embeddedPublicKeys=<公钥>"
+var embeddedPublicKeys string
+
+var (
+	trustedMutex sync.RWMutex
+	trustedKeys  map[string]ed25519.PublicKey // 密钥ID到公钥的映射
+)
+
+func init() {
+	if err := SetTrustedKeys(""); err != nil {
+		panic("invalid embedded public keys: " + err.Error())
+	}
+}
+
+/* SetTrustedKeys 设置验证许可文件签名使用的可信公钥，构建时写入的公钥始终可信
+ * 没有任何可信公钥时不检查签名，以兼容未启用签名的服务端签发的许可文件
+ * @params: keys: 以逗号分隔的 base64 编码 Ed25519 公钥，即服务端 GET /signing-key 返回的 publicKey
+ * @return: error: 公钥无效时返回错误对象；否则为 nil
+ */
+func SetTrustedKeys(keys string) error {
+
+	parsed := make(map[string]ed25519.PublicKey)
+	for _, value := range strings.Split(embeddedPublicKeys+","+keys, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid Ed25519 public key %q", value)
+		}
+		parsed[signingKeyID(raw)] = raw
+	}
+
+	trustedMutex.Lock()
+	defer trustedMutex.Unlock()
+	trustedKeys = parsed
+	return nil
+}
+
+/* SignatureRequired 是否配置了可信公钥，配置后只接受由其中某个公钥签名的许可文件
+ * @return: bool: 是否配置了可信公钥
+ */
+func SignatureRequired() bool {
+	trustedMutex.RLock()
+	defer trustedMutex.RUnlock()
+
+	return len(trustedKeys) > 0
+}
+
+/* CheckSignature 使用可信公钥验证许可文件对授权信息的 Ed25519 签名，签名覆盖许可文件中 authorized 对象的原始字节
+ * 没有配置可信公钥时不检查
+ * @params: info: DecodeLicenseContent 返回的授权信息
+ * @return: error: 没有签名时返回包装了 ErrUnsigned 的错误对象，签名无效或密钥不可信时返回包装了 ErrBadSignature 的错误对象；否则为 nil
+ */
+func CheckSignature(info *LicenseInfo) error {
+
+	trustedMutex.RLock()
+	keys := trustedKeys
+	trustedMutex.RUnlock()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if info.signature == "" {
+		return fmt.Errorf("%w: it was issued before the server enabled license signing, ask the vendor to renew it", ErrUnsigned)
+	}
+	key, ok := keys[info.keyID]
+	if !ok {
+		return fmt.Errorf("%w: signed by untrusted key %q", ErrBadSignature, info.keyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(info.signature)
+	if err != nil || !ed25519.Verify(key, info.signed, sig) {
+		return fmt.Errorf("%w: the authorized section was modified", ErrBadSignature)
+	}
+	return nil
+}
+
+// signingKeyID 公钥的密钥ID：sha256(公钥) 的前 8 个字节（16 位十六进制），与服务端的计算方法相同
+func signingKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
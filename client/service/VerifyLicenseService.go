@@ -1,13 +1,13 @@
 package service
 
 import (
+	"client/logger"
 	"client/utils"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"regexp"
+	"time"
 )
 
 /* VerifyLicense 验证许可文件
@@ -17,69 +17,60 @@ import (
  */
 func VerifyLicense(licenseName string) (bool, error) {
 
-	var jsonString string
-
 	// 打开许可文件
 	ciphertext, err := os.Open(licenseName)
 	if err != nil {
-		panic(err)
+		logger.Errorf("failed to open license file %s: %v", licenseName, err)
+		return false, err
 	}
 
 	// 异常处理
 	defer func(ciphertext *os.File) {
 		err := ciphertext.Close()
 		if err != nil {
+			logger.Warnf("failed to close license file %s: %v", licenseName, err)
 		}
 	}(ciphertext)
 
 	// 读取许可文件内容
 	licenseContent, err := ioutil.ReadAll(ciphertext)
 	if err != nil {
-		panic(err)
-	}
-
-	outputBytes, err := utils.DeobfuscationUtil(string(licenseContent), utils.MachineCode())
-	if err != nil {
-		fmt.Println("Error deobfuscating data:", err)
-		return false, nil
-	} else {
-		re := regexp.MustCompile(`{(.*)}`)
-		jsonString = re.FindStringSubmatch(string(outputBytes))[0]
-		if err != nil {
-			panic(err)
-		}
-		fmt.Println("Deobfuscated bytes:", jsonString)
+		logger.Errorf("failed to read license file %s: %v", licenseName, err)
+		return false, err
 	}
 
-	// 提取 signatureCode 值
-	var (
-		data map[string]interface{}
-	)
+	return VerifyLicenseContent(licenseContent)
+}
 
-	if err := json.Unmarshal([]byte(jsonString), &data); err != nil {
-		return false, err
-	}
+/* VerifyLicenseContent 验证许可文件内容：能用本机机器码解码、签名有效（配置了可信公钥时）、绑定到本机且未过期
+ * @params: licenseContent: 许可文件的原始内容（混淆后的密文）
+ * @return: true 表示许可内容有效，false 表示许可内容无效
+ *			error: 验证失败，则返回一个错误对象；否则为 nil
+ */
+func VerifyLicenseContent(licenseContent []byte) (bool, error) {
 
-	// 将 data["authorized"] 转换为 map[string]interface{} 类型，并赋值给 authorized 变量。
-	// ok 的值为 true 表示转换成功，否则表示转换失败，需要返回错误信息。
-	authorized, ok := data["authorized"].(map[string]interface{})
-	if !ok {
-		return false, errors.New("failed to extract authorized object from license")
+	info, err := DecodeLicenseContent(licenseContent)
+	if err != nil {
+		logger.Errorf("error decoding license: %v", err)
+		return false, fmt.Errorf("license verification failed: %w", err)
 	}
+	logger.Debugf("decoded license %s", info.Id)
 
-	// 将 authorized["signatureCode"] 转换为 string 类型，并赋值给 signatureCode 变量。
-	// ok 的值为 true 表示转换成功，否则表示转换失败，需要返回错误信息。
-	signatureCode, ok := authorized["signatureCode"].(string)
-	if !ok {
-		return false, errors.New("failed to extract signatureCode from license")
+	if err := CheckSignature(info); err != nil {
+		logger.Warnf("license verification failed: %v", err)
+		return false, fmt.Errorf("license verification failed: %w", err)
 	}
 
 	// 检查 signatureCode 和 MachineCode 是否匹配
-	machineCode := utils.MachineCode()
-	if machineCode != signatureCode {
+	if info.SignatureCode != utils.MachineCode() {
+		logger.Warnf("license verification failed: signatureCode does not match MachineCode")
 		return false, errors.New("license verification failed: signatureCode does not match MachineCode")
-	} else {
-		fmt.Println("license verification succeeded!")
-		return true, nil
 	}
+
+	if err := CheckExpiration(info, time.Now()); err != nil {
+		logger.Warnf("license verification failed: %v", err)
+		return false, fmt.Errorf("license verification failed: %w", err)
+	}
+	logger.Infof("license verification succeeded")
+	return true, nil
 }
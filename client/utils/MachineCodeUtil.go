@@ -4,40 +4,85 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"net"
+	"os"
+	"runtime"
 )
 
+// NetInterface 参与机器码计算的网络接口信息
+type NetInterface struct {
+	Name         string   `json:"name"`
+	HardwareAddr string   `json:"hardwareAddr"`
+	IPv4         []string `json:"ipv4,omitempty"`
+	Up           bool     `json:"up"`
+	Loopback     bool     `json:"loopback"`
+	Selected     bool     `json:"selected"` // 是否为计算机器码使用的接口
+}
+
+// Fingerprint 机器码及其组成部分
+type Fingerprint struct {
+	MachineCode  string         `json:"machineCode"`  // 摘要的前 32 位
+	Algorithm    string         `json:"algorithm"`    // 摘要算法
+	Digest       string         `json:"digest"`       // 完整摘要
+	Interface    string         `json:"interface"`    // 使用的网络接口
+	HardwareAddr string         `json:"hardwareAddr"` // 参与摘要计算的 MAC 地址
+	Hostname     string         `json:"hostname"`     // 以下信息仅用于诊断，不参与计算
+	OS           string         `json:"os"`
+	Arch         string         `json:"arch"`
+	Interfaces   []NetInterface `json:"interfaces"`
+}
+
 /*
- * getMacAddr 用于获取本地网络接口的MAC地址
- * @return: success 返回MAC地址及nil
- *			failed  返回空字符串及错误信息
- * @params: null
+ * MachineFingerprint 计算机器码并给出其组成部分
+ * 机器码取自第一个已启用、非回环且配置了 IPv4 地址的网络接口的 MAC 地址的 SHA-256 摘要
+ * @return: *Fingerprint: 机器码及各组成部分，没有可用接口时 MachineCode 为空
+ *			error: 无法枚举网络接口或没有可用接口时返回错误对象；否则为 nil
  */
-func getMacAddr() (string, error) {
+func MachineFingerprint() (*Fingerprint, error) {
+	fp := &Fingerprint{Algorithm: "sha256", OS: runtime.GOOS, Arch: runtime.GOARCH}
+	fp.Hostname, _ = os.Hostname()
+
 	ifs, err := net.Interfaces()
 	if err != nil {
-		return "", err
+		return fp, err
 	}
 	for _, ifi := range ifs {
-		if ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagLoopback == 0 {
-			address, err := ifi.Addrs()
-			if err != nil {
-				return "", err
+		ni := NetInterface{
+			Name:         ifi.Name,
+			HardwareAddr: ifi.HardwareAddr.String(),
+			Up:           ifi.Flags&net.FlagUp != 0,
+			Loopback:     ifi.Flags&net.FlagLoopback != 0,
+		}
+		address, err := ifi.Addrs()
+		if err != nil && fp.Interface == "" && ni.Up && !ni.Loopback {
+			return fp, err
+		}
+		for _, addr := range address {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
 			}
-			for _, addr := range address {
-				switch v := addr.(type) {
-				case *net.IPNet:
-					if v.IP.To4() != nil {
-						return ifi.HardwareAddr.String(), nil
-					}
-				case *net.IPAddr:
-					if v.IP.To4() != nil {
-						return ifi.HardwareAddr.String(), nil
-					}
-				}
+			if ip.To4() != nil {
+				ni.IPv4 = append(ni.IPv4, ip.String())
 			}
 		}
+		if fp.Interface == "" && ni.Up && !ni.Loopback && len(ni.IPv4) > 0 {
+			ni.Selected = true
+			fp.Interface = ni.Name
+			fp.HardwareAddr = ni.HardwareAddr
+		}
+		fp.Interfaces = append(fp.Interfaces, ni)
 	}
-	return "", fmt.Errorf("failed to get mac address")
+	if fp.Interface == "" {
+		return fp, fmt.Errorf("failed to get mac address")
+	}
+
+	hash := sha256.Sum256([]byte(fp.HardwareAddr))
+	fp.Digest = fmt.Sprintf("%x", hash)
+	fp.MachineCode = fp.Digest[:32]
+	return fp, nil
 }
 
 /*
@@ -47,11 +92,9 @@ func getMacAddr() (string, error) {
  * @params: null
  */
 func MachineCode() string {
-	macAddr, err := getMacAddr()
+	fp, err := MachineFingerprint()
 	if err != nil {
 		panic(err)
 	}
-	hash := sha256.Sum256([]byte(macAddr))
-	machineCode := fmt.Sprintf("%x", hash)[:32]
-	return machineCode
+	return fp.MachineCode
 }
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+/*
+ * Bind 按结构体标签把配置绑定到结构体，并一次性报告全部问题
+ * 支持的标签：
+ *	config:"name"   键名；结构体类型的字段把 name 作为其内部键的前缀（以 . 连接），"-" 表示忽略该字段
+ *	default:"value" 键不存在时使用的默认值
+ *	required:"true" 键必须存在于配置中
+ *	min:"n" max:"n" 数值和时长的取值范围，字符串为长度范围
+ *	oneof:"a b c"   取值必须是以空格分隔的候选值之一
+ *	secret:"true"   值为敏感信息，WriteEffective 输出时隐藏
+ * 支持的字段类型：string、bool、各类整数和浮点数、time.Duration，以及以逗号分隔的 []string
+ * 没有 config 标签的字段会被忽略
+ * @params: target interface{} - 指向结构体的指针
+ * @returns: error - 存在问题时返回 *ParseError，其中每一项都带有文件名和行号
+ */
+func (c *Config) Bind(target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Bind requires a non-nil pointer to a struct, got %T", target)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	b := binder{c: c}
+	walkFields(v.Elem(), "", b.bindField)
+	if len(b.problems) > 0 {
+		return &ParseError{Problems: b.problems}
+	}
+	return nil
+}
+
+type binder struct {
+	c        *Config
+	problems []string
+}
+
+// walkFields 遍历带 config 标签的字段，对每个叶子字段调用 fn，key 为完整的点分键名
+func walkFields(v reflect.Value, prefix string, fn func(v reflect.Value, field reflect.StructField, key string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup("config")
+		if !ok || name == "-" || !field.IsExported() {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			walkFields(v.Field(i), key, fn)
+			continue
+		}
+		fn(v.Field(i), field, key)
+	}
+}
+
+func (b *binder) bindField(v reflect.Value, field reflect.StructField, key string) {
+	raw, location := "", ""
+	if e, ok := b.c.entries[key]; ok {
+		raw = e.raw
+		location = e.origin + ": "
+	} else if def, ok := field.Tag.Lookup("default"); ok {
+		raw = def
+		location = fmt.Sprintf("default for %s: ", field.Name)
+	} else {
+		if field.Tag.Get("required") == "true" {
+			b.problems = append(b.problems, fmt.Sprintf("%s: missing required key %s", b.source(), key))
+		}
+		return
+	}
+
+	if err := setValue(v, raw); err != nil {
+		b.problems = append(b.problems, fmt.Sprintf("%skey %s: %v", location, key, err))
+		return
+	}
+	if err := validate(v, field.Tag); err != nil {
+		b.problems = append(b.problems, fmt.Sprintf("%skey %s: %v", location, key, err))
+	}
+}
+
+// source 返回错误信息中使用的配置来源
+func (b *binder) source() string {
+	if b.c.filename == "" {
+		return "configuration"
+	}
+	return b.c.filename
+}
+
+// setValue 把配置文本按字段类型转换后写入字段
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 30s or 2h, got %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		switch strings.ToLower(raw) {
+		case "true":
+			v.SetBool(true)
+		case "false":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a non-negative integer, got %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", v.Type())
+		}
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// validate 按 min、max、oneof 标签校验字段值
+func validate(v reflect.Value, tag reflect.StructTag) error {
+	if oneof, ok := tag.Lookup("oneof"); ok && v.Kind() == reflect.String {
+		options := strings.Fields(oneof)
+		found := false
+		for _, option := range options {
+			if v.String() == option {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("must be one of %s, got %q", strings.Join(options, ", "), v.String())
+		}
+	}
+
+	for _, bound := range []string{"min", "max"} {
+		limit, ok := tag.Lookup(bound)
+		if !ok {
+			continue
+		}
+		actual, boundary, err := compareValues(v, limit)
+		if err != nil {
+			return fmt.Errorf("invalid %s tag %q: %v", bound, limit, err)
+		}
+		if bound == "min" && actual < boundary {
+			return fmt.Errorf("must be at least %s", limit)
+		}
+		if bound == "max" && actual > boundary {
+			return fmt.Errorf("must be at most %s", limit)
+		}
+	}
+	return nil
+}
+
+// compareValues 把字段值和范围标签转换为可以比较的数值；字符串和切片比较长度
+func compareValues(v reflect.Value, limit string) (float64, float64, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(limit)
+		return float64(v.Int()), float64(d), err
+	}
+
+	boundary, err := strconv.ParseFloat(limit, 64)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), boundary, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), boundary, err
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), boundary, err
+	case reflect.String, reflect.Slice:
+		return float64(v.Len()), boundary, err
+	}
+	return 0, 0, fmt.Errorf("range validation is not supported for %s", v.Type())
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindTarget struct {
+	Server struct {
+		Addr    string        `config:"addr" default:":8080"`
+		Port    int           `config:"port" min:"1" max:"65535"`
+		Timeout time.Duration `config:"timeout" default:"30s" min:"1s"`
+	} `config:"server"`
+	Log struct {
+		Level string `config:"level" default:"info" oneof:"debug info warn error"`
+	} `config:"log"`
+	Storage struct {
+		Path   string `config:"path" required:"true"`
+		Signed bool   `config:"signed" default:"false"`
+	} `config:"storage"`
+	Keys    []string `config:"keys"`
+	Ratio   float64  `config:"ratio" default:"0.5" max:"1"`
+	Users   uint     `config:"users"`
+	Ignored string
+	Skipped string `config:"-"`
+}
+
+// loadINI 把内容写入临时的 config.ini 并加载
+func loadINI(t *testing.T, src string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.ini")
+	if err := ioutil.WriteFile(path, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return c
+}
+
+func TestBind(t *testing.T) {
+	c := loadINI(t, `
+keys = a, b ,, c
+users = 7
+[server]
+port = 0443
+[storage]
+path = "/var/lib/license"
+signed = TRUE
+`)
+	var got bindTarget
+	got.Ignored = "kept"
+	if err := c.Bind(&got); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+
+	var want bindTarget
+	want.Server.Addr = ":8080"
+	want.Server.Port = 443
+	want.Server.Timeout = 30 * time.Second
+	want.Log.Level = "info"
+	want.Storage.Path = "/var/lib/license"
+	want.Storage.Signed = true
+	want.Keys = []string{"a", "b", "c"}
+	want.Ratio = 0.5
+	want.Users = 7
+	want.Ignored = "kept"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bind() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // 期望的全部问题，按字段声明顺序
+	}{
+		{
+			name: "missing required key",
+			src:  "[server]\nport = 80\n",
+			want: []string{"config.ini: missing required key storage.path"},
+		},
+		{
+			name: "type errors carry file and line",
+			src:  "users = -1\n[server]\nport = eighty\ntimeout = 5 minutes\n[storage]\npath = /data\nsigned = yes\n",
+			want: []string{
+				`config.ini:3: key server.port: expected an integer, got "eighty"`,
+				`config.ini:4: key server.timeout: expected a duration such as 30s or 2h, got "5 minutes"`,
+				`config.ini:7: key storage.signed: expected true or false, got "yes"`,
+				`config.ini:1: key users: expected a non-negative integer, got "-1"`,
+			},
+		},
+		{
+			name: "range and oneof",
+			src:  "ratio = 1.5\n[server]\nport = 70000\ntimeout = 10ms\n[log]\nlevel = verbose\n[storage]\npath = /data\n",
+			want: []string{
+				"config.ini:3: key server.port: must be at most 65535",
+				"config.ini:4: key server.timeout: must be at least 1s",
+				`config.ini:6: key log.level: must be one of debug, info, warn, error, got "verbose"`,
+				"config.ini:1: key ratio: must be at most 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := loadINI(t, tt.src)
+			err := c.Bind(&bindTarget{})
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Bind() error = %v, want *ParseError", err)
+			}
+			for i := range pe.Problems {
+				pe.Problems[i] = strings.TrimPrefix(pe.Problems[i], filepath.Dir(c.filename)+string(filepath.Separator))
+			}
+			if !reflect.DeepEqual(pe.Problems, tt.want) {
+				t.Errorf("Problems =\n%s\nwant\n%s", strings.Join(pe.Problems, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestBindInvalidDefault(t *testing.T) {
+	var target struct {
+		Port int `config:"port" default:"http"`
+	}
+	err := New().Bind(&target)
+	if err == nil || !strings.Contains(err.Error(), `default for Port: key port: expected an integer, got "http"`) {
+		t.Errorf("Bind() error = %v, want the invalid default to be reported", err)
+	}
+}
+
+func TestBindRequiresStructPointer(t *testing.T) {
+	var target bindTarget
+	for _, arg := range []interface{}{target, (*bindTarget)(nil), new(int)} {
+		if err := New().Bind(arg); err == nil {
+			t.Errorf("Bind(%T) succeeded, want an error", arg)
+		}
+	}
+}
@@ -1,26 +1,40 @@
-package main
+/*
+ * Package config 提供 INI、JSON、YAML 和 TOML 格式配置文件的加载与读取，供服务端和客户端共同使用
+ * 各格式中嵌套的键（INI 的 [section]、JSON/YAML 的嵌套对象、TOML 的表）统一以 section.key 的形式访问；未加引号的值在加载时按布尔、数值、字符串的顺序识别类型，
+ * 加引号的值始终为字符串。Bind 可以按结构体标签把配置绑定到结构体并完成校验
+ */
+
+package config
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
 // Config 存储全局配置信息
 type Config struct {
-	config map[string]interface{}
-	mutex  sync.Mutex
+	config   map[string]interface{}
+	entries  map[string]*entry
+	filename string
+	mutex    sync.Mutex
+
+	overrides   *Overrides
+	validator   func(*Config) error
+	subscribers []func(*Config)
+	reloadMutex sync.Mutex
 }
 
-// LoadConfig 从指定路径加载配置文件
+/*
+ * LoadConfig 从指定路径加载配置文件，按扩展名选择格式（.ini、.json、.yaml/.yml、.toml，其他扩展名按 INI 解析），
+ * 文件中的全部语法错误会一并返回
+ * @params: filename string - 配置文件路径
+ * @returns: error - 文件无法读取时返回错误；存在语法错误时返回 *ParseError
+ */
 func (c *Config) LoadConfig(filename string) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
 	file, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -32,29 +46,31 @@ func (c *Config) LoadConfig(filename string) error {
 		}
 	}(file)
 
-	config := make(map[string]interface{})
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "#") && strings.Contains(line, "=") {
-			parts := strings.SplitN(line, "=", 2)
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			if b, err := strconv.ParseBool(value); err == nil {
-				config[key] = b
-			} else if f, err := strconv.ParseFloat(value, 64); err == nil {
-				config[key] = f
-			} else {
-				config[key] = value
-			}
-		}
+	values, err := parserFor(filename).Parse(filename, file)
+	if err != nil {
+		return err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return err
+	config := make(map[string]interface{}, len(values))
+	entries := make(map[string]*entry, len(values))
+	for key, v := range values {
+		entries[key] = &entry{
+			raw:    v.Raw,
+			value:  typedValue(v.Raw, v.Quoted),
+			line:   v.Line,
+			quoted: v.Quoted,
+			source: SourceFile,
+			origin: fmt.Sprintf("%s:%d", filename, v.Line),
+		}
+		config[key] = entries[key].value
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	c.config = config
+	c.entries = entries
+	c.filename = filename
 	return nil
 }
 
@@ -106,50 +122,158 @@ func (c *Config) GetBool(key string) (bool, error) {
 	return false, fmt.Errorf("key %s not found in configuration", key)
 }
 
-var (
-	configOnce   sync.Once
-	globalConfig *Config
-)
+// GetDuration 获取指定键的时长值，值为 time.ParseDuration 支持的格式（例如 30s、2h）
+func (c *Config) GetDuration(key string) (time.Duration, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-func getConfig() *Config {
-	configOnce.Do(func() {
-		globalConfig = &Config{}
-		dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = globalConfig.LoadConfig(filepath.Join(dir, "config.ini"))
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-	})
-	return globalConfig
+	raw, ok := c.config[key]
+	if !ok {
+		return 0, fmt.Errorf("key %s not found in configuration", key)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return 0, fmt.Errorf("key %s: expected a duration such as 30s or 2h", key)
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("key %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// Has 判断配置中是否存在指定键
+func (c *Config) Has(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.config[key]
+	return ok
 }
 
-func main() {
-	fmt.Println(getConfig().GetString("database.host"))
-	fmt.Println(getConfig().GetString("database.port"))
+// GetStringOr 获取指定键的字符串值，键不存在时返回默认值；数值和布尔值返回配置文件中的原文
+func (c *Config) GetStringOr(key string, def string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	port, err := getConfig().GetInt("server.port")
+	if e, ok := c.entries[key]; ok {
+		return e.raw
+	}
+	switch value := c.config[key].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return def
+}
+
+// GetIntOr 获取指定键的整型值，键不存在时返回默认值，值不是整数时返回错误
+func (c *Config) GetIntOr(key string, def int) (int, error) {
+	if !c.Has(key) {
+		return def, nil
+	}
+	value, err := c.GetInt(key)
 	if err != nil {
-		fmt.Println(err)
-	} else {
-		fmt.Println(port)
+		return def, fmt.Errorf("key %s: expected an integer", key)
 	}
+	return value, nil
+}
 
-	floatValue, err := getConfig().GetFloat("some.float.value")
+// GetBoolOr 获取指定键的布尔值，键不存在时返回默认值，值不是 true/false 时返回错误
+func (c *Config) GetBoolOr(key string, def bool) (bool, error) {
+	if !c.Has(key) {
+		return def, nil
+	}
+	value, err := c.GetBool(key)
 	if err != nil {
-		fmt.Println(err)
-	} else {
-		fmt.Println(floatValue)
+		return def, fmt.Errorf("key %s: expected true or false", key)
 	}
+	return value, nil
+}
 
-	boolValue, err := getConfig().GetBool("some.bool.value")
+// GetDurationOr 获取指定键的时长值，键不存在时返回默认值，格式错误时返回错误
+func (c *Config) GetDurationOr(key string, def time.Duration) (time.Duration, error) {
+	if !c.Has(key) {
+		return def, nil
+	}
+	value, err := c.GetDuration(key)
 	if err != nil {
-		fmt.Println(err)
-	} else {
-		fmt.Println(boolValue)
+		return def, err
+	}
+	return value, nil
+}
+
+/*
+ * New 创建空配置，所有键都不存在
+ * @returns: *Config - 配置实例
+ */
+func New() *Config {
+	return &Config{config: make(map[string]interface{}), entries: make(map[string]*entry)}
+}
+
+/*
+ * Load 从指定路径加载配置文件
+ * @params: filename string - 配置文件路径
+ * @returns: *Config - 配置实例
+ *			error - 文件无法读取时返回错误
+ */
+func Load(filename string) (*Config, error) {
+	c := New()
+	if err := c.LoadConfig(filename); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// defaultNames 可执行文件所在目录下按顺序查找的默认配置文件名
+var defaultNames = []string{"config.ini", "config.yaml", "config.yml", "config.toml", "config.json"}
+
+/*
+ * DefaultPath 返回默认配置文件路径，即可执行文件所在目录下第一个存在的
+ * config.ini、config.yaml、config.yml、config.toml 或 config.json，都不存在时返回 config.ini
+ * @returns: string - 配置文件路径
+ *			error - 无法确定可执行文件目录时返回错误
+ */
+func DefaultPath() (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+	if err != nil {
+		return "", err
+	}
+	for _, name := range defaultNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return filepath.Join(dir, defaultNames[0]), nil
+}
+
+/*
+ * LoadDefault 加载配置文件：path 不为空时加载该文件，文件必须存在；
+ * path 为空时尝试加载 DefaultPath，默认文件不存在时返回空配置
+ * @params: path string - 配置文件路径，可为空
+ * @returns: *Config - 配置实例
+ *			error - 文件无法读取时返回错误
+ */
+func LoadDefault(path string) (*Config, error) {
+	if path != "" {
+		return Load(path)
+	}
+
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	c, err := Load(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New(), nil
+		}
+		return nil, err
 	}
+	return c, nil
 }
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Value 解析器产生的一个配置值
+type Value struct {
+	Raw    string // 值的文本，列表的各项以 ", " 连接
+	Quoted bool   // 值是否为字符串字面量，为 true 时不做类型识别
+	Line   int    // 值所在的行号
+}
+
+// Parser 把一种格式的配置内容解析为点分键到值的映射，嵌套的键以 . 连接，例如 database.host
+type Parser interface {
+	Parse(filename string, r io.Reader) (map[string]Value, error)
+}
+
+var (
+	parsersMutex sync.RWMutex
+	parsers      = map[string]Parser{
+		".ini":  iniParser{},
+		".conf": iniParser{},
+		".json": jsonParser{},
+		".yaml": yamlParser{},
+		".yml":  yamlParser{},
+		".toml": tomlParser{},
+	}
+)
+
+/*
+ * RegisterParser 为文件扩展名注册解析器，已有的解析器会被替换
+ * @params: ext string - 文件扩展名，例如 .yaml
+ *			p Parser - 解析器
+ */
+func RegisterParser(ext string, p Parser) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+
+	parsers[strings.ToLower(ext)] = p
+}
+
+// parserFor 按扩展名选择解析器，未知扩展名按 INI 格式解析
+func parserFor(filename string) Parser {
+	parsersMutex.RLock()
+	defer parsersMutex.RUnlock()
+
+	if p, ok := parsers[strings.ToLower(filepath.Ext(filename))]; ok {
+		return p
+	}
+	return iniParser{}
+}
+
+// entry 配置中的一个键
+type entry struct {
+	raw    string      // 去除引号和注释后的原始文本
+	value  interface{} // 识别类型后的值：bool、float64 或 string
+	line   int         // 键所在的行号
+	quoted bool        // 值是否使用了引号
+	source Source      // 值的来源
+	origin string      // 值的具体来源，用于错误信息，例如 config.ini:12
+}
+
+// ParseError 配置文件解析或绑定时发现的问题，Problems 中每一项都带有文件名和行号
+type ParseError struct {
+	Problems []string
+}
+
+func (e *ParseError) Error() string {
+	return strings.Join(e.Problems, "\n")
+}
+
+// numberPattern 只识别规范写法的数值，带前导零的值（例如 0123）保留为字符串
+var numberPattern = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// typedValue 识别值的类型：true/false 为布尔值，规范写法的数值为 float64，其余（以及带引号的值）为字符串
+func typedValue(raw string, quoted bool) interface{} {
+	if quoted {
+		return raw
+	}
+	if lower := strings.ToLower(raw); lower == "true" || lower == "false" {
+		return lower == "true"
+	}
+	if numberPattern.MatchString(raw) {
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	}
+	return raw
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// iniParser 解析 INI 格式的配置
+type iniParser struct{}
+
+/*
+ * Parse 解析 INI 格式的配置
+ * 支持 [section] 分节（节名作为键的前缀，以 . 连接）、# 和 ; 开头的注释行、
+ * 值后面以空白开头的 # 或 ; 行内注释、双引号字符串（支持 \" \\ \n \t 转义）、
+ * 以 \ 结尾的续行，以及 """ 包围的多行字符串
+ * @params: filename string - 文件名，用于错误信息
+ *			r io.Reader - 配置内容
+ * @returns: map[string]Value - 键到值的映射，键为 section.key
+ *			error - 存在语法错误时返回 *ParseError
+ */
+func (iniParser) Parse(filename string, r io.Reader) (map[string]Value, error) {
+	entries := make(map[string]Value)
+	var problems []string
+	problem := func(line int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s:%d: %s", filename, line, fmt.Sprintf(format, args...)))
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		lineNo++
+		return scanner.Text(), true
+	}
+
+	section := ""
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				problem(lineNo, "unterminated section header %q", trimmed)
+				continue
+			}
+			if rest := strings.TrimSpace(trimmed[end+1:]); rest != "" && !isComment(rest) {
+				problem(lineNo, "unexpected text after section header: %q", rest)
+			}
+			section = strings.TrimSpace(trimmed[1:end])
+			continue
+		}
+
+		eq := strings.Index(trimmed, "=")
+		if eq < 0 {
+			problem(lineNo, "expected key = value, got %q", trimmed)
+			continue
+		}
+		key := strings.TrimSpace(trimmed[:eq])
+		if key == "" {
+			problem(lineNo, "missing key before '='")
+			continue
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		keyLine := lineNo
+		value := strings.TrimSpace(trimmed[eq+1:])
+
+		e := Value{Line: keyLine}
+		switch {
+		case strings.HasPrefix(value, `"""`):
+			// """ 包围的多行字符串，保留换行
+			body := value[3:]
+			var lines []string
+			for {
+				if end := strings.Index(body, `"""`); end >= 0 {
+					lines = append(lines, body[:end])
+					if rest := strings.TrimSpace(body[end+3:]); rest != "" && !isComment(rest) {
+						problem(lineNo, "unexpected text after closing \"\"\": %q", rest)
+					}
+					break
+				}
+				lines = append(lines, body)
+				var ok bool
+				if body, ok = next(); !ok {
+					problem(keyLine, "unterminated multi-line string for key %s", key)
+					break
+				}
+			}
+			e.Raw = strings.TrimPrefix(strings.Join(lines, "\n"), "\n")
+			e.Quoted = true
+		case strings.HasPrefix(value, `"`):
+			s, rest, err := unquote(value)
+			if err != nil {
+				problem(keyLine, "key %s: %v", key, err)
+				continue
+			}
+			if rest = strings.TrimSpace(rest); rest != "" && !isComment(rest) {
+				problem(keyLine, "key %s: unexpected text after quoted string: %q", key, rest)
+			}
+			e.Raw = s
+			e.Quoted = true
+		default:
+			// 以 \ 结尾的行与下一行拼接，中间用一个空格连接
+			parts := []string{}
+			for {
+				value = stripComment(value)
+				if !strings.HasSuffix(value, `\`) {
+					parts = append(parts, value)
+					break
+				}
+				parts = append(parts, strings.TrimSpace(strings.TrimSuffix(value, `\`)))
+				next, ok := next()
+				if !ok {
+					problem(keyLine, "key %s: line continuation at end of file", key)
+					break
+				}
+				value = strings.TrimSpace(next)
+			}
+			e.Raw = strings.Join(nonEmpty(parts), " ")
+		}
+
+		if previous, ok := entries[key]; ok {
+			problem(keyLine, "duplicate key %s (first defined on line %d)", key, previous.Line)
+			continue
+		}
+		entries[key] = e
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &ParseError{Problems: problems}
+	}
+	return entries, nil
+}
+
+// unquote 解析以双引号开头的字符串，返回字符串内容和闭合引号之后的剩余文本
+func unquote(value string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch c {
+		case '"':
+			return b.String(), value[i+1:], nil
+		case '\\':
+			if i+1 >= len(value) {
+				return "", "", fmt.Errorf("unterminated escape sequence")
+			}
+			i++
+			switch value[i] {
+			case '"', '\\':
+				b.WriteByte(value[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", "", fmt.Errorf("invalid escape sequence \\%c", value[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted string")
+}
+
+// stripComment 去除以空白加 # 或 ; 开头的行内注释
+func stripComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if (value[i] == '#' || value[i] == ';') && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i])
+		}
+	}
+	if isComment(value) {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+func isComment(s string) bool {
+	return strings.HasPrefix(s, "#") || strings.HasPrefix(s, ";")
+}
+
+func nonEmpty(parts []string) []string {
+	list := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
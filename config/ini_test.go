@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parserCase 解析器的一个测试用例：want 不为 nil 时期望解析成功，否则期望错误信息包含 wantErr
+type parserCase struct {
+	name    string
+	src     string
+	want    map[string]Value
+	wantErr string
+}
+
+// runParserCases 用解析器逐个解析用例，比较键、值、引号标记和行号
+func runParserCases(t *testing.T, p Parser, filename string, cases []parserCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.Parse(filename, strings.NewReader(tc.src))
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Parse() = %v, want error containing %q", got, tc.wantErr)
+				}
+				var pe *ParseError
+				if !errors.As(err, &pe) {
+					t.Errorf("Parse() error is %T, want *ParseError", err)
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Parse() error = %q, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Parse() =\n%#v\nwant\n%#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestINIParser(t *testing.T) {
+	runParserCases(t, iniParser{}, "config.ini", []parserCase{
+		{
+			name: "sections and plain values",
+			src:  "name = top\n[server]\naddr = :8080\nport=0123\n\n[ log ]\nlevel = info\n",
+			want: map[string]Value{
+				"name":        {Raw: "top", Line: 1},
+				"server.addr": {Raw: ":8080", Line: 3},
+				"server.port": {Raw: "0123", Line: 4},
+				"log.level":   {Raw: "info", Line: 7},
+			},
+		},
+		{
+			name: "comments",
+			src:  "# comment\n; comment\n[a] # trailing\nurl = http://host/#anchor ; comment\nempty = # nothing\n",
+			want: map[string]Value{
+				"a.url":   {Raw: "http://host/#anchor", Line: 4},
+				"a.empty": {Raw: "", Line: 5},
+			},
+		},
+		{
+			name: "quoted strings and escapes",
+			src:  `a = "x # not a comment" # comment` + "\n" + `b = "tab\tquote\"back\\slash\n"` + "\n",
+			want: map[string]Value{
+				"a": {Raw: "x # not a comment", Quoted: true, Line: 1},
+				"b": {Raw: "tab\tquote\"back\\slash\n", Quoted: true, Line: 2},
+			},
+		},
+		{
+			name: "line continuation",
+			src:  "modules = reports, \\\n    export, \\\n    admin\nnext = 1\n",
+			want: map[string]Value{
+				"modules": {Raw: "reports, export, admin", Line: 1},
+				"next":    {Raw: "1", Line: 4},
+			},
+		},
+		{
+			name: "multi-line string",
+			src:  "banner = \"\"\"\nline 1\n  line 2\"\"\"\nnext = 2\n",
+			want: map[string]Value{
+				"banner": {Raw: "line 1\n  line 2", Quoted: true, Line: 1},
+				"next":   {Raw: "2", Line: 4},
+			},
+		},
+		{name: "missing equals", src: "[a]\njust text\n", wantErr: "config.ini:2: expected key = value"},
+		{name: "missing key", src: "= value\n", wantErr: "config.ini:1: missing key"},
+		{name: "unterminated section", src: "[server\n", wantErr: "unterminated section header"},
+		{name: "text after section", src: "[server] extra\n", wantErr: "unexpected text after section header"},
+		{name: "unterminated string", src: "a = \"open\n", wantErr: "config.ini:1: key a: unterminated quoted string"},
+		{name: "invalid escape", src: `a = "\q"` + "\n", wantErr: `invalid escape sequence \q`},
+		{name: "text after string", src: `a = "x" y` + "\n", wantErr: "unexpected text after quoted string"},
+		{name: "unterminated multi-line", src: "a = \"\"\"\nnever closed\n", wantErr: "unterminated multi-line string for key a"},
+		{name: "continuation at end of file", src: "a = x \\", wantErr: "line continuation at end of file"},
+		{name: "duplicate key", src: "[s]\na = 1\n[s]\na = 2\n", wantErr: "config.ini:4: duplicate key s.a (first defined on line 2)"},
+	})
+}
+
+func TestINIParserReportsAllProblems(t *testing.T) {
+	_, err := iniParser{}.Parse("config.ini", strings.NewReader("bad line\n[ok]\nanother bad\n"))
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Parse() error = %v, want *ParseError", err)
+	}
+	if len(pe.Problems) != 2 {
+		t.Errorf("Problems = %q, want one problem for each bad line", pe.Problems)
+	}
+}
+
+func TestTypedValue(t *testing.T) {
+	tests := []struct {
+		raw    string
+		quoted bool
+		want   interface{}
+	}{
+		{"true", false, true},
+		{"FALSE", false, false},
+		{"8080", false, float64(8080)},
+		{"-1.5e3", false, float64(-1500)},
+		{"0123", false, "0123"},
+		{"1.", false, "1."},
+		{"8080", true, "8080"},
+		{"true", true, "true"},
+		{"info", false, "info"},
+	}
+	for _, tt := range tests {
+		if got := typedValue(tt.raw, tt.quoted); got != tt.want {
+			t.Errorf("typedValue(%q, %v) = %#v, want %#v", tt.raw, tt.quoted, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// jsonParser 解析 JSON 格式的配置，顶层必须是对象
+type jsonParser struct{}
+
+/*
+ * Parse 解析 JSON 格式的配置
+ * 嵌套对象展开为点分键；数组只能包含标量，各项以 ", " 连接；null 视为空字符串
+ * @params: filename string - 文件名，用于错误信息
+ *			r io.Reader - 配置内容
+ * @returns: map[string]Value - 键到值的映射
+ *			error - 存在语法错误时返回带行号的错误
+ */
+func (jsonParser) Parse(filename string, r io.Reader) (map[string]Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &jsonReader{filename: filename, data: data, values: make(map[string]Value)}
+	p.dec = json.NewDecoder(bytes.NewReader(data))
+	p.dec.UseNumber()
+
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.wrap(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, p.errorf("the top level value must be an object")
+	}
+	if err := p.object(""); err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.errorf("unexpected data after the top level object")
+	}
+	return p.values, nil
+}
+
+type jsonReader struct {
+	filename string
+	data     []byte
+	dec      *json.Decoder
+	values   map[string]Value
+}
+
+// line 返回解码器当前位置所在的行号
+func (p *jsonReader) line() int {
+	return p.lineAt(p.dec.InputOffset())
+}
+
+func (p *jsonReader) lineAt(offset int64) int {
+	if offset > int64(len(p.data)) {
+		offset = int64(len(p.data))
+	}
+	return bytes.Count(p.data[:offset], []byte("\n")) + 1
+}
+
+func (p *jsonReader) errorf(format string, args ...interface{}) error {
+	return &ParseError{Problems: []string{fmt.Sprintf("%s:%d: %s", p.filename, p.line(), fmt.Sprintf(format, args...))}}
+}
+
+// wrap 为解码错误加上文件名和行号
+func (p *jsonReader) wrap(err error) error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return &ParseError{Problems: []string{fmt.Sprintf("%s:%d: %v", p.filename, p.lineAt(syntax.Offset), err)}}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return p.errorf("%v", err)
+}
+
+// object 读取对象的成员直到 }，prefix 为对象自身的键
+func (p *jsonReader) object(prefix string) error {
+	for p.dec.More() {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return p.wrap(err)
+		}
+		name, _ := tok.(string)
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if _, ok := p.values[key]; ok {
+			return p.errorf("duplicate key %s", key)
+		}
+		if err := p.value(key); err != nil {
+			return err
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+func (p *jsonReader) value(key string) error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return p.wrap(err)
+	}
+	line := p.line()
+
+	if delim, ok := tok.(json.Delim); ok {
+		if delim == '{' {
+			return p.object(key)
+		}
+		// 数组
+		var items []string
+		for p.dec.More() {
+			tok, err := p.dec.Token()
+			if err != nil {
+				return p.wrap(err)
+			}
+			if _, ok := tok.(json.Delim); ok {
+				return p.errorf("key %s: arrays may only contain strings, numbers and booleans", key)
+			}
+			if tok != nil {
+				items = append(items, scalarText(tok))
+			}
+		}
+		if _, err := p.dec.Token(); err != nil {
+			return p.wrap(err)
+		}
+		p.values[key] = Value{Raw: strings.Join(items, ", "), Quoted: true, Line: line}
+		return nil
+	}
+
+	switch tok.(type) {
+	case string, nil:
+		p.values[key] = Value{Raw: scalarText(tok), Quoted: true, Line: line}
+	default:
+		p.values[key] = Value{Raw: scalarText(tok), Line: line}
+	}
+	return nil
+}
+
+// scalarText 把 JSON 标量转换为配置文本
+func scalarText(tok json.Token) string {
+	switch v := tok.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package config
+
+import "testing"
+
+func TestJSONParser(t *testing.T) {
+	runParserCases(t, jsonParser{}, "config.json", []parserCase{
+		{
+			name: "nested objects and scalars",
+			src: `{
+  "server": {"addr": ":8080", "port": 8080, "tls": {"enabled": true}},
+  "log": {"level": "info", "file": null},
+  "ratio": 1.5e2,
+  "code": "0123"
+}`,
+			want: map[string]Value{
+				"server.addr":        {Raw: ":8080", Quoted: true, Line: 2},
+				"server.port":        {Raw: "8080", Line: 2},
+				"server.tls.enabled": {Raw: "true", Line: 2},
+				"log.level":          {Raw: "info", Quoted: true, Line: 3},
+				"log.file":           {Raw: "", Quoted: true, Line: 3},
+				"ratio":              {Raw: "1.5e2", Line: 4},
+				"code":               {Raw: "0123", Quoted: true, Line: 5},
+			},
+		},
+		{
+			name: "arrays of scalars",
+			src:  "{\n\"keys\": [\"a\", 2, false, null],\n\"empty\": []\n}",
+			want: map[string]Value{
+				"keys":  {Raw: "a, 2, false", Quoted: true, Line: 2},
+				"empty": {Raw: "", Quoted: true, Line: 3},
+			},
+		},
+		{name: "top level array", src: `["a"]`, wantErr: "config.json:1: the top level value must be an object"},
+		{name: "syntax error line", src: "{\n\"a\": 1,\n\"b\" 2\n}", wantErr: "config.json:3:"},
+		{name: "truncated", src: `{"a": {"b": 1}`, wantErr: "config.json:1:"},
+		{name: "nested array", src: `{"a": [[1]]}`, wantErr: "key a: arrays may only contain strings, numbers and booleans"},
+		{name: "object in array", src: `{"a": [{"b": 1}]}`, wantErr: "key a: arrays may only contain"},
+		{name: "duplicate key", src: "{\"a\": {\"b\": 1},\n\"a\": {\"b\": 2}}", wantErr: "duplicate key a.b"},
+		{name: "trailing data", src: `{"a": 1} {"b": 2}`, wantErr: "unexpected data after the top level object"},
+	})
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Source 配置值的来源，优先级从高到低为 flag、env、file、default
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+	SourceDefault Source = "default"
+	SourceUnset   Source = "unset"
+)
+
+// KeyInfo 结构体中声明的一个配置键
+type KeyInfo struct {
+	Key        string // 点分键名，例如 server.addr
+	Default    string // default 标签的值
+	HasDefault bool   // 是否声明了 default 标签
+	Secret     bool   // 是否为敏感信息
+}
+
+// secretWords 键名的最后一段包含这些词时视为敏感信息
+var secretWords = []string{"password", "passwd", "secret", "token", "private_key"}
+
+/*
+ * Keys 列出结构体中通过 config 标签声明的全部键，顺序与字段声明顺序一致
+ * @params: target interface{} - 结构体或指向结构体的指针
+ * @returns: []KeyInfo - 键列表
+ *			error - target 不是结构体时返回错误
+ */
+func Keys(target interface{}) ([]KeyInfo, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: Keys requires a struct, got %T", target)
+	}
+
+	var keys []KeyInfo
+	walkFields(v, "", func(_ reflect.Value, field reflect.StructField, key string) {
+		def, ok := field.Tag.Lookup("default")
+		keys = append(keys, KeyInfo{
+			Key:        key,
+			Default:    def,
+			HasDefault: ok,
+			Secret:     field.Tag.Get("secret") == "true" || isSecretKey(key),
+		})
+	})
+	return keys, nil
+}
+
+func isSecretKey(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, word := range secretWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overrides 收集环境变量和命令行参数对配置键的覆盖
+type Overrides struct {
+	prefix string
+	keys   []KeyInfo
+	flags  map[string]string
+}
+
+/*
+ * NewOverrides 为结构体中声明的全部键创建覆盖集合
+ * 键对应的环境变量名为 前缀_键名，键名中的 . 替换为 _ 并转为大写，
+ * 例如前缀为 LICENSE 时 server.addr 对应 LICENSE_SERVER_ADDR
+ * @params: envPrefix string - 环境变量前缀，可为空
+ *			target interface{} - 声明配置键的结构体或其指针
+ * @returns: *Overrides - 覆盖集合
+ *			error - target 不是结构体时返回错误
+ */
+func NewOverrides(envPrefix string, target interface{}) (*Overrides, error) {
+	keys, err := Keys(target)
+	if err != nil {
+		return nil, err
+	}
+	return &Overrides{prefix: envPrefix, keys: keys, flags: make(map[string]string)}, nil
+}
+
+// EnvName 返回键对应的环境变量名
+func (o *Overrides) EnvName(key string) string {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+	if o.prefix == "" {
+		return name
+	}
+	return strings.ToUpper(o.prefix) + "_" + name
+}
+
+/*
+ * RegisterFlags 为每个键注册同名命令行参数，例如 -server.addr=:9090
+ * 参数值在 Apply 时才写入配置，因此可以先解析命令行再加载配置文件
+ * @params: fs *flag.FlagSet - 命令行参数集合
+ */
+func (o *Overrides) RegisterFlags(fs *flag.FlagSet) {
+	for _, k := range o.keys {
+		key := k.Key
+		usage := fmt.Sprintf("override %s (env %s", key, o.EnvName(key))
+		if k.HasDefault && !k.Secret {
+			usage += fmt.Sprintf(", default %q", k.Default)
+		}
+		usage += ")"
+		fs.Func(key, usage, func(value string) error {
+			o.flags[key] = value
+			return nil
+		})
+	}
+}
+
+/*
+ * Apply 把环境变量和命令行参数写入配置，命令行参数优先于环境变量，二者都优先于配置文件；
+ * 配置重新加载时会自动再次应用
+ * @params: c *Config - 已加载配置文件的配置
+ */
+func (o *Overrides) Apply(c *Config) {
+	c.mutex.Lock()
+	c.overrides = o
+	c.mutex.Unlock()
+
+	for _, k := range o.keys {
+		name := o.EnvName(k.Key)
+		if value, ok := os.LookupEnv(name); ok {
+			c.set(k.Key, value, SourceEnv, "environment "+name)
+		}
+	}
+	for _, k := range o.keys {
+		if value, ok := o.flags[k.Key]; ok {
+			c.set(k.Key, value, SourceFlag, "flag -"+k.Key)
+		}
+	}
+}
+
+// set 写入一个来自配置文件以外的值
+func (c *Config) set(key string, raw string, source Source, origin string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.config == nil {
+		c.config = make(map[string]interface{})
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*entry)
+	}
+	raw = strings.TrimSpace(raw)
+	e := &entry{raw: raw, value: typedValue(raw, false), source: source, origin: origin}
+	c.entries[key] = e
+	c.config[key] = e.value
+}
+
+/*
+ * Lookup 返回键的原始值和来源
+ * @params: key string - 点分键名
+ * @returns: string - 原始值
+ *			Source - 值的来源，键不存在时为 SourceUnset
+ *			string - 具体来源，例如 config.ini:12 或 environment LICENSE_SERVER_ADDR
+ */
+func (c *Config) Lookup(key string) (string, Source, string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		return e.raw, e.source, e.origin
+	}
+	return "", SourceUnset, ""
+}
+
+/*
+ * WriteEffective 输出结构体中声明的每个键的生效值及其来源，敏感信息以 ****** 代替
+ * @params: w io.Writer - 输出目标
+ *			target interface{} - 声明配置键的结构体或其指针
+ * @returns: error - target 不是结构体或写入失败时返回错误
+ */
+func (c *Config) WriteEffective(w io.Writer, target interface{}) error {
+	keys, err := Keys(target)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, k := range keys {
+		value, source, origin := c.Lookup(k.Key)
+		if source == SourceUnset && k.HasDefault {
+			value, source = k.Default, SourceDefault
+		}
+		if k.Secret && value != "" {
+			value = "******"
+		}
+		where := string(source)
+		switch {
+		case source == SourceFile:
+			where += " " + origin
+		case origin != "":
+			where = origin
+		}
+		fmt.Fprintf(tw, "%s\t%q\t%s\n", k.Key, value, where)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+type overrideTarget struct {
+	Server struct {
+		Addr string `config:"addr" default:":8080"`
+		Port int    `config:"port" default:"80"`
+	} `config:"server"`
+	Log struct {
+		Level string `config:"level" default:"info"`
+	} `config:"log"`
+	Storage struct {
+		SigningKey string `config:"signing_key" secret:"true"`
+		Password   string `config:"db_password"`
+		Token      string `config:"api_token" default:"dev-token"`
+		Path       string `config:"path"`
+	} `config:"storage"`
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		env        map[string]string
+		flags      []string
+		key        string
+		wantValue  string
+		wantSource Source
+	}{
+		{name: "default", key: "server.addr", wantValue: ":8080", wantSource: SourceDefault},
+		{name: "file over default", file: "[server]\naddr = :9000\n",
+			key: "server.addr", wantValue: ":9000", wantSource: SourceFile},
+		{name: "env over file", file: "[server]\naddr = :9000\n",
+			env: map[string]string{"LICENSE_SERVER_ADDR": ":9100"},
+			key: "server.addr", wantValue: ":9100", wantSource: SourceEnv},
+		{name: "flag over env and file", file: "[server]\naddr = :9000\n",
+			env:   map[string]string{"LICENSE_SERVER_ADDR": ":9100"},
+			flags: []string{"-server.addr=:9200"},
+			key:   "server.addr", wantValue: ":9200", wantSource: SourceFlag},
+		{name: "flag over default", flags: []string{"-log.level", "debug"},
+			key: "log.level", wantValue: "debug", wantSource: SourceFlag},
+		{name: "env name replaces dots and dashes", env: map[string]string{"LICENSE_STORAGE_SIGNING_KEY": "/etc/key.pem"},
+			key: "storage.signing_key", wantValue: "/etc/key.pem", wantSource: SourceEnv},
+		{name: "empty env value still overrides", file: "[log]\nlevel = warn\n",
+			env: map[string]string{"LICENSE_LOG_LEVEL": ""},
+			key: "log.level", wantValue: "", wantSource: SourceEnv},
+		{name: "unrelated env is ignored", file: "[log]\nlevel = warn\n",
+			env: map[string]string{"LOG_LEVEL": "debug", "OTHER_LOG_LEVEL": "debug"},
+			key: "log.level", wantValue: "warn", wantSource: SourceFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			c := New()
+			if tt.file != "" {
+				c = loadINI(t, tt.file)
+			}
+
+			o, err := NewOverrides("license", &overrideTarget{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.RegisterFlags(fs)
+			if err := fs.Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			o.Apply(c)
+
+			var target overrideTarget
+			if err := c.Bind(&target); err != nil {
+				t.Fatal(err)
+			}
+			bound := map[string]string{
+				"server.addr":         target.Server.Addr,
+				"log.level":           target.Log.Level,
+				"storage.signing_key": target.Storage.SigningKey,
+			}
+			if got := bound[tt.key]; got != tt.wantValue {
+				t.Errorf("bound %s = %q, want %q", tt.key, got, tt.wantValue)
+			}
+
+			_, source, _ := c.Lookup(tt.key)
+			if tt.wantSource == SourceDefault {
+				if source != SourceUnset {
+					t.Errorf("Lookup(%s) source = %s, want the key to be unset", tt.key, source)
+				}
+			} else if source != tt.wantSource {
+				t.Errorf("Lookup(%s) source = %s, want %s", tt.key, source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestOverridesTypedValues(t *testing.T) {
+	t.Setenv("LICENSE_SERVER_PORT", "9090")
+	c := loadINI(t, "[server]\nport = \"80\"\n")
+	o, err := NewOverrides("LICENSE", overrideTarget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Apply(c)
+
+	// 环境变量的值与配置文件中未加引号的值一样识别类型
+	if port, err := c.GetInt("server.port"); err != nil || port != 9090 {
+		t.Errorf("GetInt(server.port) = %d, %v, want 9090", port, err)
+	}
+	if _, _, origin := c.Lookup("server.port"); origin != "environment LICENSE_SERVER_PORT" {
+		t.Errorf("origin = %q", origin)
+	}
+}
+
+func TestWriteEffectiveRedactsSecrets(t *testing.T) {
+	t.Setenv("APP_STORAGE_DB_PASSWORD", "hunter2")
+	c := loadINI(t, "[storage]\nsigning_key = /etc/license/signing.pem\npath = /var/lib/license\n")
+	o, err := NewOverrides("app", &overrideTarget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-server.port=9090"}); err != nil {
+		t.Fatal(err)
+	}
+	o.Apply(c)
+
+	var buf bytes.Buffer
+	if err := c.WriteEffective(&buf, &overrideTarget{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, secret := range []string{"/etc/license/signing.pem", "hunter2", "dev-token"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("WriteEffective output contains the secret %q:\n%s", secret, out)
+		}
+	}
+
+	rows := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		rows[fields[0]] = fields[1:]
+	}
+	want := map[string][]string{
+		"server.addr":         {`":8080"`, "default"},
+		"server.port":         {`"9090"`, "flag", "-server.port"},
+		"log.level":           {`"info"`, "default"},
+		"storage.signing_key": {`"******"`, "file", c.filename + ":2"},
+		"storage.db_password": {`"******"`, "environment", "APP_STORAGE_DB_PASSWORD"},
+		"storage.api_token":   {`"******"`, "default"},
+		"storage.path":        {`"/var/lib/license"`, "file", c.filename + ":3"},
+	}
+	for key, fields := range want {
+		if got := strings.Join(rows[key], " "); got != strings.Join(fields, " ") {
+			t.Errorf("%s row = %q, want %q", key, got, strings.Join(fields, " "))
+		}
+	}
+	if len(rows) != len(want) {
+		t.Errorf("WriteEffective printed %d keys, want %d:\n%s", len(rows), len(want), out)
+	}
+}
+
+func TestRegisterFlagsHidesSecretDefaults(t *testing.T) {
+	o, err := NewOverrides("LICENSE", &overrideTarget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+
+	if usage := fs.Lookup("storage.api_token").Usage; strings.Contains(usage, "dev-token") {
+		t.Errorf("usage of a secret flag shows its default: %q", usage)
+	}
+	if usage := fs.Lookup("server.addr").Usage; usage != `override server.addr (env LICENSE_SERVER_ADDR, default ":8080")` {
+		t.Errorf("usage = %q", usage)
+	}
+}
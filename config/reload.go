@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+/*
+ * SetValidator 设置重新加载时的校验函数，校验失败时保留原有配置
+ * @params: fn func(*Config) error - 校验函数，参数为新加载（已应用覆盖）的配置
+ */
+func (c *Config) SetValidator(fn func(*Config) error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.validator = fn
+}
+
+/*
+ * Subscribe 注册配置变更订阅者，每次重新加载成功后按注册顺序调用
+ * @params: fn func(*Config) - 订阅者，参数为已更新的配置
+ */
+func (c *Config) Subscribe(fn func(*Config)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.subscribers = append(c.subscribers, fn)
+}
+
+/*
+ * Reload 重新读取配置文件并重新应用环境变量和命令行参数覆盖，
+ * 校验通过后原子替换当前配置并通知订阅者；任一步骤失败时保留原有配置
+ * @returns: error - 读取、解析或校验失败时返回错误
+ */
+func (c *Config) Reload() error {
+	c.reloadMutex.Lock()
+	defer c.reloadMutex.Unlock()
+
+	c.mutex.Lock()
+	filename, overrides, validator := c.filename, c.overrides, c.validator
+	c.mutex.Unlock()
+	if filename == "" {
+		return errors.New("config: no configuration file to reload")
+	}
+
+	next, err := Load(filename)
+	if err != nil {
+		return err
+	}
+	if overrides != nil {
+		overrides.Apply(next)
+	}
+	if validator != nil {
+		if err := validator(next); err != nil {
+			return fmt.Errorf("config: reload of %s rejected, keeping the current configuration:\n%w", filename, err)
+		}
+	}
+
+	c.mutex.Lock()
+	c.config = next.config
+	c.entries = next.entries
+	subscribers := append([]func(*Config){}, c.subscribers...)
+	c.mutex.Unlock()
+
+	for _, fn := range subscribers {
+		fn(c)
+	}
+	return nil
+}
+
+/*
+ * Watch 监视配置文件，文件内容变化时自动调用 Reload
+ * Linux 上使用 inotify 监视文件所在目录（兼容编辑器先写临时文件再 rename 的保存方式），
+ * inotify 不可用或在其他平台上时按 interval 轮询文件
+ * @params: interval time.Duration - 轮询间隔，为 0 时使用 5 秒
+ *			onError func(error) - 重新加载失败时的回调，可为 nil
+ * @returns: func() - 停止监视
+ *			error - 没有可监视的配置文件时返回错误
+ */
+func (c *Config) Watch(interval time.Duration, onError func(error)) (func(), error) {
+	c.mutex.Lock()
+	filename := c.filename
+	c.mutex.Unlock()
+	if filename == "" {
+		return nil, errors.New("config: no configuration file to watch")
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	events, closeWatcher, err := watchFile(filename)
+	if err != nil {
+		events, closeWatcher = pollFile(filename, interval)
+	}
+
+	done := make(chan struct{})
+	last := fileDigest(filename)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			}
+
+			// 合并短时间内的连续事件，等待写入完成
+			settle := time.NewTimer(200 * time.Millisecond)
+		drain:
+			for {
+				select {
+				case <-events:
+					settle.Reset(200 * time.Millisecond)
+				case <-settle.C:
+					break drain
+				case <-done:
+					settle.Stop()
+					return
+				}
+			}
+
+			digest := fileDigest(filename)
+			if digest == nil || bytes.Equal(digest, last) {
+				continue
+			}
+			if err := c.Reload(); err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			last = digest
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			closeWatcher()
+		})
+	}, nil
+}
+
+// fileDigest 返回文件内容的摘要，文件无法读取时返回 nil
+func fileDigest(filename string) []byte {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(content)
+	return sum[:]
+}
+
+// pollFile 按固定间隔检查文件的修改时间和大小，变化时发送事件
+func pollFile(filename string, interval time.Duration) (<-chan struct{}, func()) {
+	events := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := statSignature(filename)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if current := statSignature(filename); current != last {
+				last = current
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return events, func() { close(done) }
+}
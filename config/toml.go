@@ -0,0 +1,517 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlParser 解析 TOML 格式的配置
+type tomlParser struct{}
+
+// tomlDecimalPattern TOML 十进制整数和浮点数（已去除下划线），整数部分不允许前导零
+var tomlDecimalPattern = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// tomlDateTimePattern TOML 日期、时间和日期时间，按原文保留为字符串
+var tomlDateTimePattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}([Tt ][0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?([Zz]|[-+][0-9]{2}:[0-9]{2})?)?$|^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`)
+
+/*
+ * Parse 解析 TOML 格式的配置
+ * 支持 [table] 表头、点分键和引号键、四种字符串写法、整数（含 0x/0o/0b 和下划线分隔）、浮点数、布尔值、
+ * 日期时间、由标量组成的数组（可跨行）以及内联表；不支持表数组 [[table]]
+ * 表和内联表展开为点分键；数组各项以 ", " 连接；日期时间按原文保留
+ * @params: filename string - 文件名，用于错误信息
+ *			r io.Reader - 配置内容
+ * @returns: map[string]Value - 键到值的映射
+ *			error - 存在语法错误或不支持的写法时返回 *ParseError
+ */
+func (tomlParser) Parse(filename string, r io.Reader) (map[string]Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &tomlReader{filename: filename, src: string(data), line: 1, values: make(map[string]Value)}
+	if err := p.document(); err != nil {
+		return nil, err
+	}
+	return p.values, nil
+}
+
+type tomlReader struct {
+	filename string
+	src      string
+	pos      int
+	line     int
+	values   map[string]Value
+	tables   map[string]int
+}
+
+func (p *tomlReader) errorf(format string, args ...interface{}) error {
+	return &ParseError{Problems: []string{fmt.Sprintf("%s:%d: %s", p.filename, p.line, fmt.Sprintf(format, args...))}}
+}
+
+func (p *tomlReader) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlReader) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlReader) advance(n int) {
+	for i := 0; i < n && !p.eof(); i++ {
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipSpace 跳过空格和制表符
+func (p *tomlReader) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.advance(1)
+	}
+}
+
+// skipComment 跳过 # 开头直到行尾的注释
+func (p *tomlReader) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' {
+		p.advance(1)
+	}
+}
+
+// skipBlank 跳过空白、换行和注释
+func (p *tomlReader) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.advance(1)
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine 要求当前行剩余部分只有空白或注释
+func (p *tomlReader) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.peek() == '\r' {
+		p.advance(1)
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return p.errorf("unexpected %q at end of line", p.rest())
+	}
+	return nil
+}
+
+// rest 返回当前行剩余的文本，用于错误信息
+func (p *tomlReader) rest() string {
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		return p.src[p.pos:]
+	}
+	return strings.TrimRight(p.src[p.pos:p.pos+end], "\r")
+}
+
+func (p *tomlReader) document() error {
+	p.tables = make(map[string]int)
+	table := ""
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil
+		}
+
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.src[p.pos:], "[[") {
+				return p.errorf("arrays of tables are not supported")
+			}
+			p.advance(1)
+			p.skipSpace()
+			key, err := p.key()
+			if err != nil {
+				return err
+			}
+			p.skipSpace()
+			if p.peek() != ']' {
+				return p.errorf("expected ] to close table header")
+			}
+			p.advance(1)
+			if line, ok := p.tables[key]; ok {
+				return p.errorf("table [%s] is already defined on line %d", key, line)
+			}
+			p.tables[key] = p.line
+			table = key
+			if err := p.endOfLine(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		p.skipSpace()
+		if p.peek() != '=' {
+			return p.errorf("expected = after key %s", key)
+		}
+		p.advance(1)
+		p.skipSpace()
+		if err := p.value(key); err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// key 读取点分键，各段可以是裸键或引号键
+func (p *tomlReader) key() (string, error) {
+	var parts []string
+	for {
+		var part string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.basicString()
+			if err != nil {
+				return "", err
+			}
+			part = s
+		case c == '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return "", err
+			}
+			part = s
+		default:
+			start := p.pos
+			for c := p.peek(); isBareKeyChar(c); c = p.peek() {
+				p.advance(1)
+			}
+			if start == p.pos {
+				return "", p.errorf("expected a key, got %q", p.rest())
+			}
+			part = p.src[start:p.pos]
+		}
+		parts = append(parts, part)
+
+		p.skipSpace()
+		if p.peek() != '.' {
+			return strings.Join(parts, "."), nil
+		}
+		p.advance(1)
+		p.skipSpace()
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlReader) set(key string, v Value) error {
+	if previous, ok := p.values[key]; ok {
+		return p.errorf("duplicate key %s (first defined on line %d)", key, previous.Line)
+	}
+	p.values[key] = v
+	return nil
+}
+
+// value 读取键的值并写入结果，内联表展开为子键
+func (p *tomlReader) value(key string) error {
+	line := p.line
+	switch p.peek() {
+	case '[':
+		items, err := p.array()
+		if err != nil {
+			return err
+		}
+		return p.set(key, Value{Raw: strings.Join(items, ", "), Quoted: true, Line: line})
+	case '{':
+		return p.inlineTable(key)
+	}
+
+	raw, quoted, err := p.scalar()
+	if err != nil {
+		return err
+	}
+	return p.set(key, Value{Raw: raw, Quoted: quoted, Line: line})
+}
+
+// inlineTable 读取单行内联表 { a = 1, b = "x" }
+func (p *tomlReader) inlineTable(prefix string) error {
+	p.advance(1)
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.advance(1)
+		return p.set(prefix, Value{Raw: "", Quoted: true, Line: p.line})
+	}
+	for {
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.peek() != '=' {
+			return p.errorf("expected = after key %s", key)
+		}
+		p.advance(1)
+		p.skipSpace()
+		if err := p.value(prefix + "." + key); err != nil {
+			return err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+			p.skipSpace()
+		case '}':
+			p.advance(1)
+			return nil
+		default:
+			return p.errorf("expected , or } in inline table %s", prefix)
+		}
+	}
+}
+
+// array 读取由标量组成的数组，数组可以跨行并包含注释和末尾逗号
+func (p *tomlReader) array() ([]string, error) {
+	p.advance(1)
+	var items []string
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.advance(1)
+			return items, nil
+		}
+		if p.peek() == '[' || p.peek() == '{' {
+			return nil, p.errorf("arrays may only contain strings, numbers, booleans and dates")
+		}
+		raw, _, err := p.scalar()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, raw)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// scalar 读取字符串、数值、布尔值或日期时间，返回配置文本以及是否为字符串字面量
+func (p *tomlReader) scalar() (string, bool, error) {
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		s, err := p.multilineString(`"""`)
+		return s, true, err
+	case strings.HasPrefix(p.src[p.pos:], `'''`):
+		s, err := p.multilineString(`'''`)
+		return s, true, err
+	case p.peek() == '"':
+		s, err := p.basicString()
+		return s, true, err
+	case p.peek() == '\'':
+		s, err := p.literalString()
+		return s, true, err
+	}
+
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == ',' || c == ']' || c == '}' || c == '#' || c == '\n' || c == '\r' {
+			break
+		}
+		// 日期和时间之间允许一个空格
+		if c == ' ' || c == '\t' {
+			if !(c == ' ' && p.pos-start == 10 && tomlDateTimePattern.MatchString(p.src[start:p.pos])) {
+				break
+			}
+		}
+		p.advance(1)
+	}
+	token := p.src[start:p.pos]
+	if token == "" {
+		return "", false, p.errorf("missing value")
+	}
+
+	switch token {
+	case "true", "false":
+		return token, false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		return token, false, nil
+	}
+	if tomlDateTimePattern.MatchString(token) {
+		return token, true, nil
+	}
+	return p.number(token)
+}
+
+// number 把 TOML 数值转换为十进制文本
+func (p *tomlReader) number(token string) (string, bool, error) {
+	if strings.Contains(token, "__") || strings.HasPrefix(token, "_") || strings.HasSuffix(token, "_") {
+		return "", false, p.errorf("invalid number %q", token)
+	}
+	plain := strings.ReplaceAll(token, "_", "")
+
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(plain, prefix) {
+			n, err := strconv.ParseUint(plain[2:], base, 64)
+			if err != nil {
+				return "", false, p.errorf("invalid number %q", token)
+			}
+			return strconv.FormatUint(n, 10), false, nil
+		}
+	}
+	if !tomlDecimalPattern.MatchString(plain) {
+		return "", false, p.errorf("invalid value %q (strings must be quoted)", token)
+	}
+	return plain, false, nil
+}
+
+// basicString 读取双引号字符串，支持 TOML 转义
+func (p *tomlReader) basicString() (string, error) {
+	p.advance(1)
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		if c == '"' {
+			p.advance(1)
+			return b.String(), nil
+		}
+		if c == '\\' {
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(c)
+		p.advance(1)
+	}
+}
+
+// literalString 读取单引号字符串，内容不做转义
+func (p *tomlReader) literalString() (string, error) {
+	p.advance(1)
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated literal string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.advance(end + 1)
+	return s, nil
+}
+
+// multilineString 读取 """ 或 ”' 多行字符串，紧跟开头引号的换行会被去除
+func (p *tomlReader) multilineString(delim string) (string, error) {
+	start := p.line
+	p.advance(3)
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.advance(2)
+	} else if p.peek() == '\n' {
+		p.advance(1)
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			p.line = start
+			return "", p.errorf("unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], delim) {
+			// 结束引号前最多可以有两个引号属于内容
+			n := 3
+			for n < 5 && p.pos+n < len(p.src) && p.src[p.pos+n] == delim[0] {
+				n++
+			}
+			b.WriteString(strings.Repeat(delim[:1], n-3))
+			p.advance(n)
+			return b.String(), nil
+		}
+		c := p.peek()
+		if c == '\\' && delim == `"""` {
+			// 行尾的 \ 去除换行以及下一段内容之前的空白
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.advance(1)
+				for c := p.peek(); c == ' ' || c == '\t' || c == '\r' || c == '\n'; c = p.peek() {
+					p.advance(1)
+				}
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(c)
+		p.advance(1)
+	}
+}
+
+// escape 读取一个以 \ 开头的转义序列
+func (p *tomlReader) escape(b *strings.Builder) error {
+	p.advance(1)
+	c := p.peek()
+	p.advance(1)
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape \\%c%s", c, p.src[p.pos:p.pos+n])
+		}
+		b.WriteRune(rune(code))
+		p.advance(n)
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import "testing"
+
+func TestTOMLParser(t *testing.T) {
+	runParserCases(t, tomlParser{}, "config.toml", []parserCase{
+		{
+			name: "tables, dotted keys and scalars",
+			src: `# comment
+title = "license server"
+[server]
+addr = ":8080"  # comment
+port = 8_080
+tls.enabled = true
+"quoted key" = 'C:\path'
+
+[log]
+level = "info"
+`,
+			want: map[string]Value{
+				"title":              {Raw: "license server", Quoted: true, Line: 2},
+				"server.addr":        {Raw: ":8080", Quoted: true, Line: 4},
+				"server.port":        {Raw: "8080", Line: 5},
+				"server.tls.enabled": {Raw: "true", Line: 6},
+				"server.quoted key":  {Raw: `C:\path`, Quoted: true, Line: 7},
+				"log.level":          {Raw: "info", Quoted: true, Line: 10},
+			},
+		},
+		{
+			name: "numbers and dates",
+			src:  "hex = 0xff\noct = 0o17\nbin = 0b101\nfloat = -1.5e3\ninf = +inf\nday = 2026-01-02\nat = 2026-01-02 03:04:05Z\ntime = 03:04:05\n",
+			want: map[string]Value{
+				"hex":   {Raw: "255", Line: 1},
+				"oct":   {Raw: "15", Line: 2},
+				"bin":   {Raw: "5", Line: 3},
+				"float": {Raw: "-1.5e3", Line: 4},
+				"inf":   {Raw: "+inf", Line: 5},
+				"day":   {Raw: "2026-01-02", Quoted: true, Line: 6},
+				"at":    {Raw: "2026-01-02 03:04:05Z", Quoted: true, Line: 7},
+				"time":  {Raw: "03:04:05", Quoted: true, Line: 8},
+			},
+		},
+		{
+			name: "strings",
+			src:  "basic = \"tab\\t\\u00e9\\\"\"\nml = \"\"\"\nline 1\\\n   continued\n\"\"\"\nlit = '''\nraw \\n'''\n",
+			want: map[string]Value{
+				"basic": {Raw: "tab\té\"", Quoted: true, Line: 1},
+				"ml":    {Raw: "line 1continued\n", Quoted: true, Line: 2},
+				"lit":   {Raw: `raw \n`, Quoted: true, Line: 6},
+			},
+		},
+		{
+			name: "arrays and inline tables",
+			src:  "keys = [\n  \"a\",  # comment\n  2,\n]\ndb = { host = \"localhost\", port = 5432, opts = {} }\n",
+			want: map[string]Value{
+				"keys":    {Raw: "a, 2", Quoted: true, Line: 1},
+				"db.host": {Raw: "localhost", Quoted: true, Line: 5},
+				"db.port": {Raw: "5432", Line: 5},
+				"db.opts": {Raw: "", Quoted: true, Line: 5},
+			},
+		},
+		{name: "unquoted string", src: "level = info\n", wantErr: `config.toml:1: invalid value "info" (strings must be quoted)`},
+		{name: "leading zero", src: "port = 0123\n", wantErr: `invalid value "0123"`},
+		{name: "bad underscore", src: "n = 1__0\n", wantErr: `invalid number "1__0"`},
+		{name: "bad hex", src: "n = 0xzz\n", wantErr: `invalid number "0xzz"`},
+		{name: "array of tables", src: "[[servers]]\n", wantErr: "arrays of tables are not supported"},
+		{name: "nested array", src: "a = [[1]]\n", wantErr: "arrays may only contain strings, numbers, booleans and dates"},
+		{name: "redefined table", src: "[a]\nx = 1\n[a]\n", wantErr: "config.toml:3: table [a] is already defined on line 1"},
+		{name: "duplicate key", src: "a.b = 1\n[a]\nb = 2\n", wantErr: "config.toml:3: duplicate key a.b (first defined on line 1)"},
+		{name: "missing equals", src: "a 1\n", wantErr: "expected = after key a"},
+		{name: "missing value", src: "a =\n", wantErr: "config.toml:1: missing value"},
+		{name: "text after value", src: "a = 1 2\n", wantErr: `unexpected "2" at end of line`},
+		{name: "unterminated string", src: "a = \"open\n", wantErr: "unterminated string"},
+		{name: "unterminated multi-line string", src: "\na = \"\"\"\nopen\n", wantErr: "config.toml:2: unterminated multi-line string"},
+		{name: "invalid escape", src: `a = "\q"`, wantErr: `invalid escape sequence \q`},
+		{name: "unclosed inline table", src: "a = { b = 1\n", wantErr: "expected , or } in inline table a"},
+	})
+}
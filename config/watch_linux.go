@@ -0,0 +1,81 @@
+//go:build linux
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchFile 使用 inotify 监视文件所在目录，目标文件被写入、替换或删除时发送事件
+func watchFile(filename string) (<-chan struct{}, func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, name := filepath.Split(filepath.Clean(filename))
+	if dir == "" {
+		dir = "."
+	}
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		_ = syscall.Close(fd)
+		return nil, nil, err
+	}
+
+	// 非阻塞描述符交给运行时的 poller，Close 可以唤醒阻塞中的 Read
+	file := os.NewFile(uintptr(fd), "inotify")
+	events := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				close(events)
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + syscall.SizeofInotifyEvent
+				end := start + int(event.Len)
+				offset = end
+				if end > n {
+					break
+				}
+				if cString(buf[start:end]) != name {
+					continue
+				}
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return events, func() { _ = file.Close() }, nil
+}
+
+// cString 去除 inotify 事件中文件名末尾的 NUL 填充
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// statSignature 返回文件的修改时间、大小和 inode，用于轮询时判断文件是否变化
+func statSignature(filename string) [3]int64 {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return [3]int64{}
+	}
+	var ino int64
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		ino = int64(st.Ino)
+	}
+	return [3]int64{info.ModTime().UnixNano(), info.Size(), ino}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// watchFile 非 Linux 平台不支持 inotify，由调用方退回到轮询
+func watchFile(filename string) (<-chan struct{}, func(), error) {
+	return nil, nil, errors.New("config: file notifications are not supported on this platform")
+}
+
+// statSignature 返回文件的修改时间和大小，用于轮询时判断文件是否变化
+func statSignature(filename string) [3]int64 {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return [3]int64{}
+	}
+	return [3]int64{info.ModTime().UnixNano(), info.Size(), 0}
+}
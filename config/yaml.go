@@ -0,0 +1,395 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// yamlParser 解析配置文件常用的 YAML 子集
+type yamlParser struct{}
+
+// yamlFrame 正在解析的一层映射
+type yamlFrame struct {
+	indent   int      // 映射所属键的缩进，根映射为 -1
+	key      string   // 映射所属键的完整点分键名
+	line     int      // 映射所属键的行号
+	children int      // 已解析的子键数量
+	items    []string // 以 - 开头的列表项
+	isList   bool
+}
+
+/*
+ * Parse 解析 YAML 格式的配置
+ * 支持以缩进表示的嵌套映射、# 注释、单双引号字符串、由标量组成的块列表（- item）和流式列表（[a, b]），
+ * 以及 | 和 > 块标量；不支持锚点、标签、流式映射和由映射组成的列表
+ * 嵌套映射展开为点分键；列表各项以 ", " 连接；null 和 ~ 视为空字符串
+ * @params: filename string - 文件名，用于错误信息
+ *			r io.Reader - 配置内容
+ * @returns: map[string]Value - 键到值的映射
+ *			error - 存在语法错误或不支持的写法时返回 *ParseError
+ */
+func (yamlParser) Parse(filename string, r io.Reader) (map[string]Value, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]Value)
+	var problems []string
+	problem := func(line int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s:%d: %s", filename, line, fmt.Sprintf(format, args...)))
+	}
+	set := func(key string, v Value) {
+		if previous, ok := values[key]; ok {
+			problem(v.Line, "duplicate key %s (first defined on line %d)", key, previous.Line)
+			return
+		}
+		values[key] = v
+	}
+
+	stack := []*yamlFrame{{indent: -1}}
+	// pop 结束最内层映射：列表写入列表值，没有子键的键视为空字符串
+	pop := func() {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case top.isList:
+			set(top.key, Value{Raw: strings.Join(top.items, ", "), Quoted: true, Line: top.line})
+		case top.children == 0:
+			set(top.key, Value{Raw: "", Quoted: true, Line: top.line})
+		}
+	}
+
+	started := false
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		text := strings.TrimRight(stripYAMLComment(lines[i]), " \t")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			problem(lineNo, "tabs are not allowed for indentation")
+			continue
+		}
+		if trimmed == "---" || trimmed == "..." || strings.HasPrefix(trimmed, "%") {
+			if started && trimmed == "---" {
+				problem(lineNo, "multiple documents are not supported")
+				break
+			}
+			continue
+		}
+		started = true
+
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		isItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+
+		for len(stack) > 1 {
+			top := stack[len(stack)-1]
+			if indent > top.indent || (indent == top.indent && isItem && top.children == 0) {
+				break
+			}
+			pop()
+		}
+		top := stack[len(stack)-1]
+
+		if isItem {
+			if top.key == "" || top.children > 0 {
+				problem(lineNo, "unexpected list item")
+				continue
+			}
+			item := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			if _, _, ok := splitYAMLKey(item); ok {
+				problem(lineNo, "key %s: lists of mappings are not supported", top.key)
+				continue
+			}
+			s, _, err := yamlScalar(item)
+			if err != nil {
+				problem(lineNo, "key %s: %v", top.key, err)
+				continue
+			}
+			top.isList = true
+			top.items = append(top.items, s)
+			continue
+		}
+
+		if top.isList {
+			problem(lineNo, "key %s: cannot mix list items and keys", top.key)
+			continue
+		}
+		name, rest, ok := splitYAMLKey(trimmed)
+		if !ok {
+			problem(lineNo, "expected key: value, got %q", trimmed)
+			continue
+		}
+		top.children++
+		key := name
+		if top.key != "" {
+			key = top.key + "." + name
+		}
+
+		switch {
+		case rest == "":
+			stack = append(stack, &yamlFrame{indent: indent, key: key, line: lineNo})
+		case rest[0] == '|' || rest[0] == '>':
+			block, next, err := yamlBlock(lines, i+1, indent, rest)
+			if err != nil {
+				problem(lineNo, "key %s: %v", key, err)
+			} else {
+				set(key, Value{Raw: block, Quoted: true, Line: lineNo})
+			}
+			i = next - 1
+		case rest[0] == '[':
+			items, err := yamlFlowList(rest)
+			if err != nil {
+				problem(lineNo, "key %s: %v", key, err)
+				continue
+			}
+			set(key, Value{Raw: strings.Join(items, ", "), Quoted: true, Line: lineNo})
+		case rest[0] == '{':
+			problem(lineNo, "key %s: flow mappings are not supported, use an indented block", key)
+		case rest[0] == '&' || rest[0] == '*' || rest[0] == '!':
+			problem(lineNo, "key %s: anchors, aliases and tags are not supported", key)
+		default:
+			s, quoted, err := yamlScalar(rest)
+			if err != nil {
+				problem(lineNo, "key %s: %v", key, err)
+				continue
+			}
+			set(key, Value{Raw: s, Quoted: quoted, Line: lineNo})
+		}
+	}
+	for len(stack) > 1 {
+		pop()
+	}
+
+	if len(problems) > 0 {
+		return nil, &ParseError{Problems: problems}
+	}
+	return values, nil
+}
+
+// stripYAMLComment 去除引号之外、行首或空白之后的 # 注释
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitYAMLKey 把 key: value 拆分为键和值，键可以加引号
+func splitYAMLKey(s string) (string, string, bool) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", "", false
+		}
+		rest := s[end+2:]
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return "", "", false
+		}
+		name, _, err := yamlScalar(s[:end+2])
+		if err != nil {
+			return "", "", false
+		}
+		return name, strings.TrimSpace(rest[1:]), true
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			name := strings.TrimSpace(s[:i])
+			if name == "" {
+				return "", "", false
+			}
+			return name, strings.TrimSpace(s[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// yamlScalar 解析标量，返回文本以及是否为字符串字面量
+func yamlScalar(s string) (string, bool, error) {
+	switch {
+	case s == "" || s == "~" || s == "null" || s == "Null" || s == "NULL":
+		return "", true, nil
+	case s[0] == '"':
+		if len(s) < 2 || s[len(s)-1] != '"' {
+			return "", false, fmt.Errorf("unterminated double-quoted string")
+		}
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid double-quoted string %s", s)
+		}
+		return v, true, nil
+	case s[0] == '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return "", false, fmt.Errorf("unterminated single-quoted string")
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), true, nil
+	}
+	return s, false, nil
+}
+
+// yamlFlowList 解析单行的流式列表 [a, "b", 'c']
+func yamlFlowList(s string) ([]string, error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("flow lists must be closed on the same line")
+	}
+	body := strings.TrimSpace(s[1 : len(s)-1])
+	if body == "" {
+		return nil, nil
+	}
+
+	var (
+		items []string
+		quote byte
+		start int
+	)
+	add := func(part string) error {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil
+		}
+		if part[0] == '[' || part[0] == '{' {
+			return fmt.Errorf("nested collections are not supported")
+		}
+		v, _, err := yamlScalar(part)
+		if err != nil {
+			return err
+		}
+		items = append(items, v)
+		return nil
+	}
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			if err := add(body[start:i]); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string in flow list")
+	}
+	if err := add(body[start:]); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+/*
+ * yamlBlock 读取 | 或 > 块标量
+ * @params: lines []string - 全部行
+ *			start int - 块内容的第一行下标
+ *			parentIndent int - 所属键的缩进
+ *			header string - 块标量头，例如 |、|-、>+
+ * @returns: string - 块内容
+ *			int - 块之后第一行的下标
+ *			error - 块标量头无效时返回错误
+ */
+func yamlBlock(lines []string, start int, parentIndent int, header string) (string, int, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			// 显式缩进由内容自动确定，忽略
+		default:
+			return "", start, fmt.Errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	blockIndent := -1
+	var body []string
+	end := start
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			body = append(body, "")
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent <= parentIndent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = indent
+		}
+		if indent < blockIndent {
+			break
+		}
+		body = append(body, line[blockIndent:])
+	}
+
+	// 末尾空行属于块之后的内容，只用于决定末尾换行
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, line := range body {
+			switch {
+			case i == 0 || body[i-1] == "":
+			case line == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(body, "\n")
+	}
+
+	switch chomp {
+	case '-':
+	case '+':
+		text += strings.Repeat("\n", trailing+1)
+	default:
+		if text != "" {
+			text += "\n"
+		}
+	}
+	return text, end, nil
+}
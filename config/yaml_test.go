@@ -0,0 +1,72 @@
+package config
+
+import "testing"
+
+func TestYAMLParser(t *testing.T) {
+	runParserCases(t, yamlParser{}, "config.yaml", []parserCase{
+		{
+			name: "nested mappings and scalars",
+			src: `# comment
+server:
+  addr: ":8080"   # comment
+  port: 8080
+  tls:
+    enabled: true
+log:
+  level: info
+  file: ~
+code: '0123'
+url: http://host/#anchor
+empty:
+`,
+			want: map[string]Value{
+				"server.addr":        {Raw: ":8080", Quoted: true, Line: 3},
+				"server.port":        {Raw: "8080", Line: 4},
+				"server.tls.enabled": {Raw: "true", Line: 6},
+				"log.level":          {Raw: "info", Line: 8},
+				"log.file":           {Raw: "", Quoted: true, Line: 9},
+				"code":               {Raw: "0123", Quoted: true, Line: 10},
+				"url":                {Raw: "http://host/#anchor", Line: 11},
+				"empty":              {Raw: "", Quoted: true, Line: 12},
+			},
+		},
+		{
+			name: "lists",
+			src:  "keys:\n  - a\n  - \"b, c\"\nflow: [x, 'y', \"z\"]\nsame_indent:\n- one\n- two\n",
+			want: map[string]Value{
+				"keys":        {Raw: "a, b, c", Quoted: true, Line: 1},
+				"flow":        {Raw: "x, y, z", Quoted: true, Line: 4},
+				"same_indent": {Raw: "one, two", Quoted: true, Line: 5},
+			},
+		},
+		{
+			name: "block scalars",
+			src:  "literal: |\n  line 1\n    line 2\nfolded: >-\n  one\n  two\n\n  three\nnext: 1\n",
+			want: map[string]Value{
+				"literal": {Raw: "line 1\n  line 2\n", Quoted: true, Line: 1},
+				"folded":  {Raw: "one two\nthree", Quoted: true, Line: 4},
+				"next":    {Raw: "1", Line: 9},
+			},
+		},
+		{
+			name: "document markers and quoted keys",
+			src:  "---\n\"a.b\": 1\n'c': 'it''s'\n...\n",
+			want: map[string]Value{
+				"a.b": {Raw: "1", Line: 2},
+				"c":   {Raw: "it's", Quoted: true, Line: 3},
+			},
+		},
+		{name: "tab indentation", src: "a:\n\tb: 1\n", wantErr: "config.yaml:2: tabs are not allowed"},
+		{name: "missing colon", src: "a: 1\njust text\n", wantErr: "config.yaml:2: expected key: value"},
+		{name: "list of mappings", src: "a:\n  - name: x\n", wantErr: "key a: lists of mappings are not supported"},
+		{name: "mixed list and keys", src: "a:\n  - x\n  b: 1\n", wantErr: "key a: cannot mix list items and keys"},
+		{name: "flow mapping", src: "a: {b: 1}\n", wantErr: "flow mappings are not supported"},
+		{name: "anchor", src: "a: &x 1\n", wantErr: "anchors, aliases and tags are not supported"},
+		{name: "unterminated string", src: "a: \"open\n", wantErr: "config.yaml:1: key a: unterminated double-quoted string"},
+		{name: "unclosed flow list", src: "a: [x, y\n", wantErr: "flow lists must be closed on the same line"},
+		{name: "nested flow list", src: "a: [x, [y]]\n", wantErr: "nested collections are not supported"},
+		{name: "multiple documents", src: "a: 1\n---\nb: 2\n", wantErr: "config.yaml:2: multiple documents are not supported"},
+		{name: "duplicate key", src: "a:\n  b: 1\na:\n  b: 2\n", wantErr: "config.yaml:4: duplicate key a.b (first defined on line 2)"},
+		{name: "invalid block header", src: "a: |x\n  b\n", wantErr: `invalid block scalar header "|x"`},
+	})
+}
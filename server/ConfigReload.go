@@ -0,0 +1,63 @@
+package main
+
+import (
+	"config"
+	"server/logger"
+	"server/service"
+)
+
+/*
+ * watchConfig 监视配置文件并在变化时热加载
+ * 新配置必须能通过 loadSettings 的全部校验才会生效；日志级别和克隆检测策略立即更新，
+ * 监听地址、超时、TLS、存储路径和日志输出等设置需要重启服务器才能生效，发生变化时记录警告
+ * @params: cfg *config.Config - 已加载的配置
+ *			current settings - 启动时使用的配置
+ * @returns: func() - 停止监视
+ *			error - 没有可监视的配置文件时返回错误
+ */
+func watchConfig(cfg *config.Config, current settings) (func(), error) {
+	cfg.SetValidator(func(next *config.Config) error {
+		_, err := loadSettings(next)
+		return err
+	})
+
+	cfg.Subscribe(func(c *config.Config) {
+		next, err := loadSettings(c)
+		if err != nil {
+			// 已由校验函数拦截，正常情况下不会发生
+			logger.Error("reloaded configuration is invalid", "error", err)
+			return
+		}
+
+		logger.Default().SetLevel(next.Log.Level)
+		service.SetCloneDetectionPolicy(next.CloneDetection)
+
+		var restart []string
+		if next.Server != current.Server {
+			restart = append(restart, "server/tls")
+		}
+		if next.StorePath != current.StorePath || next.AuditPath != current.AuditPath || next.LicenseDir != current.LicenseDir {
+			restart = append(restart, "storage")
+		}
+		if next.Log.Format != current.Log.Format || next.Log.Output != current.Log.Output ||
+			next.Log.MaxSizeMB != current.Log.MaxSizeMB || next.Log.MaxBackups != current.Log.MaxBackups {
+			restart = append(restart, "log output")
+		}
+		if next.Reload != current.Reload {
+			restart = append(restart, "reload")
+		}
+		if len(restart) > 0 {
+			logger.Warn("some configuration changes require a restart to take effect", "sections", restart)
+		}
+
+		logger.Info("configuration reloaded",
+			"log_level", next.Log.Level.String(),
+			"clone_window", next.CloneDetection.Window.String(),
+			"max_ip_ranges", next.CloneDetection.MaxIPRanges,
+			"auto_suspend", next.CloneDetection.AutoSuspend)
+	})
+
+	return cfg.Watch(current.Reload.PollInterval, func(err error) {
+		logger.Error("configuration reload failed", "error", err)
+	})
+}
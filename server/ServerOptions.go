@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"server/logger"
+	"server/utils"
+	"syscall"
+	"time"
+)
+
+// serverOptions HTTP 服务器参数
+type serverOptions struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	TLSCert           string
+	TLSKey            string
+	TLSClientCA       string
+	TLSRequireClient  bool
+}
+
+/*
+ * serve 启动 HTTP(S) 服务器并阻塞，直到收到 SIGINT/SIGTERM 后优雅关闭
+ * 启用 TLS 时收到 SIGHUP 会重新加载证书、私钥和客户端 CA
+ * @params: handler http.Handler - 路由处理器
+ *			opts serverOptions - 服务器参数
+ * @returns: error - 启动失败或关闭超时时返回错误
+ */
+func serve(handler http.Handler, opts serverOptions) error {
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadTimeout:       opts.ReadTimeout,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+	}
+
+	var reloader *utils.CertReloader
+	if opts.TLSCert != "" || opts.TLSKey != "" {
+		if opts.TLSCert == "" || opts.TLSKey == "" {
+			return errors.New("both tls.cert and tls.key are required to enable TLS")
+		}
+		var err error
+		reloader, err = utils.NewCertReloader(opts.TLSCert, opts.TLSKey, opts.TLSClientCA, opts.TLSRequireClient)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+	} else if opts.TLSClientCA != "" {
+		return errors.New("tls.client_ca requires tls.cert and tls.key")
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("license server listening", "addr", opts.Addr, "tls", reloader != nil, "mtls", opts.TLSClientCA != "")
+		var err error
+		if reloader != nil {
+			// 证书由 TLSConfig 提供，这里不需要再传入文件
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		errCh <- err
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case err := <-errCh:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				if reloader == nil {
+					logger.Info("received SIGHUP, TLS is not enabled, nothing to reload")
+					continue
+				}
+				if err := reloader.Reload(); err != nil {
+					logger.Error("failed to reload TLS certificates, keeping the current ones", "error", err)
+				} else {
+					logger.Info("TLS certificates reloaded")
+				}
+				continue
+			}
+
+			logger.Info("shutting down, draining in-flight requests", "signal", sig.String(), "timeout", opts.ShutdownTimeout.String())
+			ctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+			err := srv.Shutdown(ctx)
+			cancel()
+			if err != nil {
+				return err
+			}
+			<-errCh
+			logger.Info("license server stopped gracefully")
+			return nil
+		}
+	}
+}
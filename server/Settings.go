@@ -0,0 +1,128 @@
+package main
+
+import (
+	"config"
+	"fmt"
+	"server/logger"
+	"server/service"
+	"time"
+)
+
+// envPrefix 覆盖配置的环境变量前缀，例如 LICENSE_SERVER_ADDR 覆盖 server.addr
+const envPrefix = "LICENSE"
+
+// settings 服务端全部配置
+type settings struct {
+	Server         serverOptions
+	StorePath      string
+	AuditPath      string
+	LicenseDir     string
+	SigningKey     string
+	Log            logger.Options
+	CloneDetection service.CloneDetectionPolicy
+	Reload         reloadOptions
+}
+
+// reloadOptions 配置文件热加载参数
+type reloadOptions struct {
+	Watch        bool
+	PollInterval time.Duration
+}
+
+// fileSettings 配置文件中的键，由 config.Bind 按标签绑定和校验
+type fileSettings struct {
+	Server struct {
+		Addr              string        `config:"addr" default:":8080" min:"1"`
+		ReadTimeout       time.Duration `config:"read_timeout" default:"15s" min:"0s"`
+		ReadHeaderTimeout time.Duration `config:"read_header_timeout" default:"5s" min:"0s"`
+		WriteTimeout      time.Duration `config:"write_timeout" default:"30s" min:"0s"`
+		IdleTimeout       time.Duration `config:"idle_timeout" default:"120s" min:"0s"`
+		ShutdownTimeout   time.Duration `config:"shutdown_timeout" default:"30s" min:"1s"`
+	} `config:"server"`
+
+	TLS struct {
+		Cert              string `config:"cert"`
+		Key               string `config:"key"`
+		ClientCA          string `config:"client_ca"`
+		RequireClientCert bool   `config:"require_client_cert" default:"false"`
+	} `config:"tls"`
+
+	Storage struct {
+		StorePath  string `config:"store_path" default:"license-store.json" min:"1"`
+		AuditPath  string `config:"audit_path" default:"audit.log" min:"1"`
+		LicenseDir string `config:"license_dir" default:"." min:"1"`
+		SigningKey string `config:"signing_key" default:"signing.key" min:"1"`
+	} `config:"storage"`
+
+	Log struct {
+		Level      string `config:"level" default:"info"`
+		Format     string `config:"format" default:"text" oneof:"text json"`
+		Output     string `config:"output" default:"stderr" min:"1"`
+		MaxSizeMB  int    `config:"max_size_mb" default:"100" min:"0"`
+		MaxBackups int    `config:"max_backups" default:"5" min:"0" max:"1000"`
+	} `config:"log"`
+
+	Verification struct {
+		CloneWindow time.Duration `config:"clone_window" default:"2h" min:"1m"`
+		MaxIPRanges int           `config:"max_ip_ranges" default:"2" min:"1"`
+		AutoSuspend bool          `config:"auto_suspend" default:"false"`
+	} `config:"verification"`
+
+	Reload struct {
+		Watch        bool          `config:"watch" default:"true"`
+		PollInterval time.Duration `config:"poll_interval" default:"5s" min:"100ms"`
+	} `config:"reload"`
+}
+
+/*
+ * loadSettings 从配置中读取服务端配置，缺省的键使用默认值，支持的键见 fileSettings 和 config.ini.example
+ * @params: cfg *config.Config - 配置
+ * @returns: settings - 服务端配置
+ *			error - 存在无效值时返回包含全部问题（带文件名和行号）的错误
+ */
+func loadSettings(cfg *config.Config) (settings, error) {
+	var f fileSettings
+	if err := cfg.Bind(&f); err != nil {
+		return settings{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	level, err := logger.ParseLevel(f.Log.Level)
+	if err != nil {
+		return settings{}, fmt.Errorf("invalid configuration: key log.level: %w", err)
+	}
+
+	return settings{
+		Server: serverOptions{
+			Addr:              f.Server.Addr,
+			ReadTimeout:       f.Server.ReadTimeout,
+			ReadHeaderTimeout: f.Server.ReadHeaderTimeout,
+			WriteTimeout:      f.Server.WriteTimeout,
+			IdleTimeout:       f.Server.IdleTimeout,
+			ShutdownTimeout:   f.Server.ShutdownTimeout,
+			TLSCert:           f.TLS.Cert,
+			TLSKey:            f.TLS.Key,
+			TLSClientCA:       f.TLS.ClientCA,
+			TLSRequireClient:  f.TLS.RequireClientCert,
+		},
+		StorePath:  f.Storage.StorePath,
+		AuditPath:  f.Storage.AuditPath,
+		LicenseDir: f.Storage.LicenseDir,
+		SigningKey: f.Storage.SigningKey,
+		Log: logger.Options{
+			Level:      level,
+			Format:     f.Log.Format,
+			Output:     f.Log.Output,
+			MaxSizeMB:  f.Log.MaxSizeMB,
+			MaxBackups: f.Log.MaxBackups,
+		},
+		CloneDetection: service.CloneDetectionPolicy{
+			Window:      f.Verification.CloneWindow,
+			MaxIPRanges: f.Verification.MaxIPRanges,
+			AutoSuspend: f.Verification.AutoSuspend,
+		},
+		Reload: reloadOptions{
+			Watch:        f.Reload.Watch,
+			PollInterval: f.Reload.PollInterval,
+		},
+	}, nil
+}
@@ -0,0 +1,454 @@
+/*
+ * Package audit 提供防篡改的审计日志
+ * 每条记录以 JSON 行的形式追加写入文件，并包含上一条记录的哈希值，
+ * 任意记录被删除或修改都会导致哈希链断裂，可通过 Verify 离线校验；
+ * 哈希链无法发现从末尾删除的记录，因此每次追加后把最新记录的序号和哈希（链头）写入 <文件名>.head，
+ * 并可把链头保存到日志之外，校验时作为期望的链头
+ */
+
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 审计操作类型
+const (
+	ActionLicenseIssue       = "license.issue"
+	ActionLicenseRenew       = "license.renew"
+	ActionLicenseSuspend     = "license.suspend"
+	ActionLicenseRevoke      = "license.revoke"
+	ActionLicenseReinstate   = "license.reinstate"
+	ActionLicenseNotes       = "license.notes"
+	ActionLicenseAutoSuspend = "license.auto-suspend"
+	ActionCloneAlert         = "license.clone-alert"
+	ActionKeyCreate          = "apikey.create"
+	ActionKeyDisable         = "apikey.disable"
+	ActionProductCreate      = "product.create"
+	ActionProductUpdate      = "product.update"
+	ActionProductDelete      = "product.delete"
+	ActionTemplateCreate     = "template.create"
+	ActionTemplateUpdate     = "template.update"
+	ActionTemplateRetire     = "template.retire"
+	ActionCustomerCreate     = "customer.create"
+	ActionCustomerUpdate     = "customer.update"
+	ActionCustomerDelete     = "customer.delete"
+	ActionAccessDenied       = "access.denied"
+)
+
+// ActorSystem 服务端自动执行的操作使用的操作者名称
+const ActorSystem = "system"
+
+// Entry 一条审计记录
+type Entry struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor"`
+	Action   string            `json:"action"`
+	Target   string            `json:"target"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prevHash"`
+	Hash     string            `json:"hash"`
+}
+
+// Head 哈希链的链头：最新记录的序号和哈希
+type Head struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// String 链头的文本形式 SEQ:HASH，即 audit-verify -expect-head 接受的格式
+func (h Head) String() string {
+	return strconv.FormatUint(h.Seq, 10) + ":" + h.Hash
+}
+
+/*
+ * ParseHead 解析 SEQ:HASH 形式的链头
+ * @params: value string - 链头文本
+ * @returns: Head - 链头
+ *			error - 格式错误时返回错误
+ */
+func ParseHead(value string) (Head, error) {
+	seq, hash, ok := strings.Cut(strings.TrimSpace(value), ":")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil || n == 0 || len(hash) != sha256.Size*2 {
+		return Head{}, fmt.Errorf("invalid audit log head %q, expected SEQ:HASH", value)
+	}
+	return Head{Seq: n, Hash: strings.ToLower(hash)}, nil
+}
+
+// Filter 审计记录查询条件，零值字段表示不过滤
+type Filter struct {
+	Action string
+	Actor  string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// Log 审计日志
+type Log struct {
+	path    string
+	mutex   sync.RWMutex
+	entries []Entry
+	size    int64 // 日志文件中完整记录的长度，追加失败时截断到该长度
+}
+
+var (
+	defaultMutex sync.RWMutex
+	defaultLog   *Log
+)
+
+/*
+ * Open 打开审计日志文件，读取已有记录，校验哈希链并与链头文件比对
+ * 日志末尾不完整的行（写入时进程退出）被截断
+ * @params: path string - 审计日志文件路径，不存在时创建
+ * @returns: *Log - 审计日志实例
+ *			error - 读取失败、哈希链断裂或记录少于链头文件时返回错误
+ */
+func Open(path string) (*Log, error) {
+	entries, size, partial, err := readEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		if err := os.Truncate(path, size); err != nil {
+			return nil, err
+		}
+	}
+	if err := verifyEntries(entries); err != nil {
+		return nil, err
+	}
+	if err := checkHeadFile(path, entries); err != nil {
+		return nil, err
+	}
+	return &Log{path: path, entries: entries, size: size}, nil
+}
+
+// SetDefault 设置全局默认审计日志
+func SetDefault(l *Log) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	defaultLog = l
+}
+
+// Default 获取全局默认审计日志，未设置时返回 nil
+func Default() *Log {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+
+	return defaultLog
+}
+
+/*
+ * Record 向默认审计日志追加一条记录，未设置默认审计日志时忽略
+ * @params: actor string - 操作者
+ *			action string - 操作类型
+ *			target string - 操作对象
+ *			details map[string]string - 附加信息
+ * @returns: error - 写入失败时返回错误
+ */
+func Record(actor string, action string, target string, details map[string]string) error {
+	l := Default()
+	if l == nil {
+		return nil
+	}
+	_, err := l.Append(Entry{
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		Details: details,
+	})
+	return err
+}
+
+/*
+ * Append 追加一条审计记录，自动填充序号、时间和哈希，写入后更新链头文件
+ * 写入失败时把日志截断到写入前的长度，不留下不完整的行或序号重复的记录
+ * @params: entry Entry - 审计记录
+ * @returns: Entry - 写入后的完整记录
+ *			error - 写入失败时返回错误
+ */
+func (l *Log) Append(entry Entry) (Entry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry.Seq = uint64(len(l.entries)) + 1
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	entry.PrevHash = ""
+	if len(l.entries) > 0 {
+		entry.PrevHash = l.entries[len(l.entries)-1].Hash
+	}
+	hash, err := entryHash(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	line = append(line, '\n')
+	if _, err := file.Write(line); err != nil {
+		_ = file.Truncate(l.size)
+		return Entry{}, err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Truncate(l.size)
+		return Entry{}, err
+	}
+
+	l.entries = append(l.entries, entry)
+	l.size += int64(len(line))
+	if err := writeHeadFile(l.path, Head{Seq: entry.Seq, Hash: entry.Hash}); err != nil {
+		return entry, fmt.Errorf("audit log head: %w", err)
+	}
+	return entry, nil
+}
+
+// Head 获取当前的链头，日志为空时返回 nil
+func (l *Log) Head() *Head {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if len(l.entries) == 0 {
+		return nil
+	}
+	last := l.entries[len(l.entries)-1]
+	return &Head{Seq: last.Seq, Hash: last.Hash}
+}
+
+/*
+ * Query 按条件查询审计记录，按时间倒序返回
+ * @params: filter Filter - 查询条件
+ * @returns: []Entry - 符合条件的记录
+ */
+func (l *Log) Query(filter Filter) []Entry {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	list := make([]Entry, 0)
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		entry := l.entries[i]
+		if filter.Action != "" && entry.Action != filter.Action {
+			continue
+		}
+		if filter.Actor != "" && entry.Actor != filter.Actor {
+			continue
+		}
+		if filter.Target != "" && entry.Target != filter.Target {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && entry.Time.After(filter.Until) {
+			continue
+		}
+		list = append(list, entry)
+		if filter.Limit > 0 && len(list) >= filter.Limit {
+			break
+		}
+	}
+	return list
+}
+
+/*
+ * Verify 离线校验审计日志文件的哈希链，并确认日志包含链头文件和 expected 记录的链头
+ * @params: path string - 审计日志文件路径
+ *			expected *Head - 保存在日志之外的链头，为 nil 时只比对链头文件
+ * @returns: Head - 日志当前的链头，日志为空时为零值
+ *			int - 校验通过的记录条数
+ *			error - 文件无法读取、末尾有不完整的行、哈希链断裂或缺少链头记录时返回错误，错误信息包含出错的序号
+ */
+func Verify(path string, expected *Head) (Head, int, error) {
+	entries, _, partial, err := readEntries(path)
+	if err != nil {
+		return Head{}, 0, err
+	}
+	if partial {
+		return Head{}, 0, fmt.Errorf("audit log ends with an incomplete entry after seq %d", len(entries))
+	}
+	if err := verifyEntries(entries); err != nil {
+		return Head{}, 0, err
+	}
+	if err := checkHeadFile(path, entries); err != nil {
+		return Head{}, 0, err
+	}
+	if expected != nil {
+		if err := checkHead(entries, *expected, "expected head"); err != nil {
+			return Head{}, 0, err
+		}
+	}
+
+	var head Head
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		head = Head{Seq: last.Seq, Hash: last.Hash}
+	}
+	return head, len(entries), nil
+}
+
+/*
+ * readEntries 读取审计日志中的全部记录
+ * @params: path string - 审计日志文件路径，不存在时返回空列表
+ * @returns: []Entry - 记录列表
+ *			int64 - 完整记录的总长度
+ *			bool - 文件末尾是否有没有换行符的不完整的行
+ *			error - 读取失败或中间的记录无法解析时返回错误
+ */
+func readEntries(path string) ([]Entry, int64, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, false, nil
+		}
+		return nil, 0, false, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	entries := make([]Entry, 0)
+	reader := bufio.NewReader(file)
+	var size int64
+	line := 0
+	for {
+		content, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, size, len(content) > 0, nil
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+		line++
+		size += int64(len(content))
+		if len(strings.TrimSpace(string(content))) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return nil, 0, false, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// headPath 链头文件的路径
+func headPath(path string) string {
+	return path + ".head"
+}
+
+// writeHeadFile 写入临时文件后原子替换链头文件
+func writeHeadFile(path string, head Head) error {
+	content, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	target := headPath(path)
+	tmp, err := ioutil.TempFile(filepath.Dir(target), filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, target); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// checkHeadFile 确认日志包含链头文件记录的链头，链头文件不存在时（旧版本创建的日志）不检查
+func checkHeadFile(path string, entries []Entry) error {
+	content, err := ioutil.ReadFile(headPath(path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var head Head
+	if err := json.Unmarshal(content, &head); err != nil {
+		return fmt.Errorf("audit log head file %s: %w", headPath(path), err)
+	}
+	return checkHead(entries, head, "head file")
+}
+
+// checkHead 确认日志包含序号为 head.Seq 且哈希为 head.Hash 的记录
+func checkHead(entries []Entry, head Head, source string) error {
+	if head.Seq > uint64(len(entries)) {
+		return fmt.Errorf("audit log truncated: %s records seq %d, the log ends at seq %d", source, head.Seq, len(entries))
+	}
+	if head.Seq > 0 && entries[head.Seq-1].Hash != head.Hash {
+		return fmt.Errorf("audit log broken at seq %d: hash does not match the %s", head.Seq, source)
+	}
+	return nil
+}
+
+func verifyEntries(entries []Entry) error {
+	prev := ""
+	for i, entry := range entries {
+		if entry.Seq != uint64(i)+1 {
+			return fmt.Errorf("audit log broken at entry %d: expected seq %d, got %d", i+1, i+1, entry.Seq)
+		}
+		if entry.PrevHash != prev {
+			return fmt.Errorf("audit log broken at seq %d: previous hash mismatch", entry.Seq)
+		}
+		hash, err := entryHash(entry)
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			return fmt.Errorf("audit log broken at seq %d: entry hash mismatch", entry.Seq)
+		}
+		prev = entry.Hash
+	}
+	return nil
+}
+
+// entryHash 计算记录的哈希值：对不含 Hash 字段的记录 JSON 取 SHA-256
+func entryHash(entry Entry) (string, error) {
+	entry.Hash = ""
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
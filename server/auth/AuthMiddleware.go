@@ -0,0 +1,124 @@
+/*
+ * Package auth 提供管理接口的认证中间件
+ * 支持通过 "X-API-Key" 请求头或 "Authorization: Bearer <key>" 请求头携带 API 密钥，
+ * 启用双向 TLS 时也可以使用受信任 CA 签发的客户端证书认证
+ */
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"server/audit"
+	"server/logger"
+	"server/service"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Principal 已认证的调用方
+type Principal struct {
+	Kind string // apikey 或 cert
+	ID   string // API 密钥ID或证书序列号
+	Name string // 密钥名称或证书 CommonName
+	Role string // 角色，决定拥有的权限
+}
+
+// Actor 审计日志中的操作者名称
+func (p *Principal) Actor() string {
+	return p.Kind + ":" + p.ID
+}
+
+/*
+ * Require 返回要求指定权限的认证处理函数，权限由调用方的角色决定
+ * 权限不足时返回 403 并记录审计日志
+ * @params:  perm service.Permission - 路由所需的权限
+ * 			 next http.HandlerFunc - 认证通过后调用的处理函数
+ * @returns: http.HandlerFunc - 包装后的处理函数
+ */
+func Require(perm service.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var principal *Principal
+		if token := credentials(r); token != "" {
+			key, err := service.AuthenticateAPIKey(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="license-server", error="invalid_token"`)
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			principal = &Principal{Kind: "apikey", ID: key.ID, Name: key.Name, Role: key.Role}
+		} else if principal = certificatePrincipal(r); principal == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="license-server"`)
+			http.Error(w, "Missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		if !service.RoleHasPermission(principal.Role, perm) {
+			err := audit.Record(principal.Actor(), audit.ActionAccessDenied, r.URL.Path, map[string]string{
+				"name":       principal.Name,
+				"role":       principal.Role,
+				"permission": string(perm),
+				"method":     r.Method,
+				"remote":     r.RemoteAddr,
+			})
+			l := logger.FromContext(r.Context())
+			if err != nil {
+				l.Error("failed to write audit log", "action", audit.ActionAccessDenied, "error", err)
+			}
+			l.Warn("access denied", "actor", principal.Actor(), "role", principal.Role, "permission", string(perm))
+			http.Error(w, "Forbidden: role "+principal.Role+" lacks permission "+string(perm), http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKey{}, principal)
+		ctx = logger.NewContext(ctx, logger.FromContext(ctx).With("actor", principal.Actor()))
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// FromContext 获取当前请求已认证的调用方，未认证时返回 nil
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
+
+// Actor 审计日志中调用方对应的操作者名称
+func Actor(principal *Principal) string {
+	if principal == nil {
+		return "anonymous"
+	}
+	return principal.Actor()
+}
+
+/*
+ * certificatePrincipal 根据已通过校验的客户端证书确定调用方
+ * 证书的 OrganizationalUnit 为角色名称，CommonName 为集成方名称；没有有效角色时不认证
+ * @params:  r *http.Request - HTTP请求指针
+ * @returns: *Principal - 调用方，未提供证书或证书不含有效角色时返回 nil
+ */
+func certificatePrincipal(r *http.Request) *Principal {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if service.ValidRole(ou) {
+			return &Principal{Kind: "cert", ID: cert.SerialNumber.String(), Name: cert.Subject.CommonName, Role: ou}
+		}
+	}
+	return nil
+}
+
+// credentials 从请求头中提取 API 密钥
+func credentials(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return strings.TrimSpace(key)
+	}
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"server/request"
+	"server/service"
+	"server/store"
+	"time"
+)
+
+// issueParams 签发许可证的参数，与 POST /licenses 的请求体一一对应，零值表示取模板或产品版本的默认值
+type issueParams = service.IssueRequest
+
+// backend licensectl 的操作对象：运行中的服务器（HTTP）或本地存储（离线）
+type backend interface {
+	// CreateKey 创建 API 密钥，返回密钥明文和密钥记录
+	CreateKey(name string, role string) (*request.APIKeyMsg, error)
+	// Issue 签发许可证，返回许可文件内容（混淆前）
+	Issue(p issueParams) (*service.LicenseMsg, error)
+	// BulkIssue 批量签发许可证，返回包含许可文件和 report.csv 的 zip 压缩包；
+	// 有无效的行时不签发任何许可证，返回 service.ErrBulkRejected 和无效行的报告
+	BulkIssue(format string, input []byte) ([]byte, []service.BulkResult, error)
+	// Suspend 暂停许可证，可在服务器上恢复
+	Suspend(id string, reason string) (*store.LicenseRecord, error)
+	// Revoke 永久吊销许可证，吊销后不能恢复或续期
+	Revoke(id string, reason string) (*store.LicenseRecord, error)
+	// Renew 延长许可证的过期日期
+	Renew(id string, expiration time.Time) (*service.LicenseMsg, error)
+	// List 获取全部许可证记录
+	List() ([]*store.LicenseRecord, error)
+	// Query 按条件查询一页许可证记录
+	Query(q service.LicenseQuery) (*service.LicensePage, error)
+	// SetNotes 修改许可证备注
+	SetNotes(id string, notes string) (*store.LicenseRecord, error)
+	// Get 获取单个许可证记录，不存在时返回 service.ErrUnknownLicense
+	Get(id string) (*store.LicenseRecord, error)
+	// Products 获取产品目录
+	Products() ([]*store.Product, error)
+	// Customers 获取全部客户
+	Customers() ([]*store.Customer, error)
+	// Templates 获取每个签发模板的最新版本
+	Templates() ([]*store.Template, error)
+	// LicenseFile 获取许可文件内容（混淆后）
+	LicenseFile(id string) ([]byte, error)
+	// Inspect 解码并检查许可文件，优先使用存储中的特征码解码；通过服务器时要求管理员角色
+	Inspect(content []byte, machineCode string) (*service.LicenseInspection, error)
+	// SigningKey 获取签名许可文件使用的公钥
+	SigningKey() (*service.SigningKeyInfo, error)
+	// Close 释放资源
+	Close() error
+}
@@ -0,0 +1,709 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/service"
+	"server/store"
+	"server/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command licensectl 子命令
+type command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(ctx *context, args []string) error
+	Local   bool // 只操作本地文件，不连接服务器也不打开存储
+}
+
+// context 子命令的运行环境
+type context struct {
+	backend backend
+	out     printer
+}
+
+var commands = []command{
+	{"keygen", "keygen -out FILE", "generate an Ed25519 license signing key pair; set storage.signing_key to FILE and add the public key to the clients", runKeygen, true},
+	{"apikey", "apikey [-role admin] <name>", "create an API key for an operator or integration", runAPIKey, false},
+	{"issue", "issue -machine-code CODE|-request FILE [-expiration YYYY-MM-DD|-days N] [-type T] [-users N] [-project P] [-module M] [-version V] [-template NAME[@VERSION]] [-customer ID] [-licensee NAME] [-notes TEXT] [-out FILE]", "issue a license", runIssue, false},
+	{"bulk-issue", "bulk-issue [-format csv|json] -out FILE.zip <file>", "issue licenses for every row of a CSV or JSON file, all or nothing", runBulkIssue, false},
+	{"inspect", "inspect [-machine-code CODE] <file|id>", "decode and explain a license file without the machine code (admin role with -server)", runInspect, false},
+	{"verify", "verify -machine-code CODE <file|id>", "check whether a license file is valid for a machine", runVerify, false},
+	{"suspend", "suspend [-reason TEXT] <id>", "suspend a license so that clients reject it until it is reinstated on the server", runSuspend, false},
+	{"revoke", "revoke [-reason TEXT] <id>", "revoke a license permanently; it cannot be reinstated or renewed", runRevoke, false},
+	{"renew", "renew -expiration YYYY-MM-DD|-days N <id>", "extend the expiration date of a license", runRenew, false},
+	{"list", "list [-status S] [-project P] [-customer ID] [-module M] [-type T] [-machine-code CODE] [-expiring-before DATE] [-issued-from DATE] [-issued-to DATE] [-q WORDS] [-sort [-]FIELD] [-limit N] [-cursor C]", "list, filter and search issued licenses", runList, false},
+	{"notes", "notes <id> <text>", "replace the internal notes of a license (an empty text clears them)", runNotes, false},
+	{"customers", "customers", "list customers", runCustomers, false},
+	{"products", "products", "list the product catalog with edition defaults", runProducts, false},
+	{"templates", "templates", "list issuance templates", runTemplates, false},
+	{"export", "export [-format json|csv] [-out FILE]", "export all license records", runExport, false},
+}
+
+// parseFlags 解析子命令参数，要求恰好 n 个位置参数（n < 0 时不检查）
+func parseFlags(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if n >= 0 && fs.NArg() != n {
+		return fmt.Errorf("%s: expected %d argument(s), got %d", fs.Name(), n, fs.NArg())
+	}
+	return nil
+}
+
+// parseExpiration 根据 -expiration 或 -days 计算过期日期，-days 从 base 起算
+func parseExpiration(expiration string, days int, base time.Time) (time.Time, error) {
+	switch {
+	case expiration != "" && days != 0:
+		return time.Time{}, errors.New("use either -expiration or -days, not both")
+	case expiration != "":
+		return time.Parse("2006-01-02", expiration)
+	case days > 0:
+		y, m, d := base.UTC().AddDate(0, 0, days).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, errors.New("an expiration date is required (-expiration or -days)")
+}
+
+// parseTemplateRef 解析 NAME 或 NAME@VERSION 形式的模板引用，版本为 0 表示最新版本
+func parseTemplateRef(ref string) (string, int, error) {
+	name, version, pinned := strings.Cut(ref, "@")
+	if !pinned {
+		return name, 0, nil
+	}
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("invalid template version in %q", ref)
+	}
+	return name, n, nil
+}
+
+// runKeygen 生成签名许可文件使用的 Ed25519 密钥对，私钥写入 -out（不覆盖已有文件），输出公钥及密钥ID
+// 服务端配置 storage.signing_key 指向该文件后用它签名，客户端在 verification.public_keys 中加入公钥；
+// 更换密钥时客户端同时配置新旧公钥，直到旧许可证全部续期
+func runKeygen(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	out := fs.String("out", "", "write the PEM encoded private key to this file; an existing file is not overwritten")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("keygen: -out is required")
+	}
+
+	key, err := utils.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteSigningKey(*out, key); err != nil {
+		return fmt.Errorf("keygen: %w", err)
+	}
+	info := service.NewSigningKeyInfo(key)
+	return ctx.out.Print(info, []string{"KEY ID", "ALGORITHM", "PUBLIC KEY", "PRIVATE KEY"}, [][]string{
+		{info.KeyID, info.Algorithm, info.PublicKey, *out},
+	})
+}
+
+func runAPIKey(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	role := fs.String("role", service.RoleAdmin, "role of the key: "+strings.Join(service.Roles(), ", "))
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	msg, err := ctx.backend.CreateKey(fs.Arg(0), *role)
+	if err != nil {
+		return err
+	}
+	return ctx.out.Print(msg, []string{"ID", "NAME", "ROLE", "KEY"}, [][]string{
+		{msg.APIKey.ID, msg.APIKey.Name, msg.APIKey.Role, msg.Key},
+	})
+}
+
+func runIssue(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("issue", flag.ContinueOnError)
+	machineCode := fs.String("machine-code", "", "machine code (signature code) reported by the client")
+	licenseType := fs.String("type", "", "license type (the product edition when a catalog is defined)")
+	expiration := fs.String("expiration", "", "expiration date (YYYY-MM-DD); defaults to the edition's duration")
+	days := fs.Int("days", 0, "validity in days from today, instead of -expiration")
+	users := fs.Uint("users", 0, "number of allowed users; defaults to the edition's seats")
+	project := fs.String("project", "", "project name (the product id when a catalog is defined)")
+	module := fs.String("module", "", "modules, separated by commas; defaults to the edition's features")
+	version := fs.String("version", "", "product version")
+	template := fs.String("template", "", "issuance template, optionally pinned to a version (NAME@VERSION); other flags override it")
+	customer := fs.String("customer", "", "id of the customer the license is issued to")
+	licensee := fs.String("licensee", "", "licensee name embedded in the license; defaults to the customer name")
+	notes := fs.String("notes", "", "internal notes kept with the license record, not embedded in the license")
+	out := fs.String("out", "", "also write the license file to this path")
+	request := fs.String("request", "", "activation request created by the client; flags that are not given are taken from it")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *request != "" {
+		if err := applyActivationRequest(fs, *request); err != nil {
+			return err
+		}
+	}
+	if *machineCode == "" {
+		return errors.New("issue: -machine-code or -request is required")
+	}
+	templateName, templateVersion, err := parseTemplateRef(*template)
+	if err != nil {
+		return fmt.Errorf("issue: %w", err)
+	}
+	// 未指定过期日期时由服务端取模板或产品版本的默认有效期
+	var exp time.Time
+	if *expiration != "" || *days != 0 {
+		var err error
+		if exp, err = parseExpiration(*expiration, *days, time.Now()); err != nil {
+			return fmt.Errorf("issue: %w", err)
+		}
+	}
+
+	msg, err := ctx.backend.Issue(issueParams{
+		SignatureCode:   *machineCode,
+		Type:            *licenseType,
+		Expiration:      exp,
+		AllowedUsers:    *users,
+		Project:         *project,
+		Module:          *module,
+		Version:         *version,
+		Template:        templateName,
+		TemplateVersion: templateVersion,
+		CustomerID:      *customer,
+		Licensee:        *licensee,
+		Notes:           *notes,
+	})
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if err := saveLicenseFile(ctx.backend, msg.Authorized.Id, *out); err != nil {
+			return err
+		}
+	}
+	return ctx.out.Print(msg, nil, authorizedRows(msg.Authorized))
+}
+
+func runBulkIssue(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("bulk-issue", flag.ContinueOnError)
+	format := fs.String("format", "", "input format: csv or json; defaults to the file extension")
+	out := fs.String("out", "", "write the zip archive of license files and report.csv to this path")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("bulk-issue: -out is required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fs.Arg(0))), ".")
+	}
+	input, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	archive, failures, err := ctx.backend.BulkIssue(*format, input)
+	if errors.Is(err, service.ErrBulkRejected) {
+		headers, rows := bulkRows(failures)
+		if err := ctx.out.Print(failures, headers, rows); err != nil {
+			return err
+		}
+		return fmt.Errorf("bulk-issue: %d row(s) are invalid, no licenses were issued", len(failures))
+	}
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*out, archive, 0600); err != nil {
+		return err
+	}
+
+	report, err := archiveReport(archive)
+	if err != nil {
+		return err
+	}
+	headers, rows := bulkRows(report)
+	if err := ctx.out.Print(report, headers, rows); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d license(s) written to %s\n", len(report), *out)
+	return nil
+}
+
+// archiveReport 读取批量签发压缩包中的 report.csv
+func archiveReport(archive []byte) ([]service.BulkResult, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("bulk-issue: invalid archive: %w", err)
+	}
+	f, err := zr.Open("report.csv")
+	if err != nil {
+		return nil, fmt.Errorf("bulk-issue: invalid archive: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, fmt.Errorf("bulk-issue: invalid report.csv: %v", err)
+	}
+	report := make([]service.BulkResult, 0, len(records)-1)
+	for _, r := range records[1:] {
+		if len(r) < 7 {
+			return nil, errors.New("bulk-issue: invalid report.csv: missing columns")
+		}
+		row, _ := strconv.Atoi(r[0])
+		report = append(report, service.BulkResult{Row: row, SignatureCode: r[1], ID: r[2], Expiration: r[3], Licensee: r[4], Error: r[6]})
+	}
+	return report, nil
+}
+
+// activationRequest 客户端 activation-request 命令生成的激活请求
+type activationRequest struct {
+	MachineCode  string `json:"machineCode"`
+	Hostname     string `json:"hostname"`
+	Project      string `json:"project"`
+	Module       string `json:"module"`
+	Type         string `json:"type"`
+	AllowedUsers uint   `json:"usersNum"`
+}
+
+// applyActivationRequest 用激活请求中的值填充命令行未指定的参数
+func applyActivationRequest(fs *flag.FlagSet, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var req activationRequest
+	if err := json.Unmarshal(content, &req); err != nil {
+		return fmt.Errorf("issue: invalid activation request %s: %w", path, err)
+	}
+	if req.MachineCode == "" {
+		return fmt.Errorf("issue: activation request %s has no machine code", path)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	values := map[string]string{"machine-code": req.MachineCode, "project": req.Project, "module": req.Module, "type": req.Type}
+	if req.AllowedUsers > 0 {
+		values["users"] = strconv.FormatUint(uint64(req.AllowedUsers), 10)
+	}
+	for name, value := range values {
+		if !set[name] && value != "" {
+			if err := fs.Set(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveLicenseFile 获取许可文件并写入 path，path 为目录时文件名为 <id>.license
+func saveLicenseFile(b backend, id string, path string) error {
+	content, err := b.LicenseFile(id)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, id+".license")
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// loadLicense 读取许可文件：参数是存在的文件时读取该文件，否则视为许可证ID从服务器或许可文件目录获取
+func loadLicense(b backend, arg string) ([]byte, string, error) {
+	if _, err := os.Stat(arg); err == nil {
+		content, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		return content, strings.TrimSuffix(filepath.Base(arg), ".license"), nil
+	}
+	content, err := b.LicenseFile(arg)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, arg, nil
+}
+
+// useSigningKey 从服务器或离线存储获取签名公钥，用于验证许可文件的签名
+func useSigningKey(b backend) error {
+	info, err := b.SigningKey()
+	if err != nil {
+		return fmt.Errorf("get signing key: %w", err)
+	}
+	key, err := service.ParseSigningKeyInfo(*info)
+	if err != nil {
+		return err
+	}
+	service.SetVerifyKey(key)
+	return nil
+}
+
+// inspectResult inspect 的输出：许可文件的检查结果以及存储中的记录
+type inspectResult struct {
+	*service.LicenseInspection
+	Record *store.LicenseRecord `json:"record,omitempty"`
+}
+
+func runInspect(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	machineCode := fs.String("machine-code", "", "also check whether the license is bound to this machine code")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	content, _, err := loadLicense(ctx.backend, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// 由服务器（或离线时的本地存储）解码，优先使用已签发许可证的特征码，通过服务器时要求管理员角色
+	inspection, err := ctx.backend.Inspect(content, *machineCode)
+	if err != nil {
+		return err
+	}
+	record, err := ctx.backend.Get(inspection.Payload.Authorized.Id)
+	if err != nil && !errors.Is(err, service.ErrUnknownLicense) {
+		return err
+	}
+
+	result := inspectResult{LicenseInspection: inspection, Record: record}
+	return ctx.out.Print(result, nil, inspectionRows(inspection, record))
+}
+
+// check verify 的一项检查
+type check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// verifyResult verify 的输出
+type verifyResult struct {
+	Valid  bool    `json:"valid"`
+	Checks []check `json:"checks"`
+}
+
+func runVerify(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	machineCode := fs.String("machine-code", "", "machine code of the machine the license should be valid for")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	if *machineCode == "" {
+		return errors.New("verify: -machine-code is required")
+	}
+
+	content, id, err := loadLicense(ctx.backend, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := useSigningKey(ctx.backend); err != nil {
+		return err
+	}
+
+	var result verifyResult
+	add := func(name string, ok bool, detail string) {
+		result.Checks = append(result.Checks, check{Name: name, OK: ok, Detail: detail})
+	}
+
+	msg, err := service.DecodeLicenseFile(content, *machineCode)
+	if err != nil {
+		add("decode", false, err.Error())
+	} else {
+		a := msg.Authorized
+		add("decode", true, "license "+a.Id)
+		if status, detail, err := service.VerifyLicenseFileSignature(content, *machineCode); err == nil {
+			add("signature", status == service.SignatureValid, status+": "+detail)
+		}
+		add("machine code", a.SignatureCode == *machineCode, "license is bound to "+a.SignatureCode)
+		exp, err := time.Parse("2006-01-02", a.Expiration)
+		add("expiration", err == nil && !time.Now().UTC().Truncate(24*time.Hour).After(exp), a.Expiration+" ("+expiryText(a.Expiration, time.Now())+")")
+		id = a.Id
+	}
+
+	record, err := ctx.backend.Get(id)
+	switch {
+	case err == nil:
+		active := record.Status == "" || record.Status == store.StatusActive
+		add("status", active, statusText(record))
+	case errors.Is(err, service.ErrUnknownLicense):
+		add("status", false, "license "+id+" is not in the store")
+	default:
+		return err
+	}
+
+	result.Valid = true
+	rows := make([][]string, 0, len(result.Checks))
+	for _, c := range result.Checks {
+		state := "ok"
+		if !c.OK {
+			state = "FAIL"
+			result.Valid = false
+		}
+		rows = append(rows, []string{c.Name, state, c.Detail})
+	}
+	if err := ctx.out.Print(result, []string{"CHECK", "RESULT", "DETAIL"}, rows); err != nil {
+		return err
+	}
+	if !result.Valid {
+		return errInvalid
+	}
+	return nil
+}
+
+// errInvalid verify 检查未通过，只设置退出码，不再输出错误信息
+var errInvalid = errors.New("license is not valid")
+
+func runSuspend(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("suspend", flag.ContinueOnError)
+	reason := fs.String("reason", "suspended by operator", "reason recorded with the suspension")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	record, err := ctx.backend.Suspend(fs.Arg(0), *reason)
+	if err != nil {
+		return err
+	}
+	headers, rows := recordRows([]*store.LicenseRecord{record})
+	return ctx.out.Print(record, headers, rows)
+}
+
+func runRevoke(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	reason := fs.String("reason", "revoked by operator", "reason recorded with the revocation")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	record, err := ctx.backend.Revoke(fs.Arg(0), *reason)
+	if err != nil {
+		return err
+	}
+	headers, rows := recordRows([]*store.LicenseRecord{record})
+	return ctx.out.Print(record, headers, rows)
+}
+
+func runRenew(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("renew", flag.ContinueOnError)
+	expiration := fs.String("expiration", "", "new expiration date (YYYY-MM-DD)")
+	days := fs.Int("days", 0, "extend the current expiration date by this many days, instead of -expiration")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	base := time.Now()
+	if *days != 0 {
+		record, err := ctx.backend.Get(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if record.ExpirationDate.After(base) {
+			base = record.ExpirationDate
+		}
+	}
+	exp, err := parseExpiration(*expiration, *days, base)
+	if err != nil {
+		return fmt.Errorf("renew: %w", err)
+	}
+
+	msg, err := ctx.backend.Renew(fs.Arg(0), exp)
+	if err != nil {
+		return err
+	}
+	return ctx.out.Print(msg, nil, authorizedRows(msg.Authorized))
+}
+
+func runCustomers(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("customers", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	list, err := ctx.backend.Customers()
+	if err != nil {
+		return err
+	}
+	headers, rows := customerRows(list)
+	return ctx.out.Print(list, headers, rows)
+}
+
+func runTemplates(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("templates", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	list, err := ctx.backend.Templates()
+	if err != nil {
+		return err
+	}
+	headers, rows := templateRows(list)
+	return ctx.out.Print(list, headers, rows)
+}
+
+func runProducts(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("products", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	list, err := ctx.backend.Products()
+	if err != nil {
+		return err
+	}
+	headers, rows := productRows(list)
+	return ctx.out.Print(list, headers, rows)
+}
+
+func runList(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var q service.LicenseQuery
+	fs.StringVar(&q.Status, "status", "", "only show licenses with this status (active, suspended, revoked)")
+	fs.StringVar(&q.Project, "project", "", "only show licenses for this project")
+	fs.StringVar(&q.CustomerID, "customer", "", "only show licenses of this customer")
+	fs.StringVar(&q.Module, "module", "", "only show licenses that include this module")
+	fs.StringVar(&q.Type, "type", "", "only show licenses of this type")
+	fs.StringVar(&q.SignatureCode, "machine-code", "", "only show licenses issued to this machine code")
+	fs.StringVar(&q.Search, "q", "", "only show licenses whose notes or licensee contain all of these words")
+	fs.StringVar(&q.Sort, "sort", "date", "sort field, prefix with - for descending order: "+strings.Join(service.SortFields(), ", "))
+	fs.IntVar(&q.Limit, "limit", 0, "show at most this many licenses and print the cursor of the next page; 0 shows all")
+	fs.StringVar(&q.Cursor, "cursor", "", "continue from the cursor printed by a previous list -limit")
+	expiringBefore := fs.String("expiring-before", "", "only show licenses expiring before this date (YYYY-MM-DD)")
+	issuedFrom := fs.String("issued-from", "", "only show licenses issued on or after this date (YYYY-MM-DD)")
+	issuedTo := fs.String("issued-to", "", "only show licenses issued on or before this date (YYYY-MM-DD)")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	dates := map[*string]*time.Time{
+		expiringBefore: &q.ExpiringBefore,
+		issuedFrom:     &q.IssuedFrom,
+		issuedTo:       &q.IssuedTo,
+	}
+	for value, target := range dates {
+		if *value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", *value)
+		if err != nil {
+			return fmt.Errorf("list: invalid date %q", *value)
+		}
+		*target = t
+	}
+
+	list, next, err := queryLicenses(ctx.backend, q)
+	if err != nil {
+		return err
+	}
+	headers, rows := recordRows(list)
+	if err := ctx.out.Print(list, headers, rows); err != nil {
+		return err
+	}
+	if next != "" {
+		fmt.Fprintf(os.Stderr, "more licenses available, continue with -cursor %s\n", next)
+	}
+	return nil
+}
+
+// queryLicenses 查询许可证；q.Limit 为 0 时逐页获取全部结果，否则只获取一页并返回下一页的游标
+func queryLicenses(b backend, q service.LicenseQuery) ([]*store.LicenseRecord, string, error) {
+	all := q.Limit == 0
+	if all {
+		q.Limit = service.MaxPageSize
+	}
+	list := make([]*store.LicenseRecord, 0)
+	for {
+		page, err := b.Query(q)
+		if err != nil {
+			return nil, "", err
+		}
+		list = append(list, page.Licenses...)
+		if !all || page.NextCursor == "" {
+			return list, page.NextCursor, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func runNotes(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("notes", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 2); err != nil {
+		return err
+	}
+
+	record, err := ctx.backend.SetNotes(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	headers, rows := recordRows([]*store.LicenseRecord{record})
+	return ctx.out.Print(record, headers, rows)
+}
+
+func runExport(ctx *context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "json", "export format: json or csv")
+	out := fs.String("out", "", "write to this file instead of standard output")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("export: unknown format %q", *format)
+	}
+
+	list, err := ctx.backend.List()
+	if err != nil {
+		return err
+	}
+
+	w := ctx.out.w
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		w = f
+	}
+
+	if *format == "json" {
+		return printer{w: w, json: true}.JSON(list)
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"id", "license", "date", "signatureCode", "type", "expiration", "usersNum", "project", "module", "status", "statusReason", "statusChanged", "version", "template", "templateVersion", "customer", "licensee", "notes"})
+	for _, r := range list {
+		changed := ""
+		if !r.StatusChanged.IsZero() {
+			changed = r.StatusChanged.Format(time.RFC3339)
+		}
+		templateVersion := ""
+		if r.TemplateVersion > 0 {
+			templateVersion = strconv.Itoa(r.TemplateVersion)
+		}
+		_ = cw.Write([]string{
+			r.ID, r.LicenseID, r.Date.Format(time.RFC3339), r.SignatureCode, r.Type,
+			r.ExpirationDate.Format("2006-01-02"), strconv.FormatUint(uint64(r.AllowedUsers), 10),
+			r.Project, r.Module, r.Status, r.StatusReason, changed,
+			r.Version, r.Template, templateVersion, r.CustomerID, r.Licensee, r.Notes,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"server/request"
+	"server/service"
+	"server/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpBackend 通过 HTTP API 操作运行中的许可证服务器
+type httpBackend struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newHTTPBackend(baseURL string, apiKey string) *httpBackend {
+	return &httpBackend{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+/*
+ * do 发送 JSON 请求并在成功时把响应解码到 out
+ * @params: method string - HTTP 方法
+ *			path string - 路径（含查询参数）
+ *			body interface{} - 请求体，为 nil 时不发送
+ *			out interface{} - 响应结构体，为 nil 时不解码
+ * @returns: []byte - 原始响应体
+ *			error - 请求失败或状态码不是 2xx 时返回错误，错误信息包含服务器返回的内容
+ */
+func (b *httpBackend) do(method string, path string, body interface{}, out interface{}) ([]byte, error) {
+	var reader io.Reader
+	header := http.Header{}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	}
+
+	resp, data, err := b.send(method, path, header, reader)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && strings.Contains(string(data), service.ErrUnknownLicense.Error()) {
+		return nil, service.ErrUnknownLicense
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+		}
+	}
+	return data, nil
+}
+
+// send 发送请求并读取完整的响应体，header 为附加的请求头，可为 nil
+func (b *httpBackend) send(method string, path string, header http.Header, body io.Reader) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, b.baseURL+path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if b.apiKey != "" {
+		req.Header.Set("X-API-Key", b.apiKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+func (b *httpBackend) CreateKey(name string, role string) (*request.APIKeyMsg, error) {
+	var msg request.APIKeyMsg
+	if _, err := b.do("POST", "/apikeys", request.APIKeyBody{Name: name, Role: role}, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (b *httpBackend) Issue(p issueParams) (*service.LicenseMsg, error) {
+	body := request.IssueBody{
+		Template:        p.Template,
+		TemplateVersion: p.TemplateVersion,
+		SignatureCode:   p.SignatureCode,
+		Type:            p.Type,
+		DurationDays:    p.DurationDays,
+		AllowedUsers:    p.AllowedUsers,
+		Project:         p.Project,
+		Module:          p.Module,
+		Version:         p.Version,
+		Customer:        p.CustomerID,
+		Licensee:        p.Licensee,
+		Notes:           p.Notes,
+	}
+	if !p.Expiration.IsZero() {
+		body.Expiration = p.Expiration.Format("2006-01-02")
+	}
+
+	var msg service.LicenseMsg
+	if _, err := b.do("POST", "/licenses", body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (b *httpBackend) BulkIssue(format string, input []byte) ([]byte, []service.BulkResult, error) {
+	path := "/licenses/bulk?format=" + url.QueryEscape(format)
+	resp, data, err := b.send("POST", path, nil, bytes.NewReader(input))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		var msg request.BulkReportMsg
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, nil, fmt.Errorf("POST %s: invalid response: %w", path, err)
+		}
+		return nil, msg.Failures, service.ErrBulkRejected
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("POST %s: %s: %s", path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil, nil
+}
+
+func (b *httpBackend) Suspend(id string, reason string) (*store.LicenseRecord, error) {
+	return b.setStatus(id, "suspend", reason)
+}
+
+func (b *httpBackend) Revoke(id string, reason string) (*store.LicenseRecord, error) {
+	return b.setStatus(id, "revoke", reason)
+}
+
+// setStatus 调用 POST /licenses/{id}/suspend 或 /licenses/{id}/revoke
+func (b *httpBackend) setStatus(id string, action string, reason string) (*store.LicenseRecord, error) {
+	var msg request.LicenseStatusMsg
+	path := "/licenses/" + url.PathEscape(id) + "/" + action + "?reason=" + url.QueryEscape(reason)
+	if _, err := b.do("POST", path, nil, &msg); err != nil {
+		return nil, err
+	}
+	return msg.License, nil
+}
+
+func (b *httpBackend) Renew(id string, expiration time.Time) (*service.LicenseMsg, error) {
+	var msg service.LicenseMsg
+	path := "/licenses/" + url.PathEscape(id) + "/renew?expiration=" + expiration.Format("2006-01-02")
+	if _, err := b.do("POST", path, nil, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (b *httpBackend) Products() ([]*store.Product, error) {
+	var msg request.ProductListMsg
+	if _, err := b.do("GET", "/products", nil, &msg); err != nil {
+		return nil, err
+	}
+	return msg.Products, nil
+}
+
+func (b *httpBackend) Customers() ([]*store.Customer, error) {
+	var msg request.CustomerListMsg
+	if _, err := b.do("GET", "/customers", nil, &msg); err != nil {
+		return nil, err
+	}
+	return msg.Customers, nil
+}
+
+func (b *httpBackend) Templates() ([]*store.Template, error) {
+	var msg request.TemplateListMsg
+	if _, err := b.do("GET", "/templates", nil, &msg); err != nil {
+		return nil, err
+	}
+	return msg.Templates, nil
+}
+
+// List 按最大页大小逐页获取全部许可证记录
+func (b *httpBackend) List() ([]*store.LicenseRecord, error) {
+	list := make([]*store.LicenseRecord, 0)
+	q := service.LicenseQuery{Limit: service.MaxPageSize}
+	for {
+		page, err := b.Query(q)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, page.Licenses...)
+		if page.NextCursor == "" {
+			return list, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func (b *httpBackend) Query(q service.LicenseQuery) (*service.LicensePage, error) {
+	values := url.Values{}
+	params := map[string]string{
+		"customer":      q.CustomerID,
+		"project":       q.Project,
+		"module":        q.Module,
+		"type":          q.Type,
+		"status":        q.Status,
+		"signatureCode": q.SignatureCode,
+		"q":             q.Search,
+		"sort":          q.Sort,
+		"cursor":        q.Cursor,
+	}
+	dates := map[string]time.Time{
+		"expiringBefore": q.ExpiringBefore,
+		"issuedFrom":     q.IssuedFrom,
+		"issuedTo":       q.IssuedTo,
+	}
+	for name, value := range params {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	for name, value := range dates {
+		if !value.IsZero() {
+			values.Set(name, value.Format("2006-01-02"))
+		}
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	var msg request.LicenseListMsg
+	if _, err := b.do("GET", "/licenses?"+values.Encode(), nil, &msg); err != nil {
+		return nil, err
+	}
+	return &service.LicensePage{Licenses: msg.Licenses, Total: msg.Total, NextCursor: msg.NextCursor}, nil
+}
+
+func (b *httpBackend) SetNotes(id string, notes string) (*store.LicenseRecord, error) {
+	var msg request.LicenseStatusMsg
+	if _, err := b.do("PUT", "/licenses/"+url.PathEscape(id)+"/notes", request.NotesBody{Notes: notes}, &msg); err != nil {
+		return nil, err
+	}
+	return msg.License, nil
+}
+
+func (b *httpBackend) Get(id string) (*store.LicenseRecord, error) {
+	var msg request.LicenseStatusMsg
+	if _, err := b.do("GET", "/licenses/"+url.PathEscape(id), nil, &msg); err != nil {
+		return nil, err
+	}
+	return msg.License, nil
+}
+
+// LicenseFile 下载许可文件需要许可证令牌，令牌取自许可证记录
+func (b *httpBackend) LicenseFile(id string) ([]byte, error) {
+	record, err := b.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	path := "/licenses/" + url.PathEscape(id) + "/file"
+	resp, data, err := b.send("GET", path, http.Header{request.LicenseTokenHeader: {record.LicenseID}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+func (b *httpBackend) Inspect(content []byte, machineCode string) (*service.LicenseInspection, error) {
+	path := "/licenses/inspect?machineCode=" + url.QueryEscape(machineCode)
+	resp, data, err := b.send("POST", path, http.Header{"Content-Type": {"application/octet-stream"}}, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("POST %s: %s: %s", path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	var msg request.InspectionMsg
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("POST %s: invalid response: %w", path, err)
+	}
+	return msg.Inspection, nil
+}
+
+func (b *httpBackend) SigningKey() (*service.SigningKeyInfo, error) {
+	var msg request.SigningKeyMsg
+	if _, err := b.do("GET", "/signing-key", nil, &msg); err != nil {
+		return nil, err
+	}
+	return &msg.SigningKeyInfo, nil
+}
+
+func (b *httpBackend) Close() error {
+	return nil
+}
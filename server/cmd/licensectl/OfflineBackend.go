@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/user"
+	"server/audit"
+	"server/request"
+	"server/service"
+	"server/store"
+	"time"
+)
+
+// storageSettings 离线模式读取的服务端配置键，与服务端配置文件的 [storage] 一致
+type storageSettings struct {
+	Storage struct {
+		StorePath  string `config:"store_path" default:"license-store.json" min:"1"`
+		AuditPath  string `config:"audit_path" default:"audit.log" min:"1"`
+		LicenseDir string `config:"license_dir" default:"." min:"1"`
+		SigningKey string `config:"signing_key" default:"signing.key" min:"1"`
+	} `config:"storage"`
+}
+
+// offlineBackend 直接读写服务端的存储、审计日志和许可文件目录，与运行中的服务器通过存储锁互斥
+type offlineBackend struct {
+	actor string
+}
+
+/*
+ * openOffline 按服务端配置打开存储、审计日志和签名私钥，服务器正在运行时存储已被锁定，返回错误
+ * @params: configPath string - 服务端配置文件路径，为空时使用默认值（当前目录下的 license-store.json 等），
+ *			LICENSE_STORAGE_* 环境变量同样生效
+ * @returns: *offlineBackend - 离线操作对象
+ *			error - 配置无效或存储、审计日志无法打开时返回错误
+ */
+func openOffline(configPath string) (*offlineBackend, error) {
+	cfg := config.New()
+	if configPath != "" {
+		var err error
+		if cfg, err = config.Load(configPath); err != nil {
+			return nil, err
+		}
+	}
+	overrides, err := config.NewOverrides("LICENSE", &storageSettings{})
+	if err != nil {
+		return nil, err
+	}
+	overrides.Apply(cfg)
+
+	var st storageSettings
+	if err := cfg.Bind(&st); err != nil {
+		return nil, err
+	}
+
+	// 存储被运行中的服务器锁定时，应通过 -server 使用服务器的 API
+	s, err := store.Open(st.Storage.StorePath)
+	if errors.Is(err, store.ErrLocked) {
+		return nil, fmt.Errorf("open license store %s: %w; use -server to manage a running server", st.Storage.StorePath, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open license store: %w", err)
+	}
+	store.SetDefault(s)
+	service.SetLicenseDir(st.Storage.LicenseDir)
+
+	// 与服务端使用同一个签名私钥，不存在时生成
+	key, _, err := service.LoadOrCreateSigningKey(st.Storage.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("load signing key: %w", err)
+	}
+	service.SetSigningKey(key)
+
+	al, err := audit.Open(st.Storage.AuditPath)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	audit.SetDefault(al)
+
+	// 离线操作记录到审计日志中的操作者为 licensectl:<系统用户名>
+	actor := "licensectl"
+	if u, err := user.Current(); err == nil {
+		actor += ":" + u.Username
+	}
+	return &offlineBackend{actor: actor}, nil
+}
+
+func (b *offlineBackend) CreateKey(name string, role string) (*request.APIKeyMsg, error) {
+	token, key, err := service.CreateAPIKey(name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit.Record(b.actor, audit.ActionKeyCreate, key.ID, map[string]string{"name": key.Name, "role": key.Role}); err != nil {
+		return nil, err
+	}
+	return &request.APIKeyMsg{Key: token, APIKey: key, Status: "Created", Code: 201}, nil
+}
+
+func (b *offlineBackend) Issue(p issueParams) (*service.LicenseMsg, error) {
+	license, content, err := service.IssueLicense(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与服务器的响应一致，返回带签名的许可文件内容
+	var msg service.LicenseMsg
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return nil, err
+	}
+	if err := audit.Record(b.actor, audit.ActionLicenseIssue, license.ID, service.IssueAuditDetails(license)); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (b *offlineBackend) BulkIssue(format string, input []byte) ([]byte, []service.BulkResult, error) {
+	rows, err := service.ParseBulk(format, bytes.NewReader(input))
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := service.IssueBulk(rows)
+	if errors.Is(err, service.ErrBulkRejected) {
+		return nil, result.Report, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, license := range result.Licenses {
+		details := service.IssueAuditDetails(license)
+		details["batch"] = result.Batch
+		if err := audit.Record(b.actor, audit.ActionLicenseIssue, license.ID, details); err != nil {
+			return nil, nil, err
+		}
+	}
+	var archive bytes.Buffer
+	if err := result.WriteArchive(&archive); err != nil {
+		return nil, nil, err
+	}
+	return archive.Bytes(), nil, nil
+}
+
+func (b *offlineBackend) Suspend(id string, reason string) (*store.LicenseRecord, error) {
+	record, err := service.SuspendLicense(id, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit.Record(b.actor, audit.ActionLicenseSuspend, id, map[string]string{"reason": reason}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (b *offlineBackend) Revoke(id string, reason string) (*store.LicenseRecord, error) {
+	record, err := service.RevokeLicense(id, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit.Record(b.actor, audit.ActionLicenseRevoke, id, map[string]string{"reason": reason}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (b *offlineBackend) Renew(id string, expiration time.Time) (*service.LicenseMsg, error) {
+	_, content, err := service.RenewLicense(id, expiration)
+	if err != nil {
+		return nil, err
+	}
+	var msg service.LicenseMsg
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return nil, err
+	}
+	if err := audit.Record(b.actor, audit.ActionLicenseRenew, id, map[string]string{"expiration": msg.Authorized.Expiration}); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (b *offlineBackend) Products() ([]*store.Product, error) {
+	s := store.Default()
+	if s == nil {
+		return nil, service.ErrStoreUnavailable
+	}
+	return s.ListProducts(), nil
+}
+
+func (b *offlineBackend) Customers() ([]*store.Customer, error) {
+	s := store.Default()
+	if s == nil {
+		return nil, service.ErrStoreUnavailable
+	}
+	return s.ListCustomers(), nil
+}
+
+func (b *offlineBackend) Templates() ([]*store.Template, error) {
+	s := store.Default()
+	if s == nil {
+		return nil, service.ErrStoreUnavailable
+	}
+	return s.ListTemplates(), nil
+}
+
+func (b *offlineBackend) List() ([]*store.LicenseRecord, error) {
+	return service.ListLicenses()
+}
+
+func (b *offlineBackend) Query(q service.LicenseQuery) (*service.LicensePage, error) {
+	return service.QueryLicenses(q)
+}
+
+func (b *offlineBackend) SetNotes(id string, notes string) (*store.LicenseRecord, error) {
+	record, err := service.SetLicenseNotes(id, notes)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit.Record(b.actor, audit.ActionLicenseNotes, id, map[string]string{"notes": record.Notes}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (b *offlineBackend) Get(id string) (*store.LicenseRecord, error) {
+	return service.GetLicenseRecord(id)
+}
+
+func (b *offlineBackend) LicenseFile(id string) ([]byte, error) {
+	return ioutil.ReadFile(service.LicenseFilePath(id))
+}
+
+func (b *offlineBackend) Inspect(content []byte, machineCode string) (*service.LicenseInspection, error) {
+	return service.InspectLicenseFile(content, machineCode, time.Now())
+}
+
+func (b *offlineBackend) SigningKey() (*service.SigningKeyInfo, error) {
+	info, err := service.CurrentSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (b *offlineBackend) Close() error {
+	return nil
+}
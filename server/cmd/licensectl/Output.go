@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"server/service"
+	"server/store"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// printer 按 -o 参数以表格或 JSON 输出结果
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// JSON 以缩进的 JSON 输出
+func (p printer) JSON(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Table 以对齐的表格输出，headers 为空时不输出表头
+func (p printer) Table(headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	if len(headers) > 0 {
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Print 输出结果：JSON 模式输出 v，表格模式输出 headers 和 rows
+func (p printer) Print(v interface{}, headers []string, rows [][]string) error {
+	if p.json {
+		return p.JSON(v)
+	}
+	return p.Table(headers, rows)
+}
+
+// bulkRows 批量签发报告的表格
+func bulkRows(report []service.BulkResult) ([]string, [][]string) {
+	headers := []string{"ROW", "MACHINE CODE", "ID", "EXPIRATION", "LICENSEE", "ERROR"}
+	rows := make([][]string, 0, len(report))
+	for _, r := range report {
+		rows = append(rows, []string{strconv.Itoa(r.Row), r.SignatureCode, r.ID, r.Expiration, r.Licensee, r.Error})
+	}
+	return headers, rows
+}
+
+// customerRows 客户列表的表格
+func customerRows(list []*store.Customer) ([]string, [][]string) {
+	headers := []string{"ID", "NAME", "EXTERNAL ID", "CONTACT", "EMAIL"}
+	rows := make([][]string, 0, len(list))
+	for _, c := range list {
+		rows = append(rows, []string{c.ID, c.Name, c.ExternalID, c.Contact.Name, c.Contact.Email})
+	}
+	return headers, rows
+}
+
+// templateRows 签发模板列表的表格
+func templateRows(list []*store.Template) ([]string, [][]string) {
+	headers := []string{"NAME", "VERSION", "PROJECT", "TYPE", "MODULE", "DAYS", "USERS", "STATUS"}
+	rows := make([][]string, 0, len(list))
+	for _, t := range list {
+		status := "active"
+		if t.Retired {
+			status = "retired"
+		}
+		rows = append(rows, []string{
+			t.Name, strconv.Itoa(t.Version), t.Project, t.Type, service.TemplateModule(t),
+			strconv.FormatUint(uint64(t.DurationDays), 10),
+			strconv.FormatUint(uint64(t.AllowedUsers), 10),
+			status,
+		})
+	}
+	return headers, rows
+}
+
+// productRows 产品目录的表格，每个产品版本一行
+func productRows(list []*store.Product) ([]string, [][]string) {
+	headers := []string{"PRODUCT", "EDITION", "FEATURES", "DAYS", "SEATS", "VERSIONS"}
+	rows := make([][]string, 0, len(list))
+	for _, p := range list {
+		versions := strings.Join(p.Versions, ", ")
+		if len(p.Editions) == 0 {
+			rows = append(rows, []string{p.ID, "-", service.JoinFeatures(p.Features), "-", "-", versions})
+		}
+		for _, e := range p.Editions {
+			rows = append(rows, []string{
+				p.ID, e.ID, service.JoinFeatures(e.Features),
+				strconv.FormatUint(uint64(e.DurationDays), 10),
+				strconv.FormatUint(uint64(e.Seats), 10),
+				versions,
+			})
+		}
+	}
+	return headers, rows
+}
+
+// recordRows 许可证记录列表的表格
+func recordRows(list []*store.LicenseRecord) ([]string, [][]string) {
+	headers := []string{"ID", "TYPE", "PROJECT", "MODULE", "USERS", "ISSUED", "EXPIRATION", "STATUS"}
+	rows := make([][]string, 0, len(list))
+	for _, r := range list {
+		rows = append(rows, []string{
+			r.ID, r.Type, r.Project, r.Module,
+			strconv.FormatUint(uint64(r.AllowedUsers), 10),
+			r.Date.Format("2006-01-02"),
+			r.ExpirationDate.Format("2006-01-02"),
+			statusText(r),
+		})
+	}
+	return headers, rows
+}
+
+func statusText(r *store.LicenseRecord) string {
+	if r.Status == "" {
+		return store.StatusActive
+	}
+	if r.StatusReason != "" && r.Status != store.StatusActive {
+		return r.Status + " (" + r.StatusReason + ")"
+	}
+	return r.Status
+}
+
+// authorizedRows 许可文件授权信息的键值表格
+func authorizedRows(a service.Authorized) [][]string {
+	rows := [][]string{
+		{"id", a.Id},
+		{"license", a.License},
+		{"date", a.Date},
+		{"signatureCode", a.SignatureCode},
+		{"type", a.Type},
+		{"expiration", a.Expiration + " (" + expiryText(a.Expiration, time.Now()) + ")"},
+		{"usersNum", a.AllowedUsers},
+		{"project", a.Project},
+		{"module", a.Module},
+	}
+	if a.Licensee != "" {
+		rows = append(rows, []string{"licensee", a.Licensee})
+	}
+	return rows
+}
+
+// expiryText 描述过期日期相对于当前时间的状态
+func expiryText(expiration string, now time.Time) string {
+	expiry := service.CheckExpiry(expiration, now)
+	switch expiry.Status {
+	case service.ExpiryInvalid:
+		return "invalid date"
+	case service.ExpiryExpired:
+		return fmt.Sprintf("expired %d days ago", -expiry.DaysLeft)
+	case service.ExpiryExpiresToday:
+		return "expires today"
+	}
+	return fmt.Sprintf("%d days left", expiry.DaysLeft)
+}
+
+// inspectionRows 许可文件检查结果的键值表格
+func inspectionRows(in *service.LicenseInspection, record *store.LicenseRecord) [][]string {
+	rows := [][]string{
+		{"format", in.Header.Format},
+		{"length", fmt.Sprintf("%d bytes encoded, %d decoded, %d payload", in.Header.EncodedLength, in.Header.DecodedLength, in.Header.PayloadLength)},
+		{"key source", in.Header.KeySource},
+	}
+	rows = append(rows, authorizedRows(in.Payload.Authorized)...)
+	rows = append(rows,
+		[]string{"payload status", fmt.Sprintf("%s (%d)", in.Payload.Status, in.Payload.Code)},
+		[]string{"signature", in.Signature.Status + ": " + in.Signature.Detail},
+	)
+	keyID := in.Signature.KeyID
+	if keyID == "" {
+		keyID = "none"
+	}
+	rows = append(rows,
+		[]string{"signing key", keyID},
+		[]string{"expiry", in.Expiry.Status},
+		[]string{"bound to", in.Binding.MachineCode},
+		[]string{"binding", in.Binding.Method},
+	)
+	if in.Binding.Matches != nil {
+		match := "no"
+		if *in.Binding.Matches {
+			match = "yes"
+		}
+		rows = append(rows, []string{"matches " + in.Binding.Expected, match})
+	}
+	if record != nil {
+		rows = append(rows, []string{"store status", statusText(record)})
+	} else {
+		rows = append(rows, []string{"store status", "unknown (not in the store)"})
+	}
+	for _, w := range in.Warnings {
+		rows = append(rows, []string{"warning", w})
+	}
+	return rows
+}
@@ -0,0 +1,94 @@
+/*
+ * licensectl 许可证服务器的运维命令行工具
+ * 指定 -server 时通过 HTTP API 操作运行中的服务器（使用 -api-key 认证），
+ * 否则直接读写服务端配置中的存储、审计日志和许可文件目录（离线模式，服务器运行时不要使用）
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"server/logger"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: licensectl [global flags] <command> [flags] [args]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(out, "\nCommand usage:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  licensectl %s\n", c.Usage)
+	}
+	fmt.Fprintf(out, "\nGlobal flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+
+	serverURL := flag.String("server", os.Getenv("LICENSECTL_SERVER"), "license server URL, e.g. https://licenses.example.com:8080 (env LICENSECTL_SERVER); offline mode when empty")
+	apiKey := flag.String("api-key", os.Getenv("LICENSECTL_API_KEY"), "API key for the license server (env LICENSECTL_API_KEY)")
+	configPath := flag.String("config", os.Getenv("LICENSE_CONFIG"), "server configuration file used to locate the store in offline mode")
+	output := flag.String("o", "table", "output format: table or json")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "licensectl: unknown output format %q\n", *output)
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].Name == flag.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "licensectl: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	// 服务层的日志只保留警告和错误，避免干扰命令输出
+	logger.Default().SetLevel(logger.LevelWarn)
+
+	// 只操作本地文件的命令（keygen）不需要服务器或存储
+	var b backend
+	switch {
+	case cmd.Local:
+	case *serverURL != "":
+		b = newHTTPBackend(*serverURL, *apiKey)
+	default:
+		ob, err := openOffline(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "licensectl:", err)
+			os.Exit(1)
+		}
+		b = ob
+	}
+	if b != nil {
+		defer func() {
+			_ = b.Close()
+		}()
+	}
+
+	ctx := &context{backend: b, out: printer{w: os.Stdout, json: *output == "json"}}
+	if err := cmd.Run(ctx, flag.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		if !errors.Is(err, errInvalid) {
+			fmt.Fprintln(os.Stderr, "licensectl:", err)
+		}
+		os.Exit(1)
+	}
+}
@@ -2,4 +2,9 @@ module server
 
 go 1.18
 
-require github.com/gorilla/mux v1.8.0
+require (
+	config v0.0.0
+	github.com/gorilla/mux v1.8.0
+)
+
+replace config => ../config
@@ -0,0 +1,287 @@
+/*
+ * Package logger 提供分级的结构化日志
+ * 支持 text 与 json 两种输出格式，输出到标准输出、标准错误或按大小轮转的文件，
+ * 并可通过 context 携带请求ID等字段
+ */
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level 日志级别
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String 返回日志级别名称
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+/*
+ * ParseLevel 解析日志级别名称
+ * @params: s string - debug、info、warn（warning）或 error，不区分大小写
+ * @returns: Level - 日志级别
+ *			error - 名称无效时返回错误
+ */
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q", s)
+}
+
+// Options 日志配置
+type Options struct {
+	Level      Level  // 最低输出级别
+	Format     string // 输出格式：text（默认）或 json
+	Output     string // 输出目标：stderr（默认）、stdout 或文件路径
+	MaxSizeMB  int    // 输出到文件时单个文件的最大大小（MB），0 表示不轮转
+	MaxBackups int    // 轮转时保留的历史文件个数
+}
+
+// core 多个派生 Logger 共享的输出目标与级别
+type core struct {
+	mutex  sync.Mutex
+	level  int32
+	json   bool
+	writer io.Writer
+}
+
+// Logger 结构化日志记录器，fields 为附加的键值对
+type Logger struct {
+	core   *core
+	fields []interface{}
+}
+
+type contextKey struct{}
+
+var (
+	defaultMutex  sync.RWMutex
+	defaultLogger = &Logger{core: &core{level: int32(LevelInfo), writer: os.Stderr}}
+)
+
+/*
+ * New 根据配置创建日志记录器
+ * @params: opts Options - 日志配置
+ * @returns: *Logger - 日志记录器
+ *			error - 格式无效或日志文件无法打开时返回错误
+ */
+func New(opts Options) (*Logger, error) {
+	c := &core{level: int32(opts.Level)}
+
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+	case "json":
+		c.json = true
+	default:
+		return nil, fmt.Errorf("invalid log format %q", opts.Format)
+	}
+
+	switch opts.Output {
+	case "", "stderr":
+		c.writer = os.Stderr
+	case "stdout":
+		c.writer = os.Stdout
+	default:
+		w, err := NewRotatingFile(opts.Output, opts.MaxSizeMB, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		c.writer = w
+	}
+	return &Logger{core: c}, nil
+}
+
+// NewWithWriter 创建输出到指定 io.Writer 的日志记录器
+func NewWithWriter(w io.Writer, level Level, jsonFormat bool) *Logger {
+	return &Logger{core: &core{level: int32(level), json: jsonFormat, writer: w}}
+}
+
+// SetDefault 设置全局默认日志记录器
+func SetDefault(l *Logger) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	defaultLogger = l
+}
+
+// Default 获取全局默认日志记录器
+func Default() *Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+
+	return defaultLogger
+}
+
+// NewContext 返回携带日志记录器的 context
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext 获取 context 中的日志记录器，不存在时返回默认日志记录器
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
+
+// SetLevel 修改最低输出级别，对所有派生的日志记录器生效
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(&l.core.level, int32(level))
+}
+
+// GetLevel 获取当前最低输出级别
+func (l *Logger) GetLevel() Level {
+	return Level(atomic.LoadInt32(&l.core.level))
+}
+
+// With 返回附加了键值对字段的日志记录器
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+	return &Logger{core: l.core, fields: fields}
+}
+
+// Debug 输出调试日志，kv 为交替出现的键和值
+func (l *Logger) Debug(msg string, kv ...interface{}) { l.log(LevelDebug, msg, kv) }
+
+// Info 输出普通日志
+func (l *Logger) Info(msg string, kv ...interface{}) { l.log(LevelInfo, msg, kv) }
+
+// Warn 输出警告日志
+func (l *Logger) Warn(msg string, kv ...interface{}) { l.log(LevelWarn, msg, kv) }
+
+// Error 输出错误日志
+func (l *Logger) Error(msg string, kv ...interface{}) { l.log(LevelError, msg, kv) }
+
+// Debug 使用默认日志记录器输出调试日志
+func Debug(msg string, kv ...interface{}) { Default().log(LevelDebug, msg, kv) }
+
+// Info 使用默认日志记录器输出普通日志
+func Info(msg string, kv ...interface{}) { Default().log(LevelInfo, msg, kv) }
+
+// Warn 使用默认日志记录器输出警告日志
+func Warn(msg string, kv ...interface{}) { Default().log(LevelWarn, msg, kv) }
+
+// Error 使用默认日志记录器输出错误日志
+func Error(msg string, kv ...interface{}) { Default().log(LevelError, msg, kv) }
+
+func (l *Logger) log(level Level, msg string, kv []interface{}) {
+	if level < l.GetLevel() {
+		return
+	}
+
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+
+	var buf bytes.Buffer
+	now := time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	if l.core.json {
+		writeJSON(&buf, now, level, msg, fields)
+	} else {
+		writeText(&buf, now, level, msg, fields)
+	}
+
+	l.core.mutex.Lock()
+	defer l.core.mutex.Unlock()
+	_, _ = l.core.writer.Write(buf.Bytes())
+}
+
+// writeText 输出形如 `time LEVEL msg key=value` 的文本日志
+func writeText(buf *bytes.Buffer, now string, level Level, msg string, fields []interface{}) {
+	buf.WriteString(now)
+	buf.WriteByte(' ')
+	buf.WriteString(strings.ToUpper(level.String()))
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+	for i := 0; i < len(fields); i += 2 {
+		key, value := field(fields, i)
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		s := fmt.Sprint(value)
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			s = fmt.Sprintf("%q", s)
+		}
+		buf.WriteString(s)
+	}
+	buf.WriteByte('\n')
+}
+
+// writeJSON 按字段顺序输出一行 JSON 日志
+func writeJSON(buf *bytes.Buffer, now string, level Level, msg string, fields []interface{}) {
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, now)
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, msg)
+	for i := 0; i < len(fields); i += 2 {
+		key, value := field(fields, i)
+		buf.WriteByte(',')
+		writeJSONValue(buf, key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, value)
+	}
+	buf.WriteString("}\n")
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		content, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(content)
+}
+
+// field 取出第 i 个键值对，键不是字符串或缺少值时做兼容处理
+func field(fields []interface{}, i int) (string, interface{}) {
+	key, ok := fields[i].(string)
+	if !ok {
+		key = fmt.Sprint(fields[i])
+	}
+	if i+1 >= len(fields) {
+		return key, "(MISSING)"
+	}
+	return key, fields[i+1]
+}
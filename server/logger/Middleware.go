@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader 请求ID所在的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// statusRecorder 记录处理函数写出的状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+/*
+ * Middleware 为每个请求分配请求ID，将携带请求ID的日志记录器放入请求 context，并在请求结束后输出访问日志
+ * 请求头中已有 X-Request-ID 时沿用该ID
+ * @params: next http.Handler - 下一个处理器
+ * @returns: http.Handler - 包装后的处理器
+ */
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		l := Default().With("requestId", requestID)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(NewContext(r.Context(), l)))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		level := LevelInfo
+		if rec.status >= 500 {
+			level = LevelError
+		} else if rec.status >= 400 {
+			level = LevelWarn
+		}
+		l.log(level, "http request", []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start).String(),
+			"remote", r.RemoteAddr,
+		})
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// rotateRetryInterval 轮转失败后再次尝试轮转前的等待时间，期间日志继续写入当前文件
+const rotateRetryInterval = time.Minute
+
+// RotatingFile 按大小轮转的日志文件，超过上限时将当前文件重命名为 path.1，历史文件依次后移
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mutex   sync.Mutex
+	file    *os.File // 当前文件无法打开时为 nil，下次写入时重试
+	size    int64
+	retryAt time.Time // 轮转失败后在此之前不再尝试轮转
+}
+
+/*
+ * NewRotatingFile 打开按大小轮转的日志文件
+ * @params: path string - 日志文件路径
+ *			maxSizeMB int - 单个文件最大大小（MB），0 表示不轮转
+ *			maxBackups int - 保留的历史文件个数
+ * @returns: *RotatingFile - 日志文件
+ *			error - 文件无法打开时返回错误
+ */
+func NewRotatingFile(path string, maxSizeMB int, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write 写入日志，写入前检查是否需要轮转；轮转失败时继续写入当前文件，避免日志丢失
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.maxSize > 0 && f.size+int64(len(p)) > f.maxSize && f.size > 0 && !time.Now().Before(f.retryAt) {
+		if err := f.rotate(); err != nil {
+			// 失败后等待一段时间再重试，避免每次写入都移动历史文件
+			f.retryAt = time.Now().Add(rotateRetryInterval)
+			_, _ = fmt.Fprintf(os.Stderr, "log rotation of %s failed, writing to the current file: %v\n", f.path, err)
+		}
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate 轮转日志文件；关闭或重命名失败时重新打开原文件，之后的日志继续写入原文件
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err == nil {
+		err = f.shift()
+	}
+	if err != nil {
+		if openErr := f.open(); openErr != nil {
+			return fmt.Errorf("%v; reopening %s: %v", err, f.path, openErr)
+		}
+		return err
+	}
+	return f.open()
+}
+
+// shift 把当前文件重命名为 path.1，历史文件依次后移；不保留历史文件时直接删除当前文件
+func (f *RotatingFile) shift() error {
+	if f.maxBackups > 0 {
+		_ = os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxBackups))
+		for i := f.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		}
+		return os.Rename(f.path, f.path+".1")
+	}
+	return os.Remove(f.path)
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	f, err := NewRotatingFile(path, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	f.maxSize = 10
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]string{"server.log": "fourth\n", "server.log.1": "third\n", "server.log.2": "second\n"} {
+		content, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more than maxBackups files are kept")
+	}
+}
+
+func TestRotatingFileKeepsWritingWhenRotationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	f, err := NewRotatingFile(path, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	f.maxSize = 10
+
+	// path.1 是非空目录，无法删除，也无法用日志文件替换，轮转在关闭当前文件之后失败
+	if err := os.MkdirAll(filepath.Join(path+".1", "busy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if n, err := f.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("Write(%q) = %d, %v, want the line written to the current file", line, n, err)
+		}
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "first\nsecond\nthird\n" {
+		t.Errorf("log file = %q, want every line kept", content)
+	}
+	if f.retryAt.IsZero() {
+		t.Error("a failed rotation is retried on every write")
+	}
+}
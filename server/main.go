@@ -1,12 +1,158 @@
 package main
 
 import (
-	"net/http"
+	"config"
+	"flag"
+	"fmt"
+	"os"
+	"server/audit"
+	"server/logger"
+	"server/metrics"
 	"server/router"
+	"server/service"
+	"server/store"
 )
 
 func main() {
 
+	// 命令行参数：-config 指定配置文件（也可以通过 LICENSE_CONFIG 指定），默认读取可执行文件所在目录下的 config.ini；
+	// 每个配置键都可以通过同名参数（例如 -server.addr）或环境变量（例如 LICENSE_SERVER_ADDR）覆盖，
+	// 优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
+	configPath := flag.String("config", os.Getenv(envPrefix+"_CONFIG"), "path to the configuration file in .ini, .json, .yaml or .toml format (default: config.ini next to the executable)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and where each value came from, then exit")
+	overrides, err := config.NewOverrides(envPrefix, &fileSettings{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	overrides.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.LoadDefault(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load configuration:", err)
+		os.Exit(1)
+	}
+	overrides.Apply(cfg)
+	if *printConfig {
+		if err := cfg.WriteEffective(os.Stdout, &fileSettings{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	st, err := loadSettings(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		return
+	}
+
+	// 配置日志
+	l, err := logger.New(st.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to configure logging:", err)
+		os.Exit(1)
+	}
+	logger.SetDefault(l)
+
+	// audit-verify 子命令：离线校验审计日志的哈希链后退出，-expect-head 为之前保存在日志之外的链头
+	if flag.Arg(0) == "audit-verify" {
+		fs := flag.NewFlagSet("audit-verify", flag.ExitOnError)
+		expectHead := fs.String("expect-head", "", "head (SEQ:HASH) printed by an earlier audit-verify and kept outside the log; fails if the log no longer contains it")
+		_ = fs.Parse(flag.Args()[1:])
+		path := st.AuditPath
+		if fs.NArg() > 0 {
+			path = fs.Arg(0)
+		}
+		var expected *audit.Head
+		if *expectHead != "" {
+			head, err := audit.ParseHead(*expectHead)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			expected = &head
+		}
+		head, n, err := audit.Verify(path, expected)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: %d entries, hash chain intact\nhead: %s\n", path, n, head)
+		return
+	}
+
+	// 打开许可证存储
+	s, err := store.Open(st.StorePath)
+	if err != nil {
+		logger.Error("failed to open license store", "path", st.StorePath, "error", err)
+		os.Exit(1)
+	}
+	store.SetDefault(s)
+	service.SetLicenseDir(st.LicenseDir)
+	service.SetCloneDetectionPolicy(st.CloneDetection)
+
+	// 读取签名许可文件的私钥，不存在时生成新的密钥对
+	signingKey, _, err := service.LoadOrCreateSigningKey(st.SigningKey)
+	if err != nil {
+		logger.Error("failed to load license signing key", "path", st.SigningKey, "error", err)
+		os.Exit(1)
+	}
+	service.SetSigningKey(signingKey)
+	logger.Info("license signing key loaded", "path", st.SigningKey, "keyId", service.NewSigningKeyInfo(signingKey).KeyID)
+
+	// 由存储实时计算的仪表盘指标
+	metrics.NewGaugeFunc("license_server_suspended_licenses", "Number of licenses currently suspended or revoked.", func() float64 {
+		return float64(len(s.ListRevokedLicenses()))
+	})
+	metrics.NewGaugeFunc("license_server_open_alerts", "Number of unresolved clone detection alerts.", func() float64 {
+		return float64(len(s.ListAlerts(false)))
+	})
+
+	// 打开审计日志
+	al, err := audit.Open(st.AuditPath)
+	if err != nil {
+		logger.Error("failed to open audit log", "path", st.AuditPath, "error", err)
+		os.Exit(1)
+	}
+	audit.SetDefault(al)
+
+	// bootstrap-admin 子命令：创建第一个管理员密钥后退出
+	if flag.Arg(0) == "bootstrap-admin" {
+		name := "admin"
+		if flag.NArg() > 1 {
+			name = flag.Arg(1)
+		}
+		token, key, err := service.BootstrapAdminKey(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := audit.Record("bootstrap", audit.ActionKeyCreate, key.ID, map[string]string{"name": key.Name, "role": key.Role}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created admin API key %s (%s). Store it safely, it will not be shown again:\n%s\n", key.ID, key.Name, token)
+		return
+	}
+
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unknown command %q, expected bootstrap-admin or audit-verify\n", flag.Arg(0))
+		os.Exit(2)
+	}
+
+	// 配置文件变化时热加载
+	if st.Reload.Watch {
+		stopWatch, err := watchConfig(cfg, st)
+		if err != nil {
+			logger.Info("configuration file watching disabled", "reason", err)
+		} else {
+			defer stopWatch()
+		}
+	}
+
 	r := router.SetupRouter()
 	if r == nil {
 		// 路由器配置失败，无法启动服务器
@@ -14,7 +160,8 @@ func main() {
 	}
 
 	// 启动服务器
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		panic(err)
+	if err := serve(r, st.Server); err != nil {
+		logger.Error("license server stopped", "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 许可证服务端的业务指标
+var (
+	// LicensesIssued 签发的许可证数量，按许可证类型和项目统计
+	LicensesIssued = NewCounterVec("license_server_licenses_issued_total",
+		"Number of licenses issued.", "type", "project")
+
+	// LicensesRenewed 续期的许可证数量，按许可证类型和项目统计
+	LicensesRenewed = NewCounterVec("license_server_licenses_renewed_total",
+		"Number of licenses renewed.", "type", "project")
+
+	// LicensesRevoked 被暂停或吊销的许可证数量，trigger 为 operator（人工暂停）、clone-detection（自动暂停）或 revoke（吊销）
+	LicensesRevoked = NewCounterVec("license_server_licenses_revoked_total",
+		"Number of licenses suspended or revoked.", "type", "project", "trigger")
+
+	// LicensesReinstated 被恢复的许可证数量
+	LicensesReinstated = NewCounterVec("license_server_licenses_reinstated_total",
+		"Number of suspended licenses reinstated.", "type", "project")
+
+	// VerificationRequests 客户端校验请求数量，kind 为 checkin 或 refresh，outcome 为处理结果
+	VerificationRequests = NewCounterVec("license_server_verification_requests_total",
+		"Number of client verification requests (check-ins and license refreshes) by outcome.", "kind", "outcome")
+
+	// StoreErrors 存储读写失败次数
+	StoreErrors = NewCounterVec("license_server_store_errors_total",
+		"Number of license store errors.", "operation")
+
+	// HTTPRequestDuration HTTP 请求耗时，按路由模板、方法和状态码统计
+	HTTPRequestDuration = NewHistogramVec("license_server_http_request_duration_seconds",
+		"HTTP request latency by route.", nil, "route", "method", "code")
+)
+
+// statusRecorder 记录处理函数写出的状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// routeKey 请求 context 中保存路由模板的键
+type routeKey struct{}
+
+/*
+ * Middleware 记录每个请求的耗时，需要包装整个路由器，未匹配任何路由的请求（404、405）也会被记录，路由标签为 unmatched
+ * 路由标签使用 mux 的路径模板，避免许可证ID等路径参数导致标签过多，模板由路由器内的 RouteMiddleware 写回；
+ * 方法标签只保留标准的 HTTP 方法，其余方法记为 other
+ * @params: next http.Handler - 下一个处理器，通常为路由器
+ * @returns: http.Handler - 包装后的处理器
+ */
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := "unmatched"
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(), route, methodLabel(r.Method), strconv.Itoa(rec.status))
+	})
+}
+
+// methodLabel 返回请求方法的标签值，客户端可以发送任意方法名，非标准方法统一记为 other，避免标签无限增长
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+/*
+ * RouteMiddleware 在路由器内通过 Use 注册，把匹配到的路由模板交给外层的 Middleware
+ * @params: next http.Handler - 下一个处理器
+ * @returns: http.Handler - 包装后的处理器
+ */
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*route = "unknown"
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					*route = template
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
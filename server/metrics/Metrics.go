@@ -0,0 +1,334 @@
+/*
+ * Package metrics 提供 Prometheus 文本格式的指标采集
+ * 只依赖标准库，支持带标签的计数器、直方图以及回调式的仪表盘指标
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector 可以输出指标的对象
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors []collector
+}
+
+// DefaultRegistry 默认注册表，/metrics 输出其中的全部指标
+var DefaultRegistry = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.collectors {
+		if existing.name() == c.name() {
+			panic("metrics: duplicate metric " + c.name())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText 以 Prometheus 文本格式输出全部指标
+func (r *Registry) WriteText(w io.Writer) {
+	r.mutex.RLock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mutex.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+/*
+ * Handler 返回输出默认注册表指标的HTTP处理函数
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	DefaultRegistry.WriteText(bw)
+	_ = bw.Flush()
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	metricName string
+	help       string
+	labels     []string
+
+	mutex  sync.Mutex
+	values map[string]*labeledValue
+}
+
+type labeledValue struct {
+	labelValues []string
+	value       float64
+}
+
+/*
+ * NewCounterVec 创建并注册带标签的计数器
+ * @params: name string - 指标名称
+ *			help string - 指标说明
+ *			labels ...string - 标签名称
+ * @returns: *CounterVec - 计数器
+ */
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		values:     make(map[string]*labeledValue),
+	}
+	DefaultRegistry.register(c)
+	return c
+}
+
+// Inc 将指定标签值的计数加一，标签值个数必须与标签名称一致
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 将指定标签值的计数增加 delta，delta 必须非负
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", c.metricName, len(c.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.values[key]
+	if !ok {
+		v = &labeledValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+// Value 获取指定标签值的当前计数
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if v, ok := c.values[strings.Join(labelValues, "\xff")]; ok {
+		return v.value
+	}
+	return 0
+}
+
+func (c *CounterVec) name() string { return c.metricName }
+
+func (c *CounterVec) write(w io.Writer) {
+	writeHeader(w, c.metricName, c.help, "counter")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		_, _ = fmt.Fprintf(w, "%s%s %s\n", c.metricName, formatLabels(c.labels, v.labelValues, "", ""), formatFloat(v.value))
+	}
+}
+
+// GaugeFunc 每次采集时通过回调获取数值的仪表盘指标
+type GaugeFunc struct {
+	metricName string
+	help       string
+	fn         func() float64
+}
+
+/*
+ * NewGaugeFunc 创建并注册回调式仪表盘指标
+ * @params: name string - 指标名称
+ *			help string - 指标说明
+ *			fn func() float64 - 采集时调用的回调
+ * @returns: *GaugeFunc - 仪表盘指标
+ */
+func NewGaugeFunc(name string, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, fn: fn}
+	DefaultRegistry.register(g)
+	return g
+}
+
+func (g *GaugeFunc) name() string { return g.metricName }
+
+func (g *GaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.metricName, g.help, "gauge")
+	_, _ = fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.fn()))
+}
+
+// DefaultBuckets 默认的直方图分桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	metricName string
+	help       string
+	labels     []string
+	buckets    []float64
+
+	mutex  sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+/*
+ * NewHistogramVec 创建并注册带标签的直方图
+ * @params: name string - 指标名称
+ *			help string - 指标说明
+ *			buckets []float64 - 分桶上限，为 nil 时使用 DefaultBuckets
+ *			labels ...string - 标签名称
+ * @returns: *HistogramVec - 直方图
+ */
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		buckets:    sorted,
+		values:     make(map[string]*histogramValue),
+	}
+	DefaultRegistry.register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", h.metricName, len(h.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = v
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *HistogramVec) name() string { return h.metricName }
+
+func (h *HistogramVec) write(w io.Writer) {
+	writeHeader(w, h.metricName, h.help, "histogram")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		for i, upper := range h.buckets {
+			_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(h.labels, v.labelValues, "le", formatFloat(upper)), v.counts[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(h.labels, v.labelValues, "le", "+Inf"), v.count)
+		_, _ = fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, formatLabels(h.labels, v.labelValues, "", ""), formatFloat(v.sum))
+		_, _ = fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, formatLabels(h.labels, v.labelValues, "", ""), v.count)
+	}
+}
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// formatLabels 输出 {a="x",b="y"} 形式的标签，extraName 不为空时追加一个额外标签（直方图的 le）
+func formatLabels(names []string, values []string, extraName string, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName)
+		b.WriteString(`="`)
+		b.WriteString(extraValue)
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
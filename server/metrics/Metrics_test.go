@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVecText(t *testing.T) {
+	c := NewCounterVec("test_counter_total", "Help with \\ backslash\nand newline.", "type", "project")
+	c.Inc("standard", `quote " and \ backslash`)
+	c.Add(2.5, "trial", "multi\nline")
+	c.Inc("standard", `quote " and \ backslash`)
+
+	var buf bytes.Buffer
+	c.write(&buf)
+
+	want := "# HELP test_counter_total Help with \\\\ backslash\\nand newline.\n" +
+		"# TYPE test_counter_total counter\n" +
+		"test_counter_total{type=\"standard\",project=\"quote \\\" and \\\\ backslash\"} 2\n" +
+		"test_counter_total{type=\"trial\",project=\"multi\\nline\"} 2.5\n"
+	if got := buf.String(); got != want {
+		t.Errorf("counter text:\n%s\nwant:\n%s", got, want)
+	}
+	if v := c.Value("trial", "multi\nline"); v != 2.5 {
+		t.Errorf("Value = %v, want 2.5", v)
+	}
+}
+
+func TestGaugeFuncText(t *testing.T) {
+	value := 3.0
+	g := NewGaugeFunc("test_gauge", "A gauge.", func() float64 { return value })
+
+	var buf bytes.Buffer
+	g.write(&buf)
+	want := "# HELP test_gauge A gauge.\n# TYPE test_gauge gauge\ntest_gauge 3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("gauge text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramVecText(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "A histogram.", []float64{1, 0.1}, "route")
+	h.Observe(0.05, "/licenses/{id}")
+	h.Observe(0.5, "/licenses/{id}")
+	h.Observe(3, "/licenses/{id}")
+
+	var buf bytes.Buffer
+	h.write(&buf)
+	want := "# HELP test_duration_seconds A histogram.\n" +
+		"# TYPE test_duration_seconds histogram\n" +
+		"test_duration_seconds_bucket{route=\"/licenses/{id}\",le=\"0.1\"} 1\n" +
+		"test_duration_seconds_bucket{route=\"/licenses/{id}\",le=\"1\"} 2\n" +
+		"test_duration_seconds_bucket{route=\"/licenses/{id}\",le=\"+Inf\"} 3\n" +
+		"test_duration_seconds_sum{route=\"/licenses/{id}\"} 3.55\n" +
+		"test_duration_seconds_count{route=\"/licenses/{id}\"} 3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("histogram text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	c := NewCounterVec("test_mismatch_total", "Mismatch.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with the wrong number of label values did not panic")
+		}
+	}()
+	c.Inc("only-one")
+}
+
+func TestHandler(t *testing.T) {
+	LicensesIssued.Inc("standard", "reports")
+
+	rec := httptest.NewRecorder()
+	Handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE license_server_licenses_issued_total counter",
+		`license_server_licenses_issued_total{type="standard",project="reports"} 1`,
+		"# TYPE license_server_http_request_duration_seconds histogram",
+		"# TYPE license_server_store_errors_total counter",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("/metrics output is missing %q", line)
+		}
+	}
+
+	// 指标按名称排序输出，每个指标的 HELP 紧跟 TYPE
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	var names []string
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# HELP ") {
+			name := strings.Fields(line)[2]
+			if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "# TYPE "+name+" ") {
+				t.Errorf("HELP for %s is not followed by its TYPE line", name)
+			}
+			names = append(names, name)
+		}
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("metrics are not sorted: %s before %s", names[i-1], names[i])
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/audit"
+	"server/service"
+	"server/store"
+)
+
+// APIKeyBody 创建 API 密钥的请求体
+type APIKeyBody struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// APIKeyMsg 创建 API 密钥的响应，Key 为密钥明文，仅返回一次
+type APIKeyMsg struct {
+	Key    string        `json:"key"`
+	APIKey *store.APIKey `json:"apiKey"`
+	Status string        `json:"status"`
+	Code   int           `json:"code"`
+}
+
+// APIKeyListMsg API 密钥列表响应
+type APIKeyListMsg struct {
+	APIKeys []*store.APIKey `json:"apiKeys"`
+	Status  string          `json:"status"`
+	Code    int             `json:"code"`
+}
+
+/*
+ * CreateAPIKeyRequest 创建新的 API 密钥
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func CreateAPIKeyRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body APIKeyBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid api key body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, key, err := service.CreateAPIKey(body.Name, body.Role)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionKeyCreate, key.ID, map[string]string{"name": key.Name, "role": key.Role}) {
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, APIKeyMsg{
+		Key:    token,
+		APIKey: key,
+		Status: http.StatusText(http.StatusCreated),
+		Code:   http.StatusCreated,
+	})
+}
+
+/*
+ * GetAPIKeysRequest 获取全部 API 密钥（不含密钥明文）
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetAPIKeysRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, APIKeyListMsg{
+		APIKeys: s.ListAPIKeys(),
+		Status:  http.StatusText(http.StatusOK),
+		Code:    http.StatusOK,
+	})
+}
+
+/*
+ * DisableAPIKeyRequest 停用指定的 API 密钥
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func DisableAPIKeyRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := s.DisableAPIKey(id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionKeyDisable, id, nil) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
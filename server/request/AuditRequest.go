@@ -0,0 +1,96 @@
+package request
+
+import (
+	"net/http"
+	"server/audit"
+	"server/auth"
+	"server/logger"
+	"strconv"
+	"time"
+)
+
+// AuditListMsg 审计记录列表响应，Head 为当前链头，可保存到日志之外供 audit-verify -expect-head 使用
+type AuditListMsg struct {
+	Entries []audit.Entry `json:"entries"`
+	Head    *audit.Head   `json:"head,omitempty"`
+	Status  string        `json:"status"`
+	Code    int           `json:"code"`
+}
+
+/*
+ * GetAuditRequest 查询审计日志
+ * 支持的查询参数：action、actor、target、since、until（RFC3339 或 2006-01-02）、limit
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetAuditRequest(w http.ResponseWriter, r *http.Request) {
+
+	l := audit.Default()
+	if l == nil {
+		http.Error(w, "audit log is not configured", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action: query.Get("action"),
+		Actor:  query.Get("actor"),
+		Target: query.Get("target"),
+	}
+
+	var err error
+	if filter.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		http.Error(w, "Invalid since value: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		http.Error(w, "Invalid until value: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, AuditListMsg{
+		Entries: l.Query(filter),
+		Head:    l.Head(),
+		Status:  http.StatusText(http.StatusOK),
+		Code:    http.StatusOK,
+	})
+}
+
+// parseTimeParam 解析 RFC3339 或 2006-01-02 格式的时间参数，空字符串返回零值
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+/*
+ * recordAudit 以当前请求的认证身份记录一条审计日志
+ * 写入失败时返回 500 并返回 false，调用方不再写入成功响应：修改已经保存，但没有审计记录的修改不能报告为成功
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * 			 action string - 操作类型
+ * 			 target string - 操作对象
+ * 			 details map[string]string - 附加信息
+ * @returns: bool - 是否写入成功
+ */
+func recordAudit(w http.ResponseWriter, r *http.Request, action string, target string, details map[string]string) bool {
+
+	if err := audit.Record(auth.Actor(auth.FromContext(r.Context())), action, target, details); err != nil {
+		logger.FromContext(r.Context()).Error("failed to write audit log, the change was applied without an audit record",
+			"action", action, "target", target, "error", err)
+		http.Error(w, "The change was applied but could not be recorded in the audit log", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
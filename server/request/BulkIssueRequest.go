@@ -0,0 +1,88 @@
+package request
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"server/audit"
+	"server/logger"
+	"server/service"
+)
+
+// maxBulkBodySize 批量签发请求体的最大字节数
+const maxBulkBodySize = 8 << 20
+
+// BulkReportMsg 批量签发被拒绝时的响应，Failures 列出全部无效的行
+type BulkReportMsg struct {
+	Failures []service.BulkResult `json:"failures"`
+	Status   string               `json:"status"`
+	Code     int                  `json:"code"`
+}
+
+// bulkFormat 根据查询参数 format 或 Content-Type 确定批量签发的输入格式
+func bulkFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return "csv"
+	case "application/json":
+		return "json"
+	}
+	return ""
+}
+
+/*
+ * BulkIssueRequest 批量签发许可证
+ * 请求体为 CSV（Content-Type: text/csv，第一行为列名）或 POST /licenses 请求体组成的 JSON 数组（Content-Type: application/json），
+ * 也可用查询参数 format 指定格式；全部行有效时在一次事务中签发，返回包含许可文件和 report.csv 的 zip 压缩包，
+ * 任一行无效时不签发任何许可证，返回 422 和全部无效行的报告
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func BulkIssueRequest(w http.ResponseWriter, r *http.Request) {
+
+	format := bulkFormat(r)
+	if format == "" {
+		http.Error(w, "Unsupported bulk input: use Content-Type text/csv or application/json, or ?format=csv|json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	rows, err := service.ParseBulk(format, http.MaxBytesReader(w, r.Body, maxBulkBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := service.IssueBulk(rows)
+	if errors.Is(err, service.ErrBulkRejected) {
+		writeJSON(w, http.StatusUnprocessableEntity, BulkReportMsg{
+			Failures: result.Report,
+			Status:   http.StatusText(http.StatusUnprocessableEntity),
+			Code:     http.StatusUnprocessableEntity,
+		})
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	for _, license := range result.Licenses {
+		details := service.IssueAuditDetails(license)
+		details["batch"] = result.Batch
+		if !recordAudit(w, r, audit.ActionLicenseIssue, license.ID, details) {
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="licenses-`+result.Batch+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	if err := result.WriteArchive(w); err != nil {
+		logger.FromContext(r.Context()).Error("failed to write bulk license archive", "batch", result.Batch, "error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"server/metrics"
+	"server/service"
+	"server/store"
+)
+
+// CheckinBody 客户端签到请求体
+type CheckinBody struct {
+	LicenseID string `json:"licenseId"`
+	// Token 许可文件中的许可证令牌（license 字段），只有持有许可文件的客户端才知道，用于认证签到
+	Token       string `json:"license"`
+	Fingerprint string `json:"fingerprint"`
+	AppVersion  string `json:"appVersion"`
+	ActiveUsers uint   `json:"activeUsers"`
+}
+
+// CheckinMsg 签到响应
+type CheckinMsg struct {
+	Usage  *store.LicenseUsage `json:"usage"`
+	Status string              `json:"status"`
+	Code   int                 `json:"code"`
+}
+
+// CheckinListMsg 签到汇总列表响应
+type CheckinListMsg struct {
+	Usages []*store.LicenseUsage `json:"usages"`
+	Status string                `json:"status"`
+	Code   int                   `json:"code"`
+}
+
+/*
+ * PostCheckinRequest 处理客户端签到请求，记录许可证ID、机器指纹、程序版本和活跃用户数
+ * 该接口不需要 API 密钥，请求体必须包含许可文件中的许可证令牌，令牌不匹配时返回 403，不记录签到
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func PostCheckinRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body CheckinBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		metrics.VerificationRequests.Inc("checkin", "invalid")
+		http.Error(w, "Invalid check-in body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !licenseIDPattern.MatchString(body.LicenseID) {
+		metrics.VerificationRequests.Inc("checkin", "invalid")
+		http.Error(w, "Invalid license id", http.StatusBadRequest)
+		return
+	}
+
+	if body.Token == "" {
+		metrics.VerificationRequests.Inc("checkin", "invalid")
+		http.Error(w, "License token is required", http.StatusBadRequest)
+		return
+	}
+
+	if body.Fingerprint == "" {
+		metrics.VerificationRequests.Inc("checkin", "invalid")
+		http.Error(w, "Fingerprint is required", http.StatusBadRequest)
+		return
+	}
+
+	remoteAddr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteAddr = r.RemoteAddr
+	}
+
+	usage, err := service.RecordCheckin(body.Token, store.Checkin{
+		LicenseID:   body.LicenseID,
+		Fingerprint: body.Fingerprint,
+		AppVersion:  body.AppVersion,
+		ActiveUsers: body.ActiveUsers,
+		RemoteAddr:  remoteAddr,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownLicense) {
+			metrics.VerificationRequests.Inc("checkin", "unknown_license")
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCheckinToken) {
+			metrics.VerificationRequests.Inc("checkin", "unauthorized")
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		metrics.VerificationRequests.Inc("checkin", "error")
+		internalError(w, r, err)
+		return
+	}
+
+	if usage.SuspectedClone {
+		metrics.VerificationRequests.Inc("checkin", "suspected_clone")
+	} else {
+		metrics.VerificationRequests.Inc("checkin", "ok")
+	}
+
+	writeJSON(w, http.StatusOK, CheckinMsg{
+		Usage:  usage,
+		Status: http.StatusText(http.StatusOK),
+		Code:   http.StatusOK,
+	})
+}
+
+/*
+ * GetCheckinsRequest 获取全部许可证的签到汇总
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetCheckinsRequest(w http.ResponseWriter, r *http.Request) {
+
+	usages, err := service.ListCheckins()
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CheckinListMsg{
+		Usages: usages,
+		Status: http.StatusText(http.StatusOK),
+		Code:   http.StatusOK,
+	})
+}
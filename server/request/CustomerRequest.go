@@ -0,0 +1,222 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/audit"
+	"server/service"
+	"server/store"
+	"strings"
+)
+
+// CustomerMsg 单个客户的响应
+type CustomerMsg struct {
+	Customer *store.Customer `json:"customer"`
+	Status   string          `json:"status"`
+	Code     int             `json:"code"`
+}
+
+// CustomerListMsg 客户列表响应
+type CustomerListMsg struct {
+	Customers []*store.Customer `json:"customers"`
+	Status    string            `json:"status"`
+	Code      int               `json:"code"`
+}
+
+// customerError 将客户错误转换为对应的 HTTP 状态码
+func customerError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCustomer):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUnknownCustomer), errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Customer not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrDuplicateCustomer), errors.Is(err, store.ErrCustomerInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		internalError(w, r, err)
+	}
+}
+
+// customerDetails 客户的审计附加信息
+func customerDetails(customer *store.Customer) map[string]string {
+	return map[string]string{
+		"name":       customer.Name,
+		"externalId": customer.ExternalID,
+		"contact":    customer.Contact.Email,
+	}
+}
+
+/*
+ * GetCustomersRequest 获取全部客户，查询参数 externalId 按外部 CRM ID 过滤，name 按名称（不区分大小写的子串）过滤
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetCustomersRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	externalID := r.URL.Query().Get("externalId")
+	name := strings.ToLower(r.URL.Query().Get("name"))
+	customers := make([]*store.Customer, 0)
+	for _, customer := range s.ListCustomers() {
+		if externalID != "" && !strings.EqualFold(customer.ExternalID, externalID) {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(customer.Name), name) {
+			continue
+		}
+		customers = append(customers, customer)
+	}
+
+	writeJSON(w, http.StatusOK, CustomerListMsg{
+		Customers: customers,
+		Status:    http.StatusText(http.StatusOK),
+		Code:      http.StatusOK,
+	})
+}
+
+/*
+ * GetCustomerRequest 获取单个客户
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetCustomerRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	customer, err := s.GetCustomer(mux.Vars(r)["id"])
+	if err != nil {
+		customerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CustomerMsg{
+		Customer: customer,
+		Status:   http.StatusText(http.StatusOK),
+		Code:     http.StatusOK,
+	})
+}
+
+/*
+ * GetCustomerLicensesRequest 获取关联到客户的全部许可证
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetCustomerLicensesRequest(w http.ResponseWriter, r *http.Request) {
+
+	licenses, err := service.CustomerLicenses(mux.Vars(r)["id"])
+	if err != nil {
+		customerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LicenseListMsg{
+		Licenses: licenses,
+		Total:    len(licenses),
+		Status:   http.StatusText(http.StatusOK),
+		Code:     http.StatusOK,
+	})
+}
+
+/*
+ * CreateCustomerRequest 创建客户，客户ID由服务端生成
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func CreateCustomerRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body store.Customer
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid customer body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	customer, err := service.CreateCustomer(&body)
+	if err != nil {
+		customerError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionCustomerCreate, customer.ID, customerDetails(customer)) {
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CustomerMsg{
+		Customer: customer,
+		Status:   http.StatusText(http.StatusCreated),
+		Code:     http.StatusCreated,
+	})
+}
+
+/*
+ * UpdateCustomerRequest 覆盖客户信息，请求体中的ID被忽略
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func UpdateCustomerRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body store.Customer
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid customer body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	body.ID = mux.Vars(r)["id"]
+
+	customer, err := service.UpdateCustomer(&body)
+	if err != nil {
+		customerError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionCustomerUpdate, customer.ID, customerDetails(customer)) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CustomerMsg{
+		Customer: customer,
+		Status:   http.StatusText(http.StatusOK),
+		Code:     http.StatusOK,
+	})
+}
+
+/*
+ * DeleteCustomerRequest 删除没有关联许可证的客户
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func DeleteCustomerRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := s.DeleteCustomer(id); err != nil {
+		customerError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionCustomerDelete, id, nil) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
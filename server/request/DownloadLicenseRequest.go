@@ -0,0 +1,99 @@
+package request
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"server/metrics"
+	"server/service"
+	"server/store"
+)
+
+// licenseIDPattern 许可证ID由 utils.GenerateUniqueID 生成，为 16 位纯数字
+var licenseIDPattern = regexp.MustCompile(`^[0-9]{16}$`)
+
+// LicenseTokenHeader 下载许可文件时携带许可证令牌（许可文件的 license 字段）的请求头
+const LicenseTokenHeader = "X-License-Token"
+
+/*
+ * DownloadLicenseRequest 下载已生成的许可文件，供客户端在线刷新使用，请求需在 X-License-Token 中携带许可证令牌
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func DownloadLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	id := mux.Vars(r)["id"]
+	if !licenseIDPattern.MatchString(id) {
+		metrics.VerificationRequests.Inc("refresh", "invalid")
+		http.Error(w, "Invalid license id", http.StatusBadRequest)
+		return
+	}
+
+	// 许可证ID会出现在吊销列表和日志中，只有同时提供许可文件中的许可证令牌才能下载，避免他人凭ID取得许可文件
+	token := r.Header.Get(LicenseTokenHeader)
+	if token == "" {
+		metrics.VerificationRequests.Inc("refresh", "unauthorized")
+		http.Error(w, LicenseTokenHeader+" header is required", http.StatusUnauthorized)
+		return
+	}
+	record, err := service.AuthenticateLicense(id, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownLicense):
+			metrics.VerificationRequests.Inc("refresh", "not_found")
+			http.Error(w, "License not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidCheckinToken):
+			metrics.VerificationRequests.Inc("refresh", "unauthorized")
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrStoreUnavailable):
+			metrics.VerificationRequests.Inc("refresh", "error")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			metrics.VerificationRequests.Inc("refresh", "error")
+			internalError(w, r, err)
+		}
+		return
+	}
+
+	// 已暂停或吊销的许可证不再提供下载，客户端据此得知许可已失效
+	if record.Status != "" && record.Status != store.StatusActive {
+		metrics.VerificationRequests.Inc("refresh", "revoked")
+		http.Error(w, "License is "+record.Status, http.StatusGone)
+		return
+	}
+
+	content, err := ioutil.ReadFile(service.LicenseFilePath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			metrics.VerificationRequests.Inc("refresh", "not_found")
+			http.Error(w, "License not found", http.StatusNotFound)
+			return
+		}
+		metrics.VerificationRequests.Inc("refresh", "error")
+		internalError(w, r, err)
+		return
+	}
+
+	// ETag 为许可文件内容的 SHA-256，客户端用本地文件计算出相同的值并通过 If-None-Match 发送，未变化时返回 304
+	sum := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		metrics.VerificationRequests.Inc("refresh", "not_modified")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	metrics.VerificationRequests.Inc("refresh", "ok")
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+id+`.license"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
@@ -8,36 +8,19 @@
 package request
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
 	"regexp"
+	"server/audit"
 	"server/service"
-	"server/utils"
 	"strconv"
 	"time"
 )
 
 // Authorized 授权详细信息
-type Authorized struct {
-	Id            string `json:"id"`
-	License       string `json:"license"`
-	Date          string `json:"date"`
-	SignatureCode string `json:"signatureCode"`
-	Type          string `json:"type"`
-	Expiration    string `json:"expiration"`
-	AllowedUsers  string `json:"usersNum"`
-	Project       string `json:"project"`
-	Module        string `json:"module"`
-}
+type Authorized = service.Authorized
 
 // Msg 授权信息、状态和代码
-type Msg struct {
-	Authorized Authorized `json:"authorized"`
-	Status     string     `json:"status"`
-	Code       int        `json:"code"`
-}
+type Msg = service.LicenseMsg
 
 /*
  * GetLicenseRequest 处理获取许可证请求的HTTP处理程序
@@ -52,8 +35,12 @@ func GetLicenseRequest(w http.ResponseWriter, r *http.Request) {
 	usersNumberString := r.URL.Query().Get("usersNum")
 	obj := r.URL.Query().Get("object")
 	module := r.URL.Query().Get("module")
+	version := r.URL.Query().Get("version")
+	customer := r.URL.Query().Get("customer")
+	licensee := r.URL.Query().Get("licensee")
+	notes := r.URL.Query().Get("notes")
 
-	// 校验 signatureCode 是否为 32 位纯数字
+	// 校验 signatureCode 非空且不超过 32 个字符，不限制字符种类
 	signatureCode := r.URL.Query().Get("signatureCode")
 	matched, err := regexp.MatchString(`^.{1,32}$`, signatureCode)
 	if err != nil {
@@ -66,80 +53,51 @@ func GetLicenseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 校验输入参数并检查是否正确
-	expiration, err := time.Parse("2006-01-02", expirationString)
-	if err != nil {
-		http.Error(w, "Invalid expiration date format: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	usersNumber, err := strconv.ParseUint(usersNumberString, 10, 32)
-	if err != nil {
-		http.Error(w, "Invalid allowed users value: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// 使用输入参数调用GenerateLicense函数生成许可证
-	license, err := service.GenerateLicense(signatureCode, licenseType, expiration, uint(usersNumber), obj, module)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// 构建响应结构体
-	msg := Msg{
-		Authorized: Authorized{
-			Id:            utils.GenerateUniqueID(),
-			SignatureCode: license.SignatureCode,
-			License:       license.LicenseID,
-			Date:          license.Date.Format("2006-01-02 15:04:05"),
-			Type:          license.Type,
-			Expiration:    license.ExpirationDate.Format("2006-01-02"),
-			AllowedUsers:  strconv.FormatUint(uint64(license.AllowedUsers), 10),
-			Project:       license.Project,
-			Module:        license.Module,
-		},
-		Status: http.StatusText(200),
-		Code:   http.StatusOK,
-	}
-
-	// 将响应结构体转换为JSON格式
-	response, err := json.Marshal(msg)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// 校验输入参数并检查是否正确，过期日期和用户数量省略时取产品版本的默认值
+	var expiration time.Time
+	if expirationString != "" {
+		expiration, err = time.Parse("2006-01-02", expirationString)
+		if err != nil {
+			http.Error(w, "Invalid expiration date format: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
-	// 以json数据的id字段作为文件名存储到文件中
-	licenseFileName := msg.Authorized.Id + ".license"
-	file, err := os.Create(licenseFileName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer func(file *os.File) {
-		err := file.Close()
+	var usersNumber uint64
+	if usersNumberString != "" {
+		usersNumber, err = strconv.ParseUint(usersNumberString, 10, 32)
 		if err != nil {
+			http.Error(w, "Invalid allowed users value: "+err.Error(), http.StatusBadRequest)
+			return
 		}
-	}(file)
+	}
 
-	// 对数据进行加密操作
-	encrypted, err := utils.ObfuscationUtil(response, signatureCode)
+	// 按产品目录校验输入参数并签发许可证，混淆后的许可文件以许可证ID作为文件名写入许可文件目录
+	license, response, err := service.IssueLicense(service.IssueRequest{
+		SignatureCode: signatureCode,
+		Type:          licenseType,
+		Expiration:    expiration,
+		AllowedUsers:  uint(usersNumber),
+		Project:       obj,
+		Module:        module,
+		Version:       version,
+		CustomerID:    customer,
+		Licensee:      licensee,
+		Notes:         notes,
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if service.IsCatalogError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		internalError(w, r, err)
 		return
 	}
 
-	// 将加密后的数据写入文件
-	_, err = file.WriteString(encrypted)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !recordAudit(w, r, audit.ActionLicenseIssue, license.ID, service.IssueAuditDetails(license)) {
 		return
 	}
 
-	// 设置响应头并将JSON格式的响应写入HTTP响应写入器中
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprint(w, string(response))
+	writeLicenseContent(w, http.StatusOK, response)
 
 }
@@ -0,0 +1,46 @@
+package request
+
+import (
+	"io/ioutil"
+	"net/http"
+	"server/service"
+	"time"
+)
+
+// maxLicenseFileSize 检查的许可文件的最大字节数，服务端生成的许可文件约为 5.5 KB
+const maxLicenseFileSize = 64 << 10
+
+// InspectionMsg 许可文件检查结果的响应
+type InspectionMsg struct {
+	Inspection *service.LicenseInspection `json:"inspection"`
+	Status     string                     `json:"status"`
+	Code       int                        `json:"code"`
+}
+
+/*
+ * InspectLicenseRequest 解码并检查请求体中的许可文件，查询参数 machineCode 可选；
+ * 结果包含许可文件绑定的机器码，因此只允许管理员调用
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func InspectLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	content, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxLicenseFileSize))
+	if err != nil {
+		http.Error(w, "Invalid license file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inspection, err := service.InspectLicenseFile(content, r.URL.Query().Get("machineCode"), time.Now())
+	if err != nil {
+		http.Error(w, "Invalid license file: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, InspectionMsg{
+		Inspection: inspection,
+		Status:     http.StatusText(http.StatusOK),
+		Code:       http.StatusOK,
+	})
+}
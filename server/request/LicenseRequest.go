@@ -0,0 +1,214 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/audit"
+	"server/service"
+	"server/store"
+	"strconv"
+	"time"
+)
+
+// IssueBody POST /licenses 的请求体
+type IssueBody = service.IssueBody
+
+// LicenseListMsg 许可证列表响应，Total 为满足条件的许可证总数，NextCursor 为空表示没有下一页
+type LicenseListMsg struct {
+	Licenses   []*store.LicenseRecord `json:"licenses"`
+	Total      int                    `json:"total"`
+	NextCursor string                 `json:"nextCursor,omitempty"`
+	Status     string                 `json:"status"`
+	Code       int                    `json:"code"`
+}
+
+// NotesBody 修改许可证备注的请求体
+type NotesBody struct {
+	Notes string `json:"notes"`
+}
+
+/*
+ * GetLicensesRequest 查询许可证记录
+ * 过滤参数：customer、project、module、type、status、signatureCode、expiringBefore、issuedFrom、issuedTo（日期格式 2006-01-02），
+ * q 全文搜索备注和被许可方名称；sort 为排序字段，前缀 - 表示降序；limit 为每页数量，cursor 为上一页返回的 nextCursor
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetLicensesRequest(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	q := service.LicenseQuery{
+		CustomerID:    query.Get("customer"),
+		Project:       query.Get("project"),
+		Module:        query.Get("module"),
+		Type:          query.Get("type"),
+		Status:        query.Get("status"),
+		SignatureCode: query.Get("signatureCode"),
+		Search:        query.Get("q"),
+		Sort:          query.Get("sort"),
+		Cursor:        query.Get("cursor"),
+	}
+	dates := map[string]*time.Time{
+		"expiringBefore": &q.ExpiringBefore,
+		"issuedFrom":     &q.IssuedFrom,
+		"issuedTo":       &q.IssuedTo,
+	}
+	for name, target := range dates {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" date format: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			*target = t
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit value: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.Limit = limit
+	}
+
+	page, err := service.QueryLicenses(q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LicenseListMsg{
+		Licenses:   page.Licenses,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		Status:     http.StatusText(http.StatusOK),
+		Code:       http.StatusOK,
+	})
+}
+
+/*
+ * GetLicenseRecordRequest 获取单个许可证记录
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetLicenseRecordRequest(w http.ResponseWriter, r *http.Request) {
+
+	record, err := service.GetLicenseRecord(mux.Vars(r)["id"])
+	writeLicenseStatus(w, r, record, err)
+}
+
+/*
+ * SetLicenseNotesRequest 修改许可证备注，备注只保存在服务端
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func SetLicenseNotesRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body NotesBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid notes body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	record, err := service.SetLicenseNotes(id, body.Notes)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownLicense) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionLicenseNotes, id, map[string]string{"notes": record.Notes}) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LicenseStatusMsg{
+		License: record,
+		Status:  http.StatusText(http.StatusOK),
+		Code:    http.StatusOK,
+	})
+}
+
+/*
+ * CreateLicenseRequest 以 JSON 请求体签发许可证，可引用签发模板并覆盖其中的字段，返回签名后的许可文件内容
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func CreateLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body IssueBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid license body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := body.IssueRequest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	license, content, err := service.IssueLicense(req)
+	if err != nil {
+		if service.IsCatalogError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionLicenseIssue, license.ID, service.IssueAuditDetails(license)) {
+		return
+	}
+
+	writeLicenseContent(w, http.StatusCreated, content)
+}
+
+/*
+ * RenewLicenseRequest 延长许可证的过期日期，查询参数 expiration 为新的过期日期（2006-01-02），返回签名后的许可文件内容
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func RenewLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	expiration, err := time.Parse("2006-01-02", r.URL.Query().Get("expiration"))
+	if err != nil {
+		http.Error(w, "Invalid expiration date format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	license, content, err := service.RenewLicense(id, expiration)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownLicense):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrLicenseInactive), errors.Is(err, service.ErrInvalidExpiration):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			internalError(w, r, err)
+		}
+		return
+	}
+
+	msg := service.NewLicenseMsg(license)
+	if !recordAudit(w, r, audit.ActionLicenseRenew, id, map[string]string{"expiration": msg.Authorized.Expiration}) {
+		return
+	}
+	writeLicenseContent(w, http.StatusOK, content)
+}
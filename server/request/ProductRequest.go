@@ -0,0 +1,200 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/audit"
+	"server/service"
+	"server/store"
+	"strings"
+)
+
+// ProductMsg 单个产品的响应
+type ProductMsg struct {
+	Product *store.Product `json:"product"`
+	Status  string         `json:"status"`
+	Code    int            `json:"code"`
+}
+
+// ProductListMsg 产品目录响应
+type ProductListMsg struct {
+	Products []*store.Product `json:"products"`
+	Status   string           `json:"status"`
+	Code     int              `json:"code"`
+}
+
+// productError 将产品目录错误转换为对应的 HTTP 状态码
+func productError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProduct):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUnknownProduct):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrDuplicateProduct):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		internalError(w, r, err)
+	}
+}
+
+// productDetails 产品的审计附加信息
+func productDetails(product *store.Product) map[string]string {
+	editions := make([]string, 0, len(product.Editions))
+	for _, edition := range product.Editions {
+		editions = append(editions, edition.ID)
+	}
+	return map[string]string{
+		"name":     product.Name,
+		"editions": strings.Join(editions, ", "),
+		"features": service.JoinFeatures(product.Features),
+		"versions": strings.Join(product.Versions, ", "),
+	}
+}
+
+/*
+ * GetProductsRequest 获取产品目录
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetProductsRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProductListMsg{
+		Products: s.ListProducts(),
+		Status:   http.StatusText(http.StatusOK),
+		Code:     http.StatusOK,
+	})
+}
+
+/*
+ * GetProductRequest 获取单个产品
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetProductRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	product, err := s.GetProduct(mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProductMsg{
+		Product: product,
+		Status:  http.StatusText(http.StatusOK),
+		Code:    http.StatusOK,
+	})
+}
+
+/*
+ * CreateProductRequest 向产品目录添加产品
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func CreateProductRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body store.Product
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid product body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	product, err := service.CreateProduct(&body)
+	if err != nil {
+		productError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionProductCreate, product.ID, productDetails(product)) {
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ProductMsg{
+		Product: product,
+		Status:  http.StatusText(http.StatusCreated),
+		Code:    http.StatusCreated,
+	})
+}
+
+/*
+ * UpdateProductRequest 覆盖产品目录中的产品，请求体中的ID被忽略
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func UpdateProductRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body store.Product
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid product body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	body.ID = mux.Vars(r)["id"]
+
+	product, err := service.UpdateProduct(&body)
+	if err != nil {
+		productError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionProductUpdate, product.ID, productDetails(product)) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProductMsg{
+		Product: product,
+		Status:  http.StatusText(http.StatusOK),
+		Code:    http.StatusOK,
+	})
+}
+
+/*
+ * DeleteProductRequest 从产品目录删除产品，已签发的许可证不受影响
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func DeleteProductRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := s.DeleteProduct(id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionProductDelete, id, nil) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
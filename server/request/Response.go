@@ -0,0 +1,54 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"server/logger"
+)
+
+/*
+ * writeJSON 将响应结构体序列化为JSON并写入HTTP响应
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 code int - HTTP状态码
+ * 			 v interface{} - 响应结构体
+ * @returns: null
+ */
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+
+	response, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(response)
+}
+
+/*
+ * writeLicenseContent 写入签名后的许可文件内容（JSON），签发、续期和 /generate_license 返回相同的内容
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 code int - HTTP状态码
+ * 			 content []byte - service.IssueLicense 等返回的许可文件内容
+ * @returns: null
+ */
+func writeLicenseContent(w http.ResponseWriter, code int, content []byte) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(content)
+}
+
+/*
+ * internalError 记录服务端内部错误并返回 500
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * 			 err error - 错误对象
+ * @returns: null
+ */
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+
+	logger.FromContext(r.Context()).Error("request failed", "path", r.URL.Path, "error", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
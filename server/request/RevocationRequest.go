@@ -0,0 +1,173 @@
+package request
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/audit"
+	"server/service"
+	"server/store"
+	"time"
+)
+
+// Revocation 吊销列表中的一项
+type Revocation struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	Date   string `json:"date"`
+}
+
+// RevocationListMsg 吊销列表响应
+type RevocationListMsg struct {
+	Revocations []Revocation `json:"revocations"`
+	Generated   string       `json:"generated"`
+	Status      string       `json:"status"`
+	Code        int          `json:"code"`
+}
+
+// AlertListMsg 告警列表响应
+type AlertListMsg struct {
+	Alerts []*store.Alert `json:"alerts"`
+	Status string         `json:"status"`
+	Code   int            `json:"code"`
+}
+
+// LicenseStatusMsg 许可证状态变更响应
+type LicenseStatusMsg struct {
+	License *store.LicenseRecord `json:"license"`
+	Status  string               `json:"status"`
+	Code    int                  `json:"code"`
+}
+
+/*
+ * GetRevocationsRequest 获取吊销列表，客户端据此判断许可证是否已被吊销或暂停
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetRevocationsRequest(w http.ResponseWriter, r *http.Request) {
+
+	records, err := service.ListRevocations()
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	revocations := make([]Revocation, 0, len(records))
+	for _, record := range records {
+		revocations = append(revocations, Revocation{
+			Id:     record.ID,
+			Status: record.Status,
+			Reason: record.StatusReason,
+			Date:   record.StatusChanged.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, RevocationListMsg{
+		Revocations: revocations,
+		Generated:   time.Now().UTC().Format("2006-01-02 15:04:05"),
+		Status:      http.StatusText(http.StatusOK),
+		Code:        http.StatusOK,
+	})
+}
+
+/*
+ * GetAlertsRequest 获取复制检测告警，查询参数 all=true 时包含已处理的告警
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetAlertsRequest(w http.ResponseWriter, r *http.Request) {
+
+	alerts, err := service.ListAlerts(r.URL.Query().Get("all") == "true")
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AlertListMsg{
+		Alerts: alerts,
+		Status: http.StatusText(http.StatusOK),
+		Code:   http.StatusOK,
+	})
+}
+
+/*
+ * SuspendLicenseRequest 手动暂停许可证，查询参数 reason 为暂停原因
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func SuspendLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "suspended by operator"
+	}
+
+	id := mux.Vars(r)["id"]
+	record, err := service.SuspendLicense(id, reason)
+	if err == nil && !recordAudit(w, r, audit.ActionLicenseSuspend, id, map[string]string{"reason": reason}) {
+		return
+	}
+	writeLicenseStatus(w, r, record, err)
+}
+
+/*
+ * RevokeLicenseRequest 永久吊销许可证，查询参数 reason 为吊销原因，吊销后不能恢复或续期
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func RevokeLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "revoked by operator"
+	}
+
+	id := mux.Vars(r)["id"]
+	record, err := service.RevokeLicense(id, reason)
+	if err == nil && !recordAudit(w, r, audit.ActionLicenseRevoke, id, map[string]string{"reason": reason}) {
+		return
+	}
+	writeLicenseStatus(w, r, record, err)
+}
+
+/*
+ * ReinstateLicenseRequest 恢复被暂停的许可证
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func ReinstateLicenseRequest(w http.ResponseWriter, r *http.Request) {
+
+	id := mux.Vars(r)["id"]
+	record, err := service.ReinstateLicense(id)
+	if err == nil && !recordAudit(w, r, audit.ActionLicenseReinstate, id, nil) {
+		return
+	}
+	writeLicenseStatus(w, r, record, err)
+}
+
+func writeLicenseStatus(w http.ResponseWriter, r *http.Request, record *store.LicenseRecord, err error) {
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownLicense):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrLicenseRevoked):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			internalError(w, r, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LicenseStatusMsg{
+		License: record,
+		Status:  http.StatusText(http.StatusOK),
+		Code:    http.StatusOK,
+	})
+}
@@ -0,0 +1,40 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"server/service"
+)
+
+// SigningKeyMsg 签名公钥响应
+type SigningKeyMsg struct {
+	service.SigningKeyInfo
+	Status string `json:"status"`
+	Code   int    `json:"code"`
+}
+
+/*
+ * GetSigningKeyRequest 获取签名许可文件使用的 Ed25519 公钥，客户端配置 verification.public_keys 使用其中的 publicKey
+ * 公钥不是机密信息，该接口不需要认证；客户端应在构建或部署时固定公钥，而不是在校验时从服务器获取
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetSigningKeyRequest(w http.ResponseWriter, r *http.Request) {
+
+	info, err := service.CurrentSigningKey()
+	if errors.Is(err, service.ErrNoSigningKey) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SigningKeyMsg{
+		SigningKeyInfo: info,
+		Status:         http.StatusText(http.StatusOK),
+		Code:           http.StatusOK,
+	})
+}
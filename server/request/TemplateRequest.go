@@ -0,0 +1,231 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/audit"
+	"server/auth"
+	"server/service"
+	"server/store"
+	"strconv"
+)
+
+// TemplateMsg 单个模板版本的响应
+type TemplateMsg struct {
+	Template *store.Template `json:"template"`
+	Status   string          `json:"status"`
+	Code     int             `json:"code"`
+}
+
+// TemplateListMsg 模板列表响应，列出每个模板的最新版本或单个模板的全部版本
+type TemplateListMsg struct {
+	Templates []*store.Template `json:"templates"`
+	Status    string            `json:"status"`
+	Code      int               `json:"code"`
+}
+
+// templateError 将模板错误转换为对应的 HTTP 状态码
+func templateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTemplate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUnknownTemplate):
+		http.Error(w, "Template not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrDuplicateTemplate), errors.Is(err, service.ErrTemplateRetired):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		internalError(w, r, err)
+	}
+}
+
+// templateDetails 模板的审计附加信息
+func templateDetails(template *store.Template) map[string]string {
+	return map[string]string{
+		"version":      strconv.Itoa(template.Version),
+		"type":         template.Type,
+		"durationDays": strconv.FormatUint(uint64(template.DurationDays), 10),
+		"usersNum":     strconv.FormatUint(uint64(template.AllowedUsers), 10),
+		"project":      template.Project,
+		"module":       template.Module,
+		"features":     service.JoinFeatures(template.Features),
+	}
+}
+
+/*
+ * GetTemplatesRequest 获取全部模板的最新版本
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetTemplatesRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TemplateListMsg{
+		Templates: s.ListTemplates(),
+		Status:    http.StatusText(http.StatusOK),
+		Code:      http.StatusOK,
+	})
+}
+
+/*
+ * GetTemplateRequest 获取模板，查询参数 version 指定版本号，省略时返回最新版本
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetTemplateRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			http.Error(w, "Invalid template version: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	template, err := s.GetTemplate(mux.Vars(r)["name"], version)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TemplateMsg{
+		Template: template,
+		Status:   http.StatusText(http.StatusOK),
+		Code:     http.StatusOK,
+	})
+}
+
+/*
+ * GetTemplateVersionsRequest 获取模板的全部历史版本
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func GetTemplateVersionsRequest(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Default()
+	if s == nil {
+		internalError(w, r, service.ErrStoreUnavailable)
+		return
+	}
+
+	versions, err := s.TemplateVersions(mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TemplateListMsg{
+		Templates: versions,
+		Status:    http.StatusText(http.StatusOK),
+		Code:      http.StatusOK,
+	})
+}
+
+/*
+ * CreateTemplateRequest 创建新模板，版本号从 1 开始
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func CreateTemplateRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body store.Template
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid template body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := service.SaveTemplate(&body, auth.Actor(auth.FromContext(r.Context())), true)
+	if err != nil {
+		templateError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionTemplateCreate, template.Name, templateDetails(template)) {
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, TemplateMsg{
+		Template: template,
+		Status:   http.StatusText(http.StatusCreated),
+		Code:     http.StatusCreated,
+	})
+}
+
+/*
+ * UpdateTemplateRequest 为模板追加新版本，已签发的许可证仍引用签发时的版本；停用的模板可以借此重新启用
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func UpdateTemplateRequest(w http.ResponseWriter, r *http.Request) {
+
+	var body store.Template
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid template body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	body.Name = mux.Vars(r)["name"]
+
+	template, err := service.SaveTemplate(&body, auth.Actor(auth.FromContext(r.Context())), false)
+	if err != nil {
+		templateError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionTemplateUpdate, template.Name, templateDetails(template)) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TemplateMsg{
+		Template: template,
+		Status:   http.StatusText(http.StatusOK),
+		Code:     http.StatusOK,
+	})
+}
+
+/*
+ * RetireTemplateRequest 停用模板，历史版本保留
+ * @params:  w http.ResponseWriter - HTTP响应写入器
+ * 			 r *http.Request - HTTP请求指针
+ * @returns: null
+ */
+func RetireTemplateRequest(w http.ResponseWriter, r *http.Request) {
+
+	name := mux.Vars(r)["name"]
+	template, err := service.RetireTemplate(name, auth.Actor(auth.FromContext(r.Context())))
+	if err != nil {
+		templateError(w, r, err)
+		return
+	}
+
+	if !recordAudit(w, r, audit.ActionTemplateRetire, name, map[string]string{"version": strconv.Itoa(template.Version)}) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
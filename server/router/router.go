@@ -3,15 +3,104 @@ package router
 import (
 	"github.com/gorilla/mux"
 	"net/http"
+	"server/auth"
+	"server/logger"
+	"server/metrics"
 	"server/request"
+	"server/service"
 )
 
+// route 路由定义，Permission 为空表示无需认证（供客户端调用）
+type route struct {
+	Path       string
+	Method     string
+	Permission service.Permission
+	Handler    http.HandlerFunc
+}
+
+// routes 全部路由及其所需权限
+var routes = []route{
+	// 生成许可证
+	{"/generate_license", "GET", service.PermLicenseIssue, request.GetLicenseRequest},
+
+	// 许可证查询、签发（可引用模板）、批量签发、续期及备注
+	{"/licenses", "GET", service.PermLicenseRead, request.GetLicensesRequest},
+	{"/licenses/{id}", "GET", service.PermLicenseRead, request.GetLicenseRecordRequest},
+	{"/licenses", "POST", service.PermLicenseIssue, request.CreateLicenseRequest},
+	{"/licenses/bulk", "POST", service.PermLicenseIssue, request.BulkIssueRequest},
+	{"/licenses/{id}/renew", "POST", service.PermLicenseIssue, request.RenewLicenseRequest},
+	{"/licenses/{id}/notes", "PUT", service.PermLicenseIssue, request.SetLicenseNotesRequest},
+
+	// 解码并检查许可文件，只允许管理员调用
+	{"/licenses/inspect", "POST", service.PermLicenseInspect, request.InspectLicenseRequest},
+
+	// 下载许可文件，供客户端在线刷新
+	{"/licenses/{id}/file", "GET", "", request.DownloadLicenseRequest},
+
+	// 签名许可文件使用的公钥
+	{"/signing-key", "GET", "", request.GetSigningKeyRequest},
+
+	// 客户端签到上报及签到汇总查询
+	{"/checkins", "POST", "", request.PostCheckinRequest},
+	{"/checkins", "GET", service.PermCheckinRead, request.GetCheckinsRequest},
+
+	// 复制检测告警、吊销列表及许可证暂停/恢复/吊销
+	{"/alerts", "GET", service.PermAlertRead, request.GetAlertsRequest},
+	{"/revocations", "GET", "", request.GetRevocationsRequest},
+	{"/licenses/{id}/suspend", "POST", service.PermLicenseSuspend, request.SuspendLicenseRequest},
+	{"/licenses/{id}/reinstate", "POST", service.PermLicenseSuspend, request.ReinstateLicenseRequest},
+	{"/licenses/{id}/revoke", "POST", service.PermLicenseRevoke, request.RevokeLicenseRequest},
+
+	// API 密钥管理
+	{"/apikeys", "POST", service.PermKeyManage, request.CreateAPIKeyRequest},
+	{"/apikeys", "GET", service.PermKeyManage, request.GetAPIKeysRequest},
+	{"/apikeys/{id}", "DELETE", service.PermKeyManage, request.DisableAPIKeyRequest},
+
+	// 产品目录，签发许可证时按目录校验并填充版本默认值
+	{"/products", "GET", service.PermLicenseRead, request.GetProductsRequest},
+	{"/products", "POST", service.PermCatalogManage, request.CreateProductRequest},
+	{"/products/{id}", "GET", service.PermLicenseRead, request.GetProductRequest},
+	{"/products/{id}", "PUT", service.PermCatalogManage, request.UpdateProductRequest},
+	{"/products/{id}", "DELETE", service.PermCatalogManage, request.DeleteProductRequest},
+
+	// 签发模板，修改时追加新版本
+	{"/templates", "GET", service.PermLicenseRead, request.GetTemplatesRequest},
+	{"/templates", "POST", service.PermTemplateManage, request.CreateTemplateRequest},
+	{"/templates/{name}", "GET", service.PermLicenseRead, request.GetTemplateRequest},
+	{"/templates/{name}", "PUT", service.PermTemplateManage, request.UpdateTemplateRequest},
+	{"/templates/{name}", "DELETE", service.PermTemplateManage, request.RetireTemplateRequest},
+	{"/templates/{name}/versions", "GET", service.PermLicenseRead, request.GetTemplateVersionsRequest},
+
+	// 客户及其许可证
+	{"/customers", "GET", service.PermLicenseRead, request.GetCustomersRequest},
+	{"/customers", "POST", service.PermCustomerManage, request.CreateCustomerRequest},
+	{"/customers/{id}", "GET", service.PermLicenseRead, request.GetCustomerRequest},
+	{"/customers/{id}", "PUT", service.PermCustomerManage, request.UpdateCustomerRequest},
+	{"/customers/{id}", "DELETE", service.PermCustomerManage, request.DeleteCustomerRequest},
+	{"/customers/{id}/licenses", "GET", service.PermLicenseRead, request.GetCustomerLicensesRequest},
+
+	// 审计日志查询
+	{"/audit", "GET", service.PermAuditRead, request.GetAuditRequest},
+
+	// Prometheus 指标，采集端使用 bearer token 认证
+	{"/metrics", "GET", service.PermMetricsRead, metrics.Handler},
+}
+
 func SetupRouter() http.Handler {
 
-	// 创建新的路由器实例
+	// 创建新的路由器实例，路由器内的中间件只对匹配到的路由生效，用于记录路由模板
 	r := mux.NewRouter()
+	r.Use(metrics.RouteMiddleware)
+
+	// 注册全部路由，需要权限的路由经过认证中间件
+	for _, rt := range routes {
+		handler := rt.Handler
+		if rt.Permission != "" {
+			handler = auth.Require(rt.Permission, handler)
+		}
+		r.HandleFunc(rt.Path, handler).Methods(rt.Method)
+	}
 
-	// 为 "/generate_license" 路径注册生成许可证的处理函数
-	r.HandleFunc("/generate_license", request.GetLicenseRequest).Methods("GET")
-	return r
+	// 日志和指标中间件包装整个路由器，所有请求（包括 404、405）都会分配请求ID、记录访问日志和耗时
+	return logger.Middleware(metrics.Middleware(r))
 }
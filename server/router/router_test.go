@@ -0,0 +1,61 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"server/logger"
+	"server/metrics"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEveryRequestIsLoggedAndMeasured(t *testing.T) {
+	var logs bytes.Buffer
+	previous := logger.Default()
+	logger.SetDefault(logger.NewWithWriter(&logs, logger.LevelInfo, false))
+	defer logger.SetDefault(previous)
+
+	handler := SetupRouter()
+	tests := []struct {
+		method string
+		path   string
+		status int
+		route  string
+		label  string // 方法标签，为空时与 method 相同
+	}{
+		{http.MethodGet, "/no-such-route", http.StatusNotFound, "unmatched", ""},
+		{http.MethodDelete, "/signing-key", http.StatusMethodNotAllowed, "unmatched", ""},
+		{http.MethodGet, "/licenses/1234567890123456/file", http.StatusUnauthorized, "/licenses/{id}/file", ""},
+		{"FOO", "/no-such-route", http.StatusNotFound, "unmatched", "other"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+		if rec.Header().Get(logger.RequestIDHeader) == "" {
+			t.Errorf("%s %s has no %s response header", tt.method, tt.path, logger.RequestIDHeader)
+		}
+	}
+
+	var text bytes.Buffer
+	metrics.DefaultRegistry.WriteText(&text)
+	for _, tt := range tests {
+		code := strconv.Itoa(tt.status)
+		line := "method=" + tt.method + " path=" + tt.path + " status=" + code
+		if !strings.Contains(logs.String(), line) {
+			t.Errorf("access log is missing %q:\n%s", line, logs.String())
+		}
+		label := tt.label
+		if label == "" {
+			label = tt.method
+		}
+		series := `license_server_http_request_duration_seconds_count{route="` + tt.route + `",method="` + label + `",code="` + code + `"} 1`
+		if !strings.Contains(text.String(), series) {
+			t.Errorf("metrics are missing %s", series)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"server/logger"
+	"server/store"
+	"server/utils"
+	"time"
+)
+
+// ErrAlreadyBootstrapped 已存在 API 密钥，无法再次初始化
+var ErrAlreadyBootstrapped = errors.New("api keys already exist, bootstrap refused")
+
+/*
+ * CreateAPIKey 创建新的 API 密钥，密钥明文只在创建时返回一次，存储中仅保存哈希值
+ *
+ * @params: name string - 密钥名称，便于识别用途
+ * 			role string - 角色，决定密钥拥有的权限
+ * @returns:string - 密钥明文
+ * 			*store.APIKey - 密钥记录
+ * 			error - 角色无效或写入失败时返回错误
+ */
+func CreateAPIKey(name string, role string) (string, *store.APIKey, error) {
+
+	s := store.Default()
+	if s == nil {
+		return "", nil, ErrStoreUnavailable
+	}
+
+	if !ValidRole(role) {
+		return "", nil, fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := "lt_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &store.APIKey{
+		ID:      utils.GenerateUniqueID(),
+		Name:    name,
+		Hash:    HashAPIKey(token),
+		Role:    role,
+		Created: time.Now().UTC(),
+	}
+	if err := s.SaveAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	logger.Info("api key created", "id", key.ID, "name", key.Name, "role", key.Role)
+	return token, key, nil
+}
+
+/*
+ * BootstrapAdminKey 创建第一个管理员密钥，存储中已有密钥时拒绝执行
+ *
+ * @params: name string - 密钥名称
+ * @returns:string - 密钥明文
+ * 			*store.APIKey - 密钥记录
+ * 			error - 已初始化或写入失败时返回错误
+ */
+func BootstrapAdminKey(name string) (string, *store.APIKey, error) {
+
+	s := store.Default()
+	if s == nil {
+		return "", nil, ErrStoreUnavailable
+	}
+	if len(s.ListAPIKeys()) > 0 {
+		return "", nil, ErrAlreadyBootstrapped
+	}
+	return CreateAPIKey(name, RoleAdmin)
+}
+
+/*
+ * AuthenticateAPIKey 校验密钥明文，返回对应的密钥记录
+ *
+ * @params: token string - 密钥明文
+ * @returns:*store.APIKey - 密钥记录
+ * 			error - 密钥不存在或已停用时返回错误
+ */
+func AuthenticateAPIKey(token string) (*store.APIKey, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+
+	key, err := s.FindAPIKey(HashAPIKey(token))
+	if err != nil {
+		return nil, err
+	}
+	if key.Disabled {
+		return nil, errors.New("api key is disabled")
+	}
+	return key, nil
+}
+
+// HashAPIKey 计算密钥明文的 SHA-256 哈希值
+func HashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"server/logger"
+	"server/store"
+	"server/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBulkRows 一次批量签发最多包含的行数
+const MaxBulkRows = 1000
+
+// ErrInvalidBulk 批量签发的输入无法解析：格式错误、未知的列、没有数据行或行数过多
+var ErrInvalidBulk = errors.New("invalid bulk issuance input")
+
+// ErrBulkRejected 批量签发中有参数无效的行，没有签发任何许可证
+var ErrBulkRejected = errors.New("bulk issuance rejected")
+
+// BulkRow 批量签发的一行，Row 为 CSV 文件中的行号或 JSON 数组中的序号（从 1 开始），用于在报告中定位
+type BulkRow struct {
+	Row  int
+	Body IssueBody
+	// Err 该行的值无法解析时的错误，例如 usersNum 不是数字
+	Err error
+}
+
+// BulkResult 批量签发报告中的一行，签发成功时 ID 不为空，失败时 Error 不为空
+type BulkResult struct {
+	Row           int    `json:"row"`
+	SignatureCode string `json:"signatureCode"`
+	ID            string `json:"id,omitempty"`
+	Expiration    string `json:"expiration,omitempty"`
+	Licensee      string `json:"licensee,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BulkIssue 批量签发的结果
+type BulkIssue struct {
+	// Batch 批次ID，记录在审计日志中
+	Batch    string
+	Date     time.Time
+	Licenses []*License
+	// Report 签发成功时包含每一行的结果；被拒绝时只包含无效的行
+	Report []BulkResult
+	// files 许可证ID到混淆后许可文件内容的映射
+	files map[string]string
+}
+
+// bulkColumns CSV 文件可用的列，列名与 POST /licenses 请求体的字段一致（不区分大小写），machineCode 是 signatureCode 的别名
+var bulkColumns = map[string]func(body *IssueBody, value string) error{
+	"signaturecode": func(body *IssueBody, value string) error { body.SignatureCode = value; return nil },
+	"machinecode":   func(body *IssueBody, value string) error { body.SignatureCode = value; return nil },
+	"template":      func(body *IssueBody, value string) error { body.Template = value; return nil },
+	"templateversion": func(body *IssueBody, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid templateVersion %q", value)
+		}
+		body.TemplateVersion = n
+		return nil
+	},
+	"type":       func(body *IssueBody, value string) error { body.Type = value; return nil },
+	"expiration": func(body *IssueBody, value string) error { body.Expiration = value; return nil },
+	"durationdays": func(body *IssueBody, value string) error {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid durationDays %q", value)
+		}
+		body.DurationDays = uint(n)
+		return nil
+	},
+	"usersnum": func(body *IssueBody, value string) error {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid usersNum %q", value)
+		}
+		body.AllowedUsers = uint(n)
+		return nil
+	},
+	"project":  func(body *IssueBody, value string) error { body.Project = value; return nil },
+	"module":   func(body *IssueBody, value string) error { body.Module = value; return nil },
+	"features": func(body *IssueBody, value string) error { body.Features = SplitFeatures(value); return nil },
+	"version":  func(body *IssueBody, value string) error { body.Version = value; return nil },
+	"customer": func(body *IssueBody, value string) error { body.Customer = value; return nil },
+	"licensee": func(body *IssueBody, value string) error { body.Licensee = value; return nil },
+	"notes":    func(body *IssueBody, value string) error { body.Notes = value; return nil },
+}
+
+/*
+ * ParseBulk 解析批量签发的输入
+ * CSV 的第一行为列名，每一行签发一个许可证，空单元格表示取模板或产品版本的默认值；
+ * JSON 为 POST /licenses 请求体组成的数组
+ *
+ * @params: format string - 输入格式，csv 或 json
+ * 			r io.Reader - 输入内容
+ * @returns:[]BulkRow - 解析出的行，单行的值无效时记录在该行的 Err 中
+ * 			error - 输入整体无法解析时返回包装了 ErrInvalidBulk 的错误
+ */
+func ParseBulk(format string, r io.Reader) ([]BulkRow, error) {
+
+	var rows []BulkRow
+	var err error
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = parseBulkCSV(r)
+	case "json":
+		rows, err = parseBulkJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q (csv, json)", ErrInvalidBulk, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(rows) == 0:
+		return nil, fmt.Errorf("%w: no rows", ErrInvalidBulk)
+	case len(rows) > MaxBulkRows:
+		return nil, fmt.Errorf("%w: %d rows, at most %d are allowed", ErrInvalidBulk, len(rows), MaxBulkRows)
+	}
+	return rows, nil
+}
+
+func parseBulkCSV(r io.Reader) ([]BulkRow, error) {
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidBulk)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBulk, err)
+	}
+
+	setters := make([]func(*IssueBody, string) error, len(header))
+	hasCode := false
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		setter, ok := bulkColumns[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidBulk, name)
+		}
+		setters[i] = setter
+		hasCode = hasCode || key == "signaturecode" || key == "machinecode"
+	}
+	if !hasCode {
+		return nil, fmt.Errorf("%w: a signatureCode (or machineCode) column is required", ErrInvalidBulk)
+	}
+
+	rows := make([]BulkRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBulk, err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := BulkRow{Row: line}
+		empty := true
+		for i, value := range record {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			empty = false
+			if err := setters[i](&row.Body, value); err != nil && row.Err == nil {
+				row.Err = err
+			}
+		}
+		if empty {
+			continue
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseBulkJSON(r io.Reader) ([]BulkRow, error) {
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var bodies []IssueBody
+	if err := decoder.Decode(&bodies); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBulk, err)
+	}
+
+	rows := make([]BulkRow, len(bodies))
+	for i, body := range bodies {
+		rows[i] = BulkRow{Row: i + 1, Body: body}
+	}
+	return rows, nil
+}
+
+/*
+ * IssueBulk 批量签发许可证
+ * 先校验全部行并生成许可文件内容，任一行无效时不签发任何许可证；全部有效时先写入许可文件，
+ * 再在一次存储写入中保存全部许可证记录，保存失败时删除已写入的许可文件
+ *
+ * @params: rows []BulkRow - ParseBulk 解析出的行
+ * @returns:*BulkIssue - 签发结果；被拒绝时只有 Report 包含无效的行
+ * 			error - 有无效的行时返回 ErrBulkRejected，写入失败时返回错误
+ */
+func IssueBulk(rows []BulkRow) (*BulkIssue, error) {
+
+	result := &BulkIssue{Batch: utils.GenerateUniqueID(), Date: time.Now().UTC(), files: make(map[string]string)}
+	failures := make([]BulkResult, 0)
+	for _, row := range rows {
+		license, err := prepareBulkRow(row, result.Date)
+		if err != nil {
+			if !IsCatalogError(err) && row.Err == nil {
+				return nil, err
+			}
+			failures = append(failures, BulkResult{Row: row.Row, SignatureCode: row.Body.SignatureCode, Error: err.Error()})
+			continue
+		}
+		_, encrypted, err := encodeLicenseFile(license)
+		if err != nil {
+			return nil, err
+		}
+		result.Licenses = append(result.Licenses, license)
+		result.files[license.ID] = encrypted
+	}
+	if len(failures) > 0 {
+		return &BulkIssue{Batch: result.Batch, Date: result.Date, Report: failures}, ErrBulkRejected
+	}
+
+	if err := commitBulk(result); err != nil {
+		return nil, err
+	}
+	for i, license := range result.Licenses {
+		licenseIssued(license)
+		result.Report = append(result.Report, BulkResult{
+			Row:           rows[i].Row,
+			SignatureCode: license.SignatureCode,
+			ID:            license.ID,
+			Expiration:    license.ExpirationDate.Format("2006-01-02"),
+			Licensee:      license.Licensee,
+		})
+	}
+	logger.Info("bulk license issuance", "batch", result.Batch, "licenses", len(result.Licenses))
+	return result, nil
+}
+
+// prepareBulkRow 校验一行并生成尚未保存的许可证
+func prepareBulkRow(row BulkRow, now time.Time) (*License, error) {
+
+	if row.Err != nil {
+		return nil, row.Err
+	}
+	req, err := row.Body.IssueRequest()
+	if err != nil {
+		return nil, err
+	}
+	return prepareLicense(req, now)
+}
+
+// commitBulk 写入全部许可文件并保存全部许可证记录，任一步失败时删除已写入的许可文件
+func commitBulk(result *BulkIssue) error {
+
+	written := make([]string, 0, len(result.Licenses))
+	rollback := func() {
+		for _, id := range written {
+			_ = os.Remove(LicenseFilePath(id))
+		}
+	}
+
+	records := make([]*store.LicenseRecord, 0, len(result.Licenses))
+	for _, license := range result.Licenses {
+		if err := writeLicenseFile(license.ID, result.files[license.ID]); err != nil {
+			rollback()
+			return err
+		}
+		written = append(written, license.ID)
+		records = append(records, licenseRecord(license))
+	}
+
+	if s := store.Default(); s != nil {
+		if err := s.SaveLicenses(records); err != nil {
+			logger.Error("failed to save bulk licenses", "batch", result.Batch, "error", err)
+			rollback()
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+ * WriteArchive 把签发的许可文件（<id>.license）和签发报告（report.csv）写成 zip 压缩包
+ *
+ * @params: w io.Writer - 输出
+ * @returns:error - 写入失败时返回错误
+ */
+func (b *BulkIssue) WriteArchive(w io.Writer) error {
+
+	zw := zip.NewWriter(w)
+	create := func(name string) (io.Writer, error) {
+		return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.Date})
+	}
+
+	for _, license := range b.Licenses {
+		f, err := create(license.ID + ".license")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, b.files[license.ID]); err != nil {
+			return err
+		}
+	}
+
+	f, err := create("report.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	_ = cw.Write([]string{"row", "signatureCode", "id", "expiration", "licensee", "file", "error"})
+	for _, r := range b.Report {
+		file := ""
+		if r.ID != "" {
+			file = r.ID + ".license"
+		}
+		_ = cw.Write([]string{strconv.Itoa(r.Row), r.SignatureCode, r.ID, r.Expiration, r.Licensee, file, r.Error})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
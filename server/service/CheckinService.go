@@ -0,0 +1,106 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"server/logger"
+	"server/store"
+	"time"
+)
+
+// ErrStoreUnavailable 未配置存储
+var ErrStoreUnavailable = errors.New("license store is not configured")
+
+// ErrUnknownLicense 签到的许可证不存在
+var ErrUnknownLicense = errors.New("unknown license id")
+
+// ErrInvalidCheckinToken 签到或下载许可文件时携带的许可证令牌与许可证不匹配
+var ErrInvalidCheckinToken = errors.New("license token does not match the license")
+
+/*
+ * AuthenticateLicense 校验请求方持有许可文件：许可证ID是公开的，许可证令牌只写在许可文件中
+ *
+ * @params: licenseID string - 许可证ID
+ * 			token string - 请求方提供的许可证令牌（许可文件的 license 字段）
+ * @returns:*store.LicenseRecord - 许可证记录
+ * 			error - 未配置存储时返回 ErrStoreUnavailable，许可证不存在时返回 ErrUnknownLicense，令牌不匹配时返回 ErrInvalidCheckinToken
+ */
+func AuthenticateLicense(licenseID string, token string) (*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+
+	record, err := s.GetLicense(licenseID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrUnknownLicense
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(record.LicenseID)) != 1 {
+		return nil, ErrInvalidCheckinToken
+	}
+	return record, nil
+}
+
+/*
+ * RecordCheckin 认证并记录客户端签到
+ * 许可证ID会出现在日志和吊销列表中，不能证明签到来自持有许可文件的客户端，因此要求同时提供许可文件中的许可证令牌，
+ * 令牌不匹配的签到不会被记录，也就不会触发复制检测告警或自动暂停
+ *
+ * @params: token string - 许可文件中的许可证令牌（license 字段）
+ * 			checkin store.Checkin - 客户端上报的签到信息，Time 为空时使用当前时间
+ * @returns:*store.LicenseUsage - 该许可证更新后的使用汇总
+ * 			error - 许可证不存在时返回 ErrUnknownLicense，令牌不匹配时返回 ErrInvalidCheckinToken，写入失败时返回错误
+ */
+func RecordCheckin(token string, checkin store.Checkin) (*store.LicenseUsage, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+
+	if _, err := AuthenticateLicense(checkin.LicenseID, token); err != nil {
+		return nil, err
+	}
+
+	if checkin.Time.IsZero() {
+		checkin.Time = time.Now().UTC()
+	}
+
+	usage, err := s.RecordCheckin(checkin)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("check-in recorded", "license", checkin.LicenseID, "fingerprint", checkin.Fingerprint,
+		"appVersion", checkin.AppVersion, "activeUsers", checkin.ActiveUsers)
+
+	// 根据签到历史分析是否存在复制使用
+	if _, err := DetectClones(checkin.LicenseID, checkin.Time); err != nil {
+		return nil, err
+	}
+	markSuspectedClone(s, usage, checkin.Time)
+	return usage, nil
+}
+
+/*
+ * ListCheckins 获取所有许可证的签到汇总，SuspectedClone 表示当前检测窗口内（且在最近一次恢复之后）是否存在复制使用
+ *
+ * @returns:[]*store.LicenseUsage - 签到汇总列表
+ * 			error - 未配置存储时返回错误
+ */
+func ListCheckins() ([]*store.LicenseUsage, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	usages := s.ListCheckins()
+	now := time.Now().UTC()
+	for _, usage := range usages {
+		markSuspectedClone(s, usage, now)
+	}
+	return usages, nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"server/audit"
+	"server/logger"
+	"server/metrics"
+	"server/store"
+	"server/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AlertConcurrentFingerprints 同一许可证在检测窗口内被多个机器指纹同时使用
+	AlertConcurrentFingerprints = "concurrent-fingerprints"
+	// AlertConcurrentIPRanges 同一许可证在检测窗口内从过多的网段签到
+	AlertConcurrentIPRanges = "concurrent-ip-ranges"
+)
+
+// ErrLicenseRevoked 许可证已被吊销，不能再暂停或恢复
+var ErrLicenseRevoked = errors.New("license is revoked")
+
+// CloneDetectionPolicy 复制检测策略
+type CloneDetectionPolicy struct {
+	Window      time.Duration // 检测窗口，窗口内的签到视为同时使用
+	MaxIPRanges int           // 检测窗口内允许的最大网段数（IPv4 按 /24，IPv6 按 /48 归并）
+	AutoSuspend bool          // 检测到复制使用时是否自动暂停许可证
+}
+
+var (
+	policyMutex sync.RWMutex
+	policy      = CloneDetectionPolicy{
+		Window:      2 * time.Hour,
+		MaxIPRanges: 2,
+		AutoSuspend: false,
+	}
+)
+
+// SetCloneDetectionPolicy 设置复制检测策略
+func SetCloneDetectionPolicy(p CloneDetectionPolicy) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+
+	policy = p
+}
+
+// GetCloneDetectionPolicy 获取当前复制检测策略
+func GetCloneDetectionPolicy() CloneDetectionPolicy {
+	policyMutex.RLock()
+	defer policyMutex.RUnlock()
+
+	return policy
+}
+
+/*
+ * DetectClones 分析许可证在检测窗口内的签到历史，发现复制使用时产生告警，并按策略自动暂停许可证
+ * 许可证被恢复后只统计恢复之后的签到，恢复前导致暂停的签到不会再次触发暂停
+ *
+ * @params: licenseID string - 许可证ID
+ * 			now time.Time - 检测时间点，窗口为 [now-Window, now]，起点不早于许可证最近一次恢复的时间
+ * @returns:[]*store.Alert - 本次检测产生或更新的告警
+ * 			error - 读写存储失败时返回错误
+ */
+func DetectClones(licenseID string, now time.Time) ([]*store.Alert, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+
+	record, err := s.GetLicense(licenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := GetCloneDetectionPolicy()
+	reasons, fingerprints, ipRanges := analyzeCheckins(s, record, p, now)
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+
+	alerts := make([]*store.Alert, 0, len(reasons))
+	for _, reason := range reasons {
+		alert, created, err := s.RaiseAlert(&store.Alert{
+			ID:           utils.GenerateUniqueID(),
+			LicenseID:    licenseID,
+			Reason:       reason,
+			Fingerprints: sortedKeys(fingerprints),
+			IPRanges:     sortedKeys(ipRanges),
+			FirstSeen:    now,
+			LastSeen:     now,
+			Suspended:    p.AutoSuspend,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			logger.Warn("suspected license clone", "license", licenseID, "reason", reason,
+				"fingerprints", len(fingerprints), "ipRanges", len(ipRanges))
+			err := audit.Record(audit.ActorSystem, audit.ActionCloneAlert, licenseID, map[string]string{
+				"alert":        alert.ID,
+				"reason":       reason,
+				"fingerprints": strings.Join(alert.Fingerprints, ","),
+				"ipRanges":     strings.Join(alert.IPRanges, ","),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if p.AutoSuspend {
+		if record.Active() {
+			reason := "suspected clone: " + strings.Join(reasons, ", ")
+			if _, err := suspendLicense(licenseID, reason, "clone-detection"); err != nil {
+				return nil, err
+			}
+			logger.Warn("license suspended automatically", "license", licenseID, "reason", reason)
+			err := audit.Record(audit.ActorSystem, audit.ActionLicenseAutoSuspend, licenseID, map[string]string{
+				"reason": reason,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return alerts, nil
+}
+
+/*
+ * analyzeCheckins 统计许可证在检测窗口内的机器指纹和网段，判断是否存在复制使用
+ * 窗口为 [now-Window, now]，起点不早于许可证最近一次恢复的时间
+ *
+ * @params: s *store.Store - 存储
+ * 			record *store.LicenseRecord - 许可证记录
+ * 			p CloneDetectionPolicy - 复制检测策略
+ * 			now time.Time - 检测时间点
+ * @returns:[]string - 告警原因，Alert* 之一，没有发现复制使用时为空
+ * 			map[string]bool - 窗口内的机器指纹
+ * 			map[string]bool - 窗口内的网段
+ */
+func analyzeCheckins(s *store.Store, record *store.LicenseRecord, p CloneDetectionPolicy, now time.Time) ([]string, map[string]bool, map[string]bool) {
+
+	since := now.Add(-p.Window)
+	if record.Reinstated.After(since) {
+		since = record.Reinstated
+	}
+
+	fingerprints := make(map[string]bool)
+	ipRanges := make(map[string]bool)
+	for _, checkin := range s.CheckinHistory(record.ID, since) {
+		fingerprints[checkin.Fingerprint] = true
+		if r := ipRange(checkin.RemoteAddr); r != "" {
+			ipRanges[r] = true
+		}
+	}
+
+	var reasons []string
+	if len(fingerprints) > 1 {
+		reasons = append(reasons, AlertConcurrentFingerprints)
+	}
+	if p.MaxIPRanges > 0 && len(ipRanges) > p.MaxIPRanges {
+		reasons = append(reasons, AlertConcurrentIPRanges)
+	}
+	return reasons, fingerprints, ipRanges
+}
+
+/*
+ * markSuspectedClone 按检测窗口和最近一次恢复之后的签到填写使用汇总的 SuspectedClone
+ *
+ * @params: s *store.Store - 存储
+ * 			usage *store.LicenseUsage - 使用汇总副本
+ * 			now time.Time - 检测时间点
+ */
+func markSuspectedClone(s *store.Store, usage *store.LicenseUsage, now time.Time) {
+
+	usage.SuspectedClone = false
+	record, err := s.GetLicense(usage.LicenseID)
+	if err != nil {
+		return
+	}
+	reasons, _, _ := analyzeCheckins(s, record, GetCloneDetectionPolicy(), now)
+	usage.SuspectedClone = len(reasons) > 0
+}
+
+/*
+ * SuspendLicense 暂停许可证，暂停后该许可证会出现在吊销列表中，下载许可文件返回 410
+ *
+ * @params: licenseID string - 许可证ID
+ * 			reason string - 暂停原因
+ * @returns:*store.LicenseRecord - 更新后的许可证记录
+ * 			error - 许可证不存在、已被吊销或写入失败时返回错误
+ */
+func SuspendLicense(licenseID string, reason string) (*store.LicenseRecord, error) {
+
+	return suspendLicense(licenseID, reason, "operator")
+}
+
+func suspendLicense(licenseID string, reason string, trigger string) (*store.LicenseRecord, error) {
+
+	record, err := setLicenseStatus(licenseID, store.StatusSuspended, reason)
+	if err != nil {
+		return nil, err
+	}
+	metrics.LicensesRevoked.Inc(record.Type, record.Project, trigger)
+	return record, nil
+}
+
+/*
+ * RevokeLicense 永久吊销许可证，吊销后不能恢复或续期，该许可证会出现在吊销列表中，下载许可文件返回 410
+ *
+ * @params: licenseID string - 许可证ID
+ * 			reason string - 吊销原因
+ * @returns:*store.LicenseRecord - 更新后的许可证记录
+ * 			error - 许可证不存在、已被吊销或写入失败时返回错误
+ */
+func RevokeLicense(licenseID string, reason string) (*store.LicenseRecord, error) {
+
+	record, err := setLicenseStatus(licenseID, store.StatusRevoked, reason)
+	if err != nil {
+		return nil, err
+	}
+	metrics.LicensesRevoked.Inc(record.Type, record.Project, "revoke")
+	return record, nil
+}
+
+/*
+ * ReinstateLicense 恢复被暂停的许可证，并将其未处理的告警标记为已处理
+ * 存储记录恢复时间，之后的复制检测只统计恢复之后的签到；已吊销的许可证不能恢复
+ *
+ * @params: licenseID string - 许可证ID
+ * @returns:*store.LicenseRecord - 更新后的许可证记录
+ * 			error - 许可证不存在、已被吊销或写入失败时返回错误
+ */
+func ReinstateLicense(licenseID string) (*store.LicenseRecord, error) {
+
+	record, err := setLicenseStatus(licenseID, store.StatusActive, "reinstated")
+	if err != nil {
+		return nil, err
+	}
+	metrics.LicensesReinstated.Inc(record.Type, record.Project)
+	if err := store.Default().ResolveAlerts(licenseID); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+/*
+ * ListRevocations 获取吊销列表，即所有非正常状态的许可证
+ *
+ * @returns:[]*store.LicenseRecord - 许可证记录列表
+ * 			error - 未配置存储时返回错误
+ */
+func ListRevocations() ([]*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	return s.ListRevokedLicenses(), nil
+}
+
+/*
+ * ListAlerts 获取复制检测告警
+ *
+ * @params: includeResolved bool - 是否包含已处理的告警
+ * @returns:[]*store.Alert - 告警列表
+ * 			error - 未配置存储时返回错误
+ */
+func ListAlerts(includeResolved bool) ([]*store.Alert, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	return s.ListAlerts(includeResolved), nil
+}
+
+func setLicenseStatus(licenseID string, status string, reason string) (*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+
+	record, err := s.SetLicenseStatus(licenseID, status, reason)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrUnknownLicense
+	case errors.Is(err, store.ErrLicenseRevoked):
+		return nil, ErrLicenseRevoked
+	case err != nil:
+		return nil, err
+	}
+	logger.Info("license status changed", "license", licenseID, "status", status, "reason", reason)
+	return record, nil
+}
+
+// ipRange 将IP地址归并到网段：IPv4 按 /24，IPv6 按 /48
+func ipRange(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%s/24", v4.Mask(net.CIDRMask(24, 32)))
+	}
+	return fmt.Sprintf("%s/48", ip.Mask(net.CIDRMask(48, 128)))
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"server/store"
+	"server/utils"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCustomer 客户信息无效
+var ErrInvalidCustomer = errors.New("invalid customer")
+
+// ErrUnknownCustomer 客户不存在
+var ErrUnknownCustomer = errors.New("unknown customer")
+
+// maxLicenseeLength 被许可方名称的最大长度，许可文件内容超过 4096 字节时会被截断
+const maxLicenseeLength = 200
+
+// normalizeCustomer 去除首尾空白并校验客户信息
+func normalizeCustomer(customer *store.Customer) error {
+
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.ExternalID = strings.TrimSpace(customer.ExternalID)
+	customer.Contact.Name = strings.TrimSpace(customer.Contact.Name)
+	customer.Contact.Email = strings.TrimSpace(customer.Contact.Email)
+	customer.Contact.Phone = strings.TrimSpace(customer.Contact.Phone)
+	switch {
+	case customer.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidCustomer)
+	case len(customer.Name) > maxLicenseeLength:
+		return fmt.Errorf("%w: name must be at most %d bytes", ErrInvalidCustomer, maxLicenseeLength)
+	case customer.Contact.Email != "" && !strings.Contains(customer.Contact.Email, "@"):
+		return fmt.Errorf("%w: invalid contact email %q", ErrInvalidCustomer, customer.Contact.Email)
+	}
+	return nil
+}
+
+/*
+ * CreateCustomer 校验并保存新的客户，客户ID由服务端生成
+ *
+ * @params: customer *store.Customer - 客户信息
+ * @returns:*store.Customer - 保存后的客户
+ * 			error - 信息无效、外部 CRM ID 重复或写入失败时返回错误
+ */
+func CreateCustomer(customer *store.Customer) (*store.Customer, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	if err := normalizeCustomer(customer); err != nil {
+		return nil, err
+	}
+
+	customer.ID = utils.GenerateUniqueID()
+	customer.Created = time.Now().UTC()
+	customer.Updated = customer.Created
+	if err := s.SaveCustomer(customer); err != nil {
+		return nil, err
+	}
+	return s.GetCustomer(customer.ID)
+}
+
+/*
+ * UpdateCustomer 校验并覆盖已有的客户；已签发许可证中的被许可方名称保持签发时的值
+ *
+ * @params: customer *store.Customer - 客户信息
+ * @returns:*store.Customer - 保存后的客户
+ * 			error - 信息无效、客户不存在、外部 CRM ID 重复或写入失败时返回错误
+ */
+func UpdateCustomer(customer *store.Customer) (*store.Customer, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	if _, err := s.GetCustomer(customer.ID); err != nil {
+		return nil, ErrUnknownCustomer
+	}
+	if err := normalizeCustomer(customer); err != nil {
+		return nil, err
+	}
+
+	customer.Updated = time.Now().UTC()
+	if err := s.SaveCustomer(customer); err != nil {
+		return nil, err
+	}
+	return s.GetCustomer(customer.ID)
+}
+
+/*
+ * CustomerLicenses 获取关联到客户的全部许可证记录
+ *
+ * @params: customerID string - 客户ID
+ * @returns:[]*store.LicenseRecord - 许可证记录列表
+ * 			error - 客户不存在时返回 ErrUnknownCustomer
+ */
+func CustomerLicenses(customerID string) ([]*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	if _, err := s.GetCustomer(customerID); err != nil {
+		return nil, ErrUnknownCustomer
+	}
+
+	page, err := s.QueryLicenses(store.IndexQuery{Sort: "date", CustomerID: customerID, Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+	return page.Licenses, nil
+}
+
+/*
+ * applyCustomer 校验签发参数中的客户，并以客户名称作为未指定的被许可方名称
+ *
+ * @params: req IssueRequest - 签发参数
+ * @returns:IssueRequest - 补全后的签发参数
+ * 			error - 客户不存在或被许可方名称过长时返回错误
+ */
+func applyCustomer(req IssueRequest) (IssueRequest, error) {
+
+	req.Licensee = strings.TrimSpace(req.Licensee)
+	if len(req.Licensee) > maxLicenseeLength {
+		return req, fmt.Errorf("%w: licensee must be at most %d bytes", ErrInvalidCustomer, maxLicenseeLength)
+	}
+	if req.CustomerID == "" {
+		return req, nil
+	}
+	s := store.Default()
+	if s == nil {
+		return req, ErrStoreUnavailable
+	}
+
+	customer, err := s.GetCustomer(req.CustomerID)
+	if err != nil {
+		return req, fmt.Errorf("%w %q", ErrUnknownCustomer, req.CustomerID)
+	}
+	if req.Licensee == "" {
+		req.Licensee = customer.Name
+	}
+	return req, nil
+}
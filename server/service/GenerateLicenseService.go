@@ -8,7 +8,11 @@
 package service
 
 import (
-	"math/rand"
+	"os"
+	"server/logger"
+	"server/metrics"
+	"server/store"
+	"server/utils"
 	"time"
 )
 
@@ -40,6 +44,15 @@ type License struct {
 	AllowedUsers   uint
 	Project        string
 	Module         string
+	Version        string
+	// Template 签发时使用的模板名称及版本，未使用模板时为空
+	Template        string
+	TemplateVersion int
+	// CustomerID 关联的客户，Licensee 为写入许可文件的被许可方名称
+	CustomerID string
+	Licensee   string
+	// Notes 备注，只保存在服务端，可全文搜索
+	Notes string
 }
 
 /*
@@ -53,23 +66,36 @@ type License struct {
  *			obj string - 项目名称
  *			module string - 模块名称
  * @returns:License - 指向生成的license对象的指针
+ * 			[]byte - 签名后、混淆前的许可文件内容（JSON）
  * 			error - 任何可能发生的错误
  */
-func GenerateLicense(signatureCode string, licenseType string, expiration time.Time, allowedUsers uint, obj string, module string) (*License, error) {
+func GenerateLicense(signatureCode string, licenseType string, expiration time.Time, allowedUsers uint, obj string, module string) (*License, []byte, error) {
 
-	// 生成随机、唯一的license
-	rand.Seed(time.Now().UnixNano())
+	license, err := newLicense(signatureCode, licenseType, expiration, allowedUsers, obj, module)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := saveLicense(license)
+	if err != nil {
+		return nil, nil, err
+	}
+	return license, content, nil
+}
 
-	const letterBytes = "uBIXDoeA7GkpSKcNfuXbBLTs7nK8bJaNPEbN2zR5DLqs373P4uKWDxNuqfyYLY5XTWng4QB4"
+// licenseTokenAlphabet 许可证令牌（签到凭据）使用的字符
+const licenseTokenAlphabet = "uBIXDoeA7GkpSKcNfuXbBLTs7nK8bJaNPEbN2zR5DLqs373P4uKWDxNuqfyYLY5XTWng4QB4"
 
-	b := make([]byte, 72)
+// newLicense 生成唯一的许可证ID和由 crypto/rand 生成的许可证令牌并填充授权信息，尚未保存；
+// 令牌同时是客户端签到的凭据，必须不可预测
+func newLicense(signatureCode string, licenseType string, expiration time.Time, allowedUsers uint, obj string, module string) (*License, error) {
 
-	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
+	licenseID, err := utils.RandomToken(licenseTokenAlphabet, 72)
+	if err != nil {
+		return nil, err
 	}
-	licenseID := string(b)
 
-	license := &License{
+	return &License{
+		ID:             utils.GenerateUniqueID(),
 		LicenseID:      licenseID,
 		Date:           time.Now().UTC(),
 		SignatureCode:  signatureCode,
@@ -78,15 +104,61 @@ func GenerateLicense(signatureCode string, licenseType string, expiration time.T
 		AllowedUsers:   allowedUsers,
 		Project:        obj,
 		Module:         module,
+	}, nil
+}
+
+/*
+ * saveLicense 写入新签发许可证的许可文件并保存记录，供签到、下载等后续操作使用，并记录签发指标和日志
+ * 与批量签发相同，先写许可文件再保存记录，保存失败时删除已写入的许可文件，不会留下没有许可文件的有效许可证
+ *
+ * @params: license *License - 新签发的许可证
+ * @returns:[]byte - 签名后、混淆前的许可文件内容（JSON）
+ * 			error - 写入许可文件或存储失败时返回错误
+ */
+func saveLicense(license *License) ([]byte, error) {
+
+	content, err := WriteLicenseFile(license)
+	if err != nil {
+		logger.Error("failed to write license file", "id", license.ID, "error", err)
+		return nil, err
+	}
+
+	if s := store.Default(); s != nil {
+		if err := s.SaveLicense(licenseRecord(license)); err != nil {
+			logger.Error("failed to save license", "id", license.ID, "error", err)
+			_ = os.Remove(LicenseFilePath(license.ID))
+			return nil, err
+		}
+	}
+
+	licenseIssued(license)
+	return content, nil
+}
+
+// licenseRecord 新签发许可证的持久化记录
+func licenseRecord(license *License) *store.LicenseRecord {
+	return &store.LicenseRecord{
+		ID:              license.ID,
+		LicenseID:       license.LicenseID,
+		Date:            license.Date,
+		SignatureCode:   license.SignatureCode,
+		Type:            license.Type,
+		ExpirationDate:  license.ExpirationDate,
+		AllowedUsers:    license.AllowedUsers,
+		Project:         license.Project,
+		Module:          license.Module,
+		Version:         license.Version,
+		Template:        license.Template,
+		TemplateVersion: license.TemplateVersion,
+		CustomerID:      license.CustomerID,
+		Licensee:        license.Licensee,
+		Notes:           license.Notes,
+		Status:          store.StatusActive,
 	}
+}
 
-	// 这里可以添加保存到数据库或文件等持久化操作
-	// ...
-	//err := ioutil.WriteFile("license.txt", []byte(license.LicenseID), 0644)
-	//if err != nil {
-	//	// 处理错误
-	//	fmt.Println("Error:", err)
-	//	return nil, nil
-	//}
-	return license, nil
+// licenseIssued 记录许可证签发的指标和日志
+func licenseIssued(license *License) {
+	metrics.LicensesIssued.Inc(license.Type, license.Project)
+	logger.Info("license generated", "id", license.ID, "type", license.Type, "project", license.Project, "module", license.Module)
 }
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"server/store"
+	"server/utils"
+	"strings"
+	"time"
+)
+
+const (
+	// LicenseFormat 服务端生成的许可文件格式：带 Ed25519 签名的 JSON 与机器特征码循环异或后用随机字节填充到 4096 字节，
+	// 再做 base64url 编码
+	LicenseFormat = "ed25519/xor-machine-code/base64url"
+
+	// 特征码来源：用户指定、存储中已签发许可证的特征码，或从许可文件中恢复
+	KeySourceProvided  = "provided"
+	KeySourceStore     = "store"
+	KeySourceRecovered = "recovered"
+
+	// 过期状态
+	ExpiryValid        = "valid"
+	ExpiryExpiresToday = "expires-today"
+	ExpiryExpired      = "expired"
+	ExpiryInvalid      = "invalid"
+)
+
+// LicenseInspection 许可文件的检查结果
+type LicenseInspection struct {
+	Header    InspectionHeader    `json:"header"`
+	Payload   LicenseMsg          `json:"payload"`
+	Signature InspectionSignature `json:"signature"`
+	Expiry    InspectionExpiry    `json:"expiry"`
+	Binding   InspectionBinding   `json:"binding"`
+	Warnings  []string            `json:"warnings,omitempty"`
+}
+
+// InspectionHeader 许可文件的格式信息
+type InspectionHeader struct {
+	Format        string `json:"format"`
+	EncodedLength int    `json:"encodedLength"` // 文件长度
+	DecodedLength int    `json:"decodedLength"` // base64 解码后的长度，包含填充
+	PayloadLength int    `json:"payloadLength"` // 有效内容（JSON）的长度
+	KeySource     string `json:"keySource"`     // 解码使用的特征码来源
+}
+
+// InspectionSignature 许可文件的签名信息
+type InspectionSignature struct {
+	Signed bool   `json:"signed"`
+	KeyID  string `json:"keyId,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// InspectionExpiry 许可证的过期状态
+type InspectionExpiry struct {
+	Expiration string `json:"expiration"`
+	Status     string `json:"status"`
+	DaysLeft   int    `json:"daysLeft"` // 距过期日期的天数，已过期时为负数
+}
+
+// InspectionBinding 许可证绑定的机器
+type InspectionBinding struct {
+	MachineCode string `json:"machineCode"`
+	Method      string `json:"method"`
+	Expected    string `json:"expected,omitempty"` // 调用方指定的机器码
+	Matches     *bool  `json:"matches,omitempty"`  // 许可证是否绑定到指定的机器码
+}
+
+/*
+ * CheckExpiry 计算过期日期相对于当前时间的状态，许可证在过期日期当天仍然有效
+ *
+ * @params: expiration string - 过期日期（YYYY-MM-DD）
+ *			now time.Time - 当前时间
+ * @returns:InspectionExpiry - 过期状态
+ */
+func CheckExpiry(expiration string, now time.Time) InspectionExpiry {
+
+	result := InspectionExpiry{Expiration: expiration, Status: ExpiryInvalid}
+	exp, err := time.Parse("2006-01-02", expiration)
+	if err != nil {
+		return result
+	}
+	y, m, d := now.UTC().Date()
+	result.DaysLeft = int(exp.Sub(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	switch {
+	case result.DaysLeft < 0:
+		result.Status = ExpiryExpired
+	case result.DaysLeft == 0:
+		result.Status = ExpiryExpiresToday
+	default:
+		result.Status = ExpiryValid
+	}
+	return result
+}
+
+/*
+ * InspectLicenseFile 解码许可文件并说明其内容，不需要客户的机器码
+ * 指定机器码时优先使用它解码，并报告许可证是否绑定到该机器；其次依次尝试存储中已签发许可证的特征码，
+ * 都不能解码时才从文件的填充字节中恢复签发时使用的特征码
+ *
+ * @params: content []byte - 许可文件内容
+ *			machineCode string - 客户的机器码，可为空
+ *			now time.Time - 计算过期状态使用的当前时间
+ * @returns:*LicenseInspection - 检查结果
+ *			error - 文件不是服务端生成的许可文件时返回错误
+ */
+func InspectLicenseFile(content []byte, machineCode string, now time.Time) (*LicenseInspection, error) {
+
+	encoded := strings.TrimSpace(string(content))
+	decoded, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &LicenseInspection{
+		Header: InspectionHeader{
+			Format:        LicenseFormat,
+			EncodedLength: len(encoded),
+			DecodedLength: len(decoded),
+		},
+		Binding: InspectionBinding{
+			Method: "first 32 hex digits of sha256(MAC address of the first active non-loopback IPv4 interface)",
+		},
+	}
+
+	key, source := "", KeySourceRecovered
+	if machineCode != "" {
+		matches := false
+		inspection.Binding.Expected = machineCode
+		inspection.Binding.Matches = &matches
+		if _, err := DecodeLicenseFile(content, machineCode); err == nil {
+			key, source = machineCode, KeySourceProvided
+		}
+	}
+	if key == "" {
+		if key = storedSignatureCode(content); key != "" {
+			source = KeySourceStore
+		}
+	}
+	if key == "" {
+		if key, err = utils.RecoverSignatureCode(encoded); err != nil {
+			return nil, err
+		}
+	}
+	inspection.Header.KeySource = source
+
+	plain, err := utils.DeobfuscationUtil(encoded, key)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(plain))
+	if err := dec.Decode(&inspection.Payload); err != nil {
+		return nil, err
+	}
+	inspection.Header.PayloadLength = int(dec.InputOffset())
+
+	// 签名覆盖 authorized 对象的原始字节，重新序列化可能与签名时不同，因此单独读取原始字节
+	var raw struct {
+		Authorized json.RawMessage `json:"authorized"`
+	}
+	if err := json.Unmarshal(plain[:inspection.Header.PayloadLength], &raw); err != nil {
+		return nil, err
+	}
+	inspection.Signature = InspectionSignature{
+		Signed: inspection.Payload.Signature != "",
+		KeyID:  inspection.Payload.KeyID,
+	}
+	inspection.Signature.Status, inspection.Signature.Detail = checkLicenseSignature(raw.Authorized, inspection.Payload.KeyID, inspection.Payload.Signature)
+
+	a := inspection.Payload.Authorized
+	inspection.Binding.MachineCode = a.SignatureCode
+	if inspection.Binding.Matches != nil {
+		*inspection.Binding.Matches = a.SignatureCode == machineCode
+	}
+	inspection.Expiry = CheckExpiry(a.Expiration, now)
+
+	if a.SignatureCode != key {
+		inspection.Warnings = append(inspection.Warnings, "the signature code inside the license differs from the key it was obfuscated with")
+	}
+	if inspection.Signature.Status != SignatureValid && inspection.Signature.Status != SignatureUnsigned {
+		inspection.Warnings = append(inspection.Warnings, "the license signature could not be verified: "+inspection.Signature.Detail)
+	}
+	if inspection.Payload.Code != http.StatusOK {
+		inspection.Warnings = append(inspection.Warnings, "the license carries a non-OK status code")
+	}
+	if bytes.Count(decoded[inspection.Header.PayloadLength:], []byte{0}) == len(decoded)-inspection.Header.PayloadLength {
+		inspection.Warnings = append(inspection.Warnings, "padding is all zero bytes, so the machine code can be recovered from the file itself")
+	}
+	return inspection, nil
+}
+
+// storedSignatureCode 在存储中已签发许可证的特征码里查找能解码该许可文件的一个，未配置存储或都不能解码时返回空
+func storedSignatureCode(content []byte) string {
+
+	s := store.Default()
+	if s == nil {
+		return ""
+	}
+	tried := make(map[string]bool)
+	for _, record := range s.ListLicenses() {
+		if tried[record.SignatureCode] {
+			continue
+		}
+		tried[record.SignatureCode] = true
+		if _, err := DecodeLicenseFile(content, record.SignatureCode); err == nil {
+			return record.SignatureCode
+		}
+	}
+	return ""
+}
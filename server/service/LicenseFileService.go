@@ -0,0 +1,220 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"server/utils"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	licenseDirMutex sync.RWMutex
+	licenseDir      = "."
+)
+
+// SetLicenseDir 设置许可文件的存放目录
+func SetLicenseDir(dir string) {
+	licenseDirMutex.Lock()
+	defer licenseDirMutex.Unlock()
+
+	licenseDir = dir
+}
+
+/*
+ * LicenseFilePath 获取许可文件的存放路径
+ *
+ * @params: id string - 许可证ID
+ * @returns:string - 许可文件路径，文件名为 <id>.license
+ */
+func LicenseFilePath(id string) string {
+
+	licenseDirMutex.RLock()
+	defer licenseDirMutex.RUnlock()
+
+	return filepath.Join(licenseDir, id+".license")
+}
+
+// Authorized 许可文件中的授权详细信息
+type Authorized struct {
+	Id            string `json:"id"`
+	License       string `json:"license"`
+	Date          string `json:"date"`
+	SignatureCode string `json:"signatureCode"`
+	Type          string `json:"type"`
+	Expiration    string `json:"expiration"`
+	AllowedUsers  string `json:"usersNum"`
+	Project       string `json:"project"`
+	Module        string `json:"module"`
+	// Licensee 被许可方名称，供产品在“关于”对话框中显示；字段位于末尾，未关联客户时省略，旧版客户端忽略该字段
+	Licensee string `json:"licensee,omitempty"`
+}
+
+// LicenseMsg 许可文件内容：授权信息、状态和代码，以及对授权信息的 Ed25519 签名
+type LicenseMsg struct {
+	Authorized Authorized `json:"authorized"`
+	Status     string     `json:"status"`
+	Code       int        `json:"code"`
+	// KeyID 签名公钥的ID，Signature 为 base64 编码的签名，覆盖 authorized 对象的原始字节；未配置签名私钥时为空
+	KeyID     string `json:"keyId,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+/*
+ * NewLicenseMsg 根据许可证构建许可文件内容
+ *
+ * @params: license *License - 许可证
+ * @returns:LicenseMsg - 许可文件内容
+ */
+func NewLicenseMsg(license *License) LicenseMsg {
+
+	return LicenseMsg{
+		Authorized: Authorized{
+			Id:            license.ID,
+			SignatureCode: license.SignatureCode,
+			License:       license.LicenseID,
+			Date:          license.Date.Format("2006-01-02 15:04:05"),
+			Type:          license.Type,
+			Expiration:    license.ExpirationDate.Format("2006-01-02"),
+			AllowedUsers:  strconv.FormatUint(uint64(license.AllowedUsers), 10),
+			Project:       license.Project,
+			Module:        license.Module,
+			Licensee:      license.Licensee,
+		},
+		Status: http.StatusText(http.StatusOK),
+		Code:   http.StatusOK,
+	}
+}
+
+/*
+ * WriteLicenseFile 生成并签名许可文件内容，使用机器特征码混淆后写入许可文件目录，已存在的文件被原子替换
+ *
+ * @params: license *License - 许可证
+ * @returns:[]byte - 混淆前的许可文件内容（JSON）
+ *			error - 序列化、混淆或写入失败时返回错误
+ */
+func WriteLicenseFile(license *License) ([]byte, error) {
+
+	content, encrypted, err := encodeLicenseFile(license)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeLicenseFile(license.ID, encrypted); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// encodeLicenseFile 生成并签名许可文件内容，返回混淆前的 JSON 和使用机器特征码混淆后的文件内容
+func encodeLicenseFile(license *License) ([]byte, string, error) {
+
+	msg := NewLicenseMsg(license)
+	if err := signLicenseMsg(&msg); err != nil {
+		return nil, "", err
+	}
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	encrypted, err := utils.ObfuscationUtil(content, license.SignatureCode)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, encrypted, nil
+}
+
+// writeLicenseFile 把混淆后的许可文件内容写入许可文件目录，已存在的文件被原子替换
+func writeLicenseFile(id string, encrypted string) error {
+
+	path := LicenseFilePath(id)
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(encrypted); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+/*
+ * DecodeLicenseFile 使用机器特征码反混淆许可文件并解析其中的内容
+ *
+ * @params: content []byte - 许可文件内容
+ *			signatureCode string - 签发时使用的机器特征码
+ * @returns:*LicenseMsg - 许可文件内容
+ *			error - 特征码不匹配或文件损坏时返回错误
+ */
+func DecodeLicenseFile(content []byte, signatureCode string) (*LicenseMsg, error) {
+
+	plain, err := utils.DeobfuscationUtil(strings.TrimSpace(string(content)), signatureCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// 有效内容之后是填充字节，只解析第一个完整的 JSON 对象
+	var msg LicenseMsg
+	if err := json.NewDecoder(bytes.NewReader(plain)).Decode(&msg); err != nil {
+		return nil, errors.New("license content could not be decoded, the machine code does not match this license")
+	}
+	if msg.Authorized.Id == "" {
+		return nil, errors.New("license does not contain an authorized section")
+	}
+	return &msg, nil
+}
+
+/*
+ * IssueAuditDetails 签发许可证时写入审计日志的附加信息，服务器和离线 licensectl 记录的内容一致
+ * @params: license *License - 签发的许可证
+ * @returns: map[string]string - 附加信息
+ */
+func IssueAuditDetails(license *License) map[string]string {
+
+	authorized := NewLicenseMsg(license).Authorized
+	details := map[string]string{
+		"signatureCode": license.SignatureCode,
+		"type":          license.Type,
+		"expiration":    authorized.Expiration,
+		"usersNum":      authorized.AllowedUsers,
+		"project":       license.Project,
+		"module":        license.Module,
+	}
+	if license.Version != "" {
+		details["version"] = license.Version
+	}
+	if license.Template != "" {
+		details["template"] = license.Template
+		details["templateVersion"] = strconv.Itoa(license.TemplateVersion)
+	}
+	if license.CustomerID != "" {
+		details["customer"] = license.CustomerID
+	}
+	if license.Licensee != "" {
+		details["licensee"] = license.Licensee
+	}
+	if license.Notes != "" {
+		details["notes"] = license.Notes
+	}
+	return details
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/store"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize 未指定 Limit 时每页返回的许可证数量
+	DefaultPageSize = 100
+	// MaxPageSize 每页最多返回的许可证数量
+	MaxPageSize = 1000
+)
+
+// ErrInvalidQuery 许可证查询参数无效
+var ErrInvalidQuery = errors.New("invalid license query")
+
+// LicenseQuery 许可证列表的过滤、搜索、排序和分页参数，字符串为空、时间为零值表示不按该条件过滤
+type LicenseQuery struct {
+	CustomerID    string
+	Project       string
+	Module        string // 许可证的模块字段包含该功能（不区分大小写）
+	Type          string
+	Status        string
+	SignatureCode string
+	// ExpiringBefore 只返回过期日期早于该日期的许可证
+	ExpiringBefore time.Time
+	// IssuedFrom、IssuedTo 签发日期（UTC）的范围，两端都包含
+	IssuedFrom time.Time
+	IssuedTo   time.Time
+	// Search 全文搜索备注和被许可方名称，多个词之间为“与”关系，不区分大小写
+	Search string
+	// Sort 排序字段，前缀 - 表示降序，默认按签发时间升序
+	Sort string
+	// Limit 每页数量，0 表示 DefaultPageSize，超过 MaxPageSize 时取 MaxPageSize
+	Limit int
+	// Cursor 上一页返回的 NextCursor，为空时从第一页开始
+	Cursor string
+}
+
+// LicensePage 一页许可证
+type LicensePage struct {
+	Licenses   []*store.LicenseRecord `json:"licenses"`
+	Total      int                    `json:"total"`
+	NextCursor string                 `json:"nextCursor,omitempty"`
+}
+
+// SortFields 获取可排序的字段名称
+func SortFields() []string {
+	return store.LicenseSortFields()
+}
+
+// cursor 分页游标的内容：排序方式以及上一页最后一条记录的排序键和ID
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"i"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return c, nil
+}
+
+/*
+ * matcher 根据查询参数生成客户和状态以外的匹配条件，客户和状态由存储的索引过滤
+ *
+ * @params: q LicenseQuery - 查询参数
+ * @returns:func(*store.LicenseRecord) bool - 匹配条件，没有其他条件时为 nil
+ */
+func (q LicenseQuery) matcher() func(*store.LicenseRecord) bool {
+
+	terms := strings.Fields(strings.ToLower(q.Search))
+	var issuedTo time.Time
+	if !q.IssuedTo.IsZero() {
+		issuedTo = q.IssuedTo.AddDate(0, 0, 1)
+	}
+	if q.Project == "" && q.Type == "" && q.SignatureCode == "" && q.Module == "" && len(terms) == 0 &&
+		q.ExpiringBefore.IsZero() && q.IssuedFrom.IsZero() && issuedTo.IsZero() {
+		return nil
+	}
+
+	return func(r *store.LicenseRecord) bool {
+		switch {
+		case q.Project != "" && r.Project != q.Project,
+			q.Type != "" && r.Type != q.Type,
+			q.SignatureCode != "" && r.SignatureCode != q.SignatureCode,
+			!q.ExpiringBefore.IsZero() && !r.ExpirationDate.Before(q.ExpiringBefore),
+			!q.IssuedFrom.IsZero() && r.Date.Before(q.IssuedFrom),
+			!issuedTo.IsZero() && !r.Date.Before(issuedTo):
+			return false
+		}
+		if q.Module != "" {
+			found := false
+			for _, feature := range SplitFeatures(r.Module) {
+				if strings.EqualFold(feature, q.Module) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if len(terms) > 0 {
+			text := strings.ToLower(r.Notes + "\n" + r.Licensee)
+			for _, term := range terms {
+				if !strings.Contains(text, term) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+/*
+ * QueryLicenses 按条件过滤、搜索和排序许可证，并以游标分页返回，排序和客户、状态过滤使用存储的索引
+ * 游标记录上一页最后一条记录的排序键，翻页期间新增或删除记录不会导致重复或遗漏已返回位置之前的记录
+ *
+ * @params: q LicenseQuery - 查询参数
+ * @returns:*LicensePage - 一页许可证，NextCursor 为空表示没有下一页
+ * 			error - 排序字段、分页参数或游标无效时返回包装了 ErrInvalidQuery 的错误
+ */
+func QueryLicenses(q LicenseQuery) (*LicensePage, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+
+	if q.Sort == "" {
+		q.Sort = "date"
+	}
+	field := strings.TrimPrefix(q.Sort, "-")
+	descending := field != q.Sort
+	if _, ok := store.LicenseSortKey(field, &store.LicenseRecord{}); !ok {
+		return nil, fmt.Errorf("%w: unknown sort field %q (fields: %s)", ErrInvalidQuery, field, strings.Join(SortFields(), ", "))
+	}
+	switch {
+	case q.Limit < 0:
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery)
+	case q.Limit == 0:
+		q.Limit = DefaultPageSize
+	case q.Limit > MaxPageSize:
+		q.Limit = MaxPageSize
+	}
+
+	iq := store.IndexQuery{
+		Sort:       field,
+		Descending: descending,
+		CustomerID: q.CustomerID,
+		Status:     q.Status,
+		Match:      q.matcher(),
+		Limit:      q.Limit,
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != q.Sort {
+			return nil, fmt.Errorf("%w: cursor was created with sort %q, not %q", ErrInvalidQuery, c.Sort, q.Sort)
+		}
+		iq.After, iq.AfterKey, iq.AfterID = true, c.Key, c.ID
+	}
+
+	result, err := s.QueryLicenses(iq)
+	if err != nil {
+		return nil, err
+	}
+	page := &LicensePage{Licenses: result.Licenses, Total: result.Total}
+	if result.More {
+		last := result.Licenses[len(result.Licenses)-1]
+		key, _ := store.LicenseSortKey(field, last)
+		page.NextCursor = encodeCursor(cursor{Sort: q.Sort, Key: key, ID: last.ID})
+	}
+	return page, nil
+}
+
+/*
+ * GetLicenseRecord 获取单个许可证记录
+ *
+ * @params: licenseID string - 许可证ID
+ * @returns:*store.LicenseRecord - 许可证记录
+ * 			error - 许可证不存在时返回 ErrUnknownLicense
+ */
+func GetLicenseRecord(licenseID string) (*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	record, err := s.GetLicense(licenseID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnknownLicense
+	}
+	return record, err
+}
+
+/*
+ * SetLicenseNotes 修改许可证备注
+ *
+ * @params: licenseID string - 许可证ID
+ * 			notes string - 新备注
+ * @returns:*store.LicenseRecord - 更新后的许可证记录
+ * 			error - 许可证不存在或写入失败时返回错误
+ */
+func SetLicenseNotes(licenseID string, notes string) (*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	record, err := s.SetLicenseNotes(licenseID, strings.TrimSpace(notes))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnknownLicense
+	}
+	return record, err
+}
@@ -0,0 +1,428 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"server/store"
+	"strings"
+	"time"
+)
+
+// ErrInvalidProduct 产品定义无效
+var ErrInvalidProduct = errors.New("invalid product")
+
+// ErrUnknownProduct 产品不在产品目录中
+var ErrUnknownProduct = errors.New("unknown product")
+
+// ErrUnknownEdition 产品没有该版本（授权类型）
+var ErrUnknownEdition = errors.New("unknown edition")
+
+// ErrUnknownVersion 产品没有该发行版本
+var ErrUnknownVersion = errors.New("unknown product version")
+
+// ErrUnknownFeature 产品没有该功能模块
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// ErrMissingExpiration 未指定过期日期，且产品版本没有默认有效期
+var ErrMissingExpiration = errors.New("expiration is required")
+
+// ErrInvalidIssueRequest 签发请求的机器特征码或日期格式无效
+var ErrInvalidIssueRequest = errors.New("invalid issue request")
+
+// catalogID 产品和产品版本ID的格式，ID 会写入许可文件的 project 和 type 字段
+var catalogID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// IssueRequest 签发许可证的参数
+// 指定 Template 时未填写的参数先取模板的值；产品目录非空时 Project 必须是目录中的产品；
+// Expiration 为零值时按 DurationDays 计算，二者都未指定时与 AllowedUsers 为 0、Module 为空一样取产品版本的默认值；
+// 指定 CustomerID 时 Licensee 默认为客户名称
+type IssueRequest struct {
+	SignatureCode   string
+	Type            string
+	Expiration      time.Time
+	DurationDays    uint
+	AllowedUsers    uint
+	Project         string
+	Module          string
+	Version         string
+	Template        string
+	TemplateVersion int
+	CustomerID      string
+	Licensee        string
+	Notes           string
+}
+
+// IssueBody POST /licenses 的请求体及批量签发中的一行，指定 template 时其余字段作为对模板的覆盖，
+// features 追加到 module 之后写入许可证的模块字段
+type IssueBody struct {
+	Template        string   `json:"template"`
+	TemplateVersion int      `json:"templateVersion"`
+	SignatureCode   string   `json:"signatureCode"`
+	Type            string   `json:"type"`
+	Expiration      string   `json:"expiration"`
+	DurationDays    uint     `json:"durationDays"`
+	AllowedUsers    uint     `json:"usersNum"`
+	Project         string   `json:"project"`
+	Module          string   `json:"module"`
+	Features        []string `json:"features"`
+	Version         string   `json:"version"`
+	Customer        string   `json:"customer"`
+	Licensee        string   `json:"licensee"`
+	Notes           string   `json:"notes"`
+}
+
+/*
+ * IssueRequest 校验请求体的格式并转换为签发参数
+ *
+ * @params: body IssueBody - 请求体
+ * @returns:IssueRequest - 签发参数
+ * 			error - 机器特征码不是 1-32 个字符或过期日期格式无效时返回包装了 ErrInvalidIssueRequest 的错误
+ */
+func (body IssueBody) IssueRequest() (IssueRequest, error) {
+
+	if body.SignatureCode == "" || len(body.SignatureCode) > 32 {
+		return IssueRequest{}, fmt.Errorf("%w: signature code must be 1-32 characters", ErrInvalidIssueRequest)
+	}
+	var expiration time.Time
+	if body.Expiration != "" {
+		var err error
+		if expiration, err = time.Parse("2006-01-02", body.Expiration); err != nil {
+			return IssueRequest{}, fmt.Errorf("%w: invalid expiration date %q, expected YYYY-MM-DD", ErrInvalidIssueRequest, body.Expiration)
+		}
+	}
+	module := body.Module
+	if len(body.Features) > 0 {
+		module = JoinFeatures(append(SplitFeatures(module), body.Features...))
+	}
+
+	return IssueRequest{
+		SignatureCode:   body.SignatureCode,
+		Type:            body.Type,
+		Expiration:      expiration,
+		DurationDays:    body.DurationDays,
+		AllowedUsers:    body.AllowedUsers,
+		Project:         body.Project,
+		Module:          module,
+		Version:         body.Version,
+		Template:        body.Template,
+		TemplateVersion: body.TemplateVersion,
+		CustomerID:      body.Customer,
+		Licensee:        body.Licensee,
+		Notes:           body.Notes,
+	}, nil
+}
+
+/*
+ * SplitFeatures 将许可证的模块字段拆分为功能列表，分隔符与客户端一致（逗号、分号和空白）
+ *
+ * @params: module string - 模块字段
+ * @returns:[]string - 功能列表
+ */
+func SplitFeatures(module string) []string {
+
+	return strings.FieldsFunc(module, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// JoinFeatures 将功能列表合并为许可证的模块字段
+func JoinFeatures(features []string) string {
+	return strings.Join(features, ", ")
+}
+
+/*
+ * normalizeProduct 校验产品定义并去除首尾空白，名称为空时使用ID
+ *
+ * @params: product *store.Product - 产品定义
+ * @returns:error - 定义无效时返回包装了 ErrInvalidProduct 的错误
+ */
+func normalizeProduct(product *store.Product) error {
+
+	var problems []string
+	product.ID = strings.TrimSpace(product.ID)
+	product.Name = strings.TrimSpace(product.Name)
+	if !catalogID.MatchString(product.ID) {
+		problems = append(problems, fmt.Sprintf("product id %q must be 1-64 letters, digits, '.', '_' or '-'", product.ID))
+	}
+	if product.Name == "" {
+		product.Name = product.ID
+	}
+
+	features := make(map[string]string)
+	for i, feature := range product.Features {
+		feature = strings.TrimSpace(feature)
+		product.Features[i] = feature
+		switch {
+		case feature == "" || len(SplitFeatures(feature)) != 1:
+			problems = append(problems, fmt.Sprintf("feature %q must be a single word without ',' or ';'", feature))
+		case features[strings.ToLower(feature)] != "":
+			problems = append(problems, fmt.Sprintf("duplicate feature %q", feature))
+		default:
+			features[strings.ToLower(feature)] = feature
+		}
+	}
+
+	versions := make(map[string]bool)
+	for i, version := range product.Versions {
+		version = strings.TrimSpace(version)
+		product.Versions[i] = version
+		switch {
+		case version == "":
+			problems = append(problems, "version must not be empty")
+		case versions[version]:
+			problems = append(problems, fmt.Sprintf("duplicate version %q", version))
+		default:
+			versions[version] = true
+		}
+	}
+
+	editions := make(map[string]bool)
+	for i := range product.Editions {
+		edition := &product.Editions[i]
+		edition.ID = strings.TrimSpace(edition.ID)
+		edition.Name = strings.TrimSpace(edition.Name)
+		switch {
+		case !catalogID.MatchString(edition.ID):
+			problems = append(problems, fmt.Sprintf("edition id %q must be 1-64 letters, digits, '.', '_' or '-'", edition.ID))
+		case editions[edition.ID]:
+			problems = append(problems, fmt.Sprintf("duplicate edition %q", edition.ID))
+		default:
+			editions[edition.ID] = true
+		}
+		for j, feature := range edition.Features {
+			name, ok := features[strings.ToLower(strings.TrimSpace(feature))]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("edition %q: feature %q is not one of the product features", edition.ID, feature))
+				continue
+			}
+			edition.Features[j] = name
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidProduct, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+/*
+ * CreateProduct 校验并保存新的产品
+ *
+ * @params: product *store.Product - 产品定义
+ * @returns:*store.Product - 保存后的产品
+ * 			error - 定义无效、ID 重复或写入失败时返回错误
+ */
+func CreateProduct(product *store.Product) (*store.Product, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	if err := normalizeProduct(product); err != nil {
+		return nil, err
+	}
+
+	product.Created = time.Now().UTC()
+	product.Updated = product.Created
+	if err := s.CreateProduct(product); err != nil {
+		return nil, err
+	}
+	return s.GetProduct(product.ID)
+}
+
+/*
+ * UpdateProduct 校验并覆盖已有的产品，已签发的许可证不受影响
+ *
+ * @params: product *store.Product - 产品定义
+ * @returns:*store.Product - 保存后的产品
+ * 			error - 定义无效、产品不存在或写入失败时返回错误
+ */
+func UpdateProduct(product *store.Product) (*store.Product, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	if err := normalizeProduct(product); err != nil {
+		return nil, err
+	}
+
+	product.Updated = time.Now().UTC()
+	if err := s.UpdateProduct(product); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrUnknownProduct
+		}
+		return nil, err
+	}
+	return s.GetProduct(product.ID)
+}
+
+/*
+ * ResolveIssueRequest 按产品目录校验签发参数并填充产品版本的默认值
+ * 产品目录为空时不做校验，保持自由填写项目名称的旧行为，但必须指定过期日期
+ *
+ * @params: req IssueRequest - 签发参数
+ * 			now time.Time - 计算默认过期日期的基准时间
+ * @returns:IssueRequest - 补全后的签发参数
+ * 			error - 产品、版本、发行版本或功能不在目录中，或缺少过期日期时返回错误
+ */
+func ResolveIssueRequest(req IssueRequest, now time.Time) (IssueRequest, error) {
+
+	if req.Expiration.IsZero() && req.DurationDays > 0 {
+		req.Expiration = daysFrom(now, req.DurationDays)
+	}
+
+	s := store.Default()
+	if s == nil || !s.HasProducts() {
+		if req.Expiration.IsZero() {
+			return req, ErrMissingExpiration
+		}
+		return req, nil
+	}
+
+	product, err := s.GetProduct(req.Project)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			ids := make([]string, 0)
+			for _, p := range s.ListProducts() {
+				ids = append(ids, p.ID)
+			}
+			return req, fmt.Errorf("%w %q (known products: %s)", ErrUnknownProduct, req.Project, strings.Join(ids, ", "))
+		}
+		return req, err
+	}
+
+	// 产品定义了版本时类型必须是其中之一，只有一个版本时可以省略
+	var edition *store.Edition
+	if len(product.Editions) > 0 {
+		if req.Type == "" && len(product.Editions) == 1 {
+			req.Type = product.Editions[0].ID
+		}
+		var ok bool
+		if edition, ok = product.Edition(req.Type); !ok {
+			ids := make([]string, 0, len(product.Editions))
+			for _, e := range product.Editions {
+				ids = append(ids, e.ID)
+			}
+			return req, fmt.Errorf("%w %q for product %s (editions: %s)", ErrUnknownEdition, req.Type, product.ID, strings.Join(ids, ", "))
+		}
+	}
+
+	if req.Version != "" && len(product.Versions) > 0 {
+		found := false
+		for _, version := range product.Versions {
+			if version == req.Version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return req, fmt.Errorf("%w %q for product %s (versions: %s)", ErrUnknownVersion, req.Version, product.ID, strings.Join(product.Versions, ", "))
+		}
+	}
+
+	// 功能名称不区分大小写，统一为目录中的写法
+	if requested := SplitFeatures(req.Module); len(requested) > 0 && len(product.Features) > 0 {
+		features := make([]string, 0, len(requested))
+		for _, name := range requested {
+			found := ""
+			for _, feature := range product.Features {
+				if strings.EqualFold(feature, name) {
+					found = feature
+					break
+				}
+			}
+			if found == "" {
+				return req, fmt.Errorf("%w %q for product %s (features: %s)", ErrUnknownFeature, name, product.ID, JoinFeatures(product.Features))
+			}
+			features = append(features, found)
+		}
+		req.Module = JoinFeatures(features)
+	}
+
+	if edition != nil {
+		if req.Module == "" {
+			req.Module = JoinFeatures(edition.Features)
+		}
+		if req.AllowedUsers == 0 {
+			req.AllowedUsers = edition.Seats
+		}
+		if req.Expiration.IsZero() && edition.DurationDays > 0 {
+			req.Expiration = daysFrom(now, edition.DurationDays)
+		}
+	}
+	if req.Expiration.IsZero() {
+		return req, ErrMissingExpiration
+	}
+	return req, nil
+}
+
+// daysFrom 返回基准时间所在日期（UTC）之后若干天的零点
+func daysFrom(now time.Time, days uint) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days))
+}
+
+/*
+ * IssueLicense 应用签发模板和客户信息，按产品目录校验签发参数、填充默认值并签发许可证，写入许可文件后保存记录
+ *
+ * @params: req IssueRequest - 签发参数
+ * @returns:*License - 签发的许可证
+ * 			[]byte - 签名后、混淆前的许可文件内容（JSON），混淆后的许可文件已写入许可文件目录
+ * 			error - 模板不可用、参数不符合产品目录或写入失败时返回错误
+ */
+func IssueLicense(req IssueRequest) (*License, []byte, error) {
+
+	license, err := prepareLicense(req, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := saveLicense(license)
+	if err != nil {
+		return nil, nil, err
+	}
+	return license, content, nil
+}
+
+/*
+ * prepareLicense 应用模板和客户、按产品目录校验签发参数并生成许可证，尚未保存
+ *
+ * @params: req IssueRequest - 签发参数
+ * 			now time.Time - 计算默认过期日期的基准时间
+ * @returns:*License - 新的许可证
+ * 			error - 参数不符合产品目录、模板或客户时返回错误
+ */
+func prepareLicense(req IssueRequest, now time.Time) (*License, error) {
+
+	req, err := ApplyTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+	if req, err = applyCustomer(req); err != nil {
+		return nil, err
+	}
+	resolved, err := ResolveIssueRequest(req, now)
+	if err != nil {
+		return nil, err
+	}
+
+	license, err := newLicense(resolved.SignatureCode, resolved.Type, resolved.Expiration, resolved.AllowedUsers, resolved.Project, resolved.Module)
+	if err != nil {
+		return nil, err
+	}
+	license.Version = resolved.Version
+	license.Template = resolved.Template
+	license.TemplateVersion = resolved.TemplateVersion
+	license.CustomerID = resolved.CustomerID
+	license.Licensee = resolved.Licensee
+	license.Notes = resolved.Notes
+	return license, nil
+}
+
+// IsCatalogError 判断错误是否由签发参数无效或不符合产品目录、模板、客户引起，调用方据此返回 400
+func IsCatalogError(err error) bool {
+	return errors.Is(err, ErrInvalidIssueRequest) || errors.Is(err, ErrUnknownProduct) || errors.Is(err, ErrUnknownEdition) || errors.Is(err, ErrUnknownVersion) ||
+		errors.Is(err, ErrUnknownFeature) || errors.Is(err, ErrMissingExpiration) ||
+		errors.Is(err, ErrUnknownTemplate) || errors.Is(err, ErrTemplateRetired) || errors.Is(err, ErrUnknownCustomer) || errors.Is(err, ErrInvalidCustomer)
+}
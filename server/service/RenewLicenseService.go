@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"server/logger"
+	"server/metrics"
+	"server/store"
+	"sync"
+	"time"
+)
+
+// ErrLicenseInactive 许可证已被暂停或吊销：暂停的许可证需要先恢复才能续期，吊销的许可证不能续期
+var ErrLicenseInactive = errors.New("license is suspended or revoked")
+
+// ErrInvalidExpiration 新的过期日期不晚于当前过期日期
+var ErrInvalidExpiration = errors.New("new expiration date must be later than the current one")
+
+// renewMutex 串行化续期，保证许可文件与最后保存的记录一致
+var renewMutex sync.Mutex
+
+/*
+ * LicenseFromRecord 将存储中的许可证记录转换为许可证
+ *
+ * @params: record *store.LicenseRecord - 许可证记录
+ * @returns:*License - 许可证
+ */
+func LicenseFromRecord(record *store.LicenseRecord) *License {
+
+	return &License{
+		ID:              record.ID,
+		LicenseID:       record.LicenseID,
+		Date:            record.Date,
+		SignatureCode:   record.SignatureCode,
+		Type:            record.Type,
+		ExpirationDate:  record.ExpirationDate,
+		AllowedUsers:    record.AllowedUsers,
+		Project:         record.Project,
+		Module:          record.Module,
+		Version:         record.Version,
+		Template:        record.Template,
+		TemplateVersion: record.TemplateVersion,
+		CustomerID:      record.CustomerID,
+		Licensee:        record.Licensee,
+		Notes:           record.Notes,
+	}
+}
+
+/*
+ * RenewLicense 延长许可证的过期日期并重新生成许可文件，许可证ID和其余授权信息保持不变，
+ * 客户端通过在线刷新即可获得续期后的许可文件
+ * 先在存储中比较并更新过期日期（已被暂停或吊销的许可证被拒绝），再写入许可文件；写入失败时把过期日期恢复原值
+ *
+ * @params: licenseID string - 许可证ID
+ * 			expiration time.Time - 新的过期日期
+ * @returns:*License - 续期后的许可证
+ * 			[]byte - 签名后、混淆前的新许可文件内容（JSON）
+ * 			error - 许可证不存在、已被暂停、日期无效或写入失败时返回错误
+ */
+func RenewLicense(licenseID string, expiration time.Time) (*License, []byte, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, nil, ErrStoreUnavailable
+	}
+
+	renewMutex.Lock()
+	defer renewMutex.Unlock()
+
+	var previous time.Time
+	record, err := s.UpdateActiveLicense(licenseID, func(record *store.LicenseRecord) error {
+		if !expiration.After(record.ExpirationDate) {
+			return ErrInvalidExpiration
+		}
+		previous = record.ExpirationDate
+		record.ExpirationDate = expiration
+		return nil
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return nil, nil, ErrUnknownLicense
+	case errors.Is(err, store.ErrLicenseInactive):
+		return nil, nil, ErrLicenseInactive
+	case err != nil:
+		return nil, nil, err
+	}
+
+	license := LicenseFromRecord(record)
+	content, err := WriteLicenseFile(license)
+	if err != nil {
+		// 许可文件仍是续期前的内容，只在记录没有被再次修改时恢复过期日期
+		_, rollbackErr := s.UpdateLicense(licenseID, func(record *store.LicenseRecord) error {
+			if !record.ExpirationDate.Equal(expiration) {
+				return ErrInvalidExpiration
+			}
+			record.ExpirationDate = previous
+			return nil
+		})
+		if rollbackErr != nil {
+			logger.Error("failed to restore license expiration after renewal failed", "id", licenseID, "error", rollbackErr)
+		}
+		return nil, nil, err
+	}
+
+	metrics.LicensesRenewed.Inc(license.Type, license.Project)
+	logger.Info("license renewed", "id", license.ID, "previous", previous.Format("2006-01-02"),
+		"expiration", expiration.Format("2006-01-02"))
+	return license, content, nil
+}
+
+/*
+ * ListLicenses 获取全部许可证，按签发时间排列
+ *
+ * @returns:[]*store.LicenseRecord - 许可证记录列表
+ * 			error - 未配置存储时返回错误
+ */
+func ListLicenses() ([]*store.LicenseRecord, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	return s.ListLicenses(), nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"sort"
+)
+
+// Permission 管理接口的操作权限
+type Permission string
+
+const (
+	// PermLicenseIssue 签发许可证
+	PermLicenseIssue Permission = "license:issue"
+	// PermLicenseSuspend 暂停、恢复许可证
+	PermLicenseSuspend Permission = "license:suspend"
+	// PermLicenseRevoke 永久吊销许可证，吊销后不能恢复，只授予管理员
+	PermLicenseRevoke Permission = "license:revoke"
+	// PermLicenseInspect 解码并检查任意许可文件，可看到许可文件绑定的机器码，只授予管理员
+	PermLicenseInspect Permission = "license:inspect"
+	// PermLicenseRead 查询许可证
+	PermLicenseRead Permission = "license:read"
+	// PermCheckinRead 查询签到汇总
+	PermCheckinRead Permission = "checkin:read"
+	// PermAlertRead 查询复制检测告警
+	PermAlertRead Permission = "alert:read"
+	// PermKeyManage 管理 API 密钥
+	PermKeyManage Permission = "apikey:manage"
+	// PermAuditRead 查询审计日志
+	PermAuditRead Permission = "audit:read"
+	// PermMetricsRead 采集 Prometheus 指标
+	PermMetricsRead Permission = "metrics:read"
+	// PermCatalogManage 管理产品目录
+	PermCatalogManage Permission = "catalog:manage"
+	// PermTemplateManage 管理签发模板
+	PermTemplateManage Permission = "template:manage"
+	// PermCustomerManage 管理客户
+	PermCustomerManage Permission = "customer:manage"
+)
+
+const (
+	// RoleViewer 只读角色，可查询许可证、签到和告警
+	RoleViewer = "viewer"
+	// RoleIssuer 销售角色，在只读基础上可签发许可证、管理签发模板和客户
+	RoleIssuer = "issuer"
+	// RoleSupport 技术支持角色，在只读基础上可暂停、恢复许可证，不能永久吊销
+	RoleSupport = "support"
+	// RoleAdmin 管理员角色，拥有全部权限，只有管理员可以永久吊销许可证
+	RoleAdmin = "admin"
+)
+
+// ErrInvalidRole 未知的角色
+var ErrInvalidRole = errors.New("invalid role")
+
+var readPermissions = []Permission{PermLicenseRead, PermCheckinRead, PermAlertRead, PermMetricsRead}
+
+var rolePermissions = map[string][]Permission{
+	RoleViewer:  readPermissions,
+	RoleIssuer:  append([]Permission{PermLicenseIssue, PermTemplateManage, PermCustomerManage}, readPermissions...),
+	RoleSupport: append([]Permission{PermLicenseSuspend}, readPermissions...),
+	RoleAdmin:   append([]Permission{PermLicenseIssue, PermLicenseSuspend, PermLicenseRevoke, PermLicenseInspect, PermKeyManage, PermAuditRead, PermCatalogManage, PermTemplateManage, PermCustomerManage}, readPermissions...),
+}
+
+/*
+ * RoleHasPermission 判断角色是否拥有指定权限
+ *
+ * @params: role string - 角色名称
+ * 			perm Permission - 权限
+ * @returns:bool - 角色拥有该权限时返回 true
+ */
+func RoleHasPermission(role string, perm Permission) bool {
+
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRole 判断角色名称是否有效
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Roles 获取全部角色名称
+func Roles() []string {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
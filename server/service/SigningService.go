@@ -0,0 +1,225 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"server/logger"
+	"server/utils"
+	"strings"
+	"sync"
+)
+
+// 许可文件签名的检查结果
+const (
+	SignatureValid      = "valid"
+	SignatureInvalid    = "invalid"
+	SignatureUnsigned   = "unsigned"
+	SignatureUnknownKey = "unknown-key"
+)
+
+// ErrNoSigningKey 未配置签名私钥
+var ErrNoSigningKey = errors.New("license signing key is not configured")
+
+var (
+	signingMutex sync.RWMutex
+	signingKey   ed25519.PrivateKey
+	// verifyKey 验证签名使用的公钥：配置了私钥时为其公钥，否则为 SetVerifyKey 设置的公钥（licensectl 从服务器获取）
+	verifyKey ed25519.PublicKey
+)
+
+// SigningKeyInfo 签名公钥的信息
+type SigningKeyInfo struct {
+	KeyID     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"` // base64 编码的 32 字节 Ed25519 公钥
+}
+
+// SetSigningKey 设置签名许可文件使用的私钥，为 nil 时生成的许可文件不带签名
+func SetSigningKey(key ed25519.PrivateKey) {
+	signingMutex.Lock()
+	defer signingMutex.Unlock()
+
+	signingKey = key
+	verifyKey = nil
+	if key != nil {
+		verifyKey = key.Public().(ed25519.PublicKey)
+	}
+}
+
+// SetVerifyKey 只设置验证签名使用的公钥，用于没有私钥的场合
+func SetVerifyKey(key ed25519.PublicKey) {
+	signingMutex.Lock()
+	defer signingMutex.Unlock()
+
+	verifyKey = key
+}
+
+/*
+ * ParseSigningKeyInfo 解析 SigningKeyInfo 中的公钥，并确认密钥ID与公钥一致
+ *
+ * @params: info SigningKeyInfo - 公钥信息
+ * @returns:ed25519.PublicKey - 公钥
+ * 			error - 公钥无效或与密钥ID不一致时返回错误
+ */
+func ParseSigningKeyInfo(info SigningKeyInfo) (ed25519.PublicKey, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(info.PublicKey)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+	key := ed25519.PublicKey(raw)
+	if utils.SigningKeyID(key) != info.KeyID {
+		return nil, errors.New("public key does not match key id " + info.KeyID)
+	}
+	return key, nil
+}
+
+/*
+ * LoadOrCreateSigningKey 读取签名私钥，文件不存在时生成新的密钥对并写入该文件
+ *
+ * @params: path string - 私钥文件路径
+ * @returns:ed25519.PrivateKey - 私钥
+ * 			bool - 是否新生成了密钥
+ * 			error - 读取、解析或写入失败时返回错误
+ */
+func LoadOrCreateSigningKey(path string) (ed25519.PrivateKey, bool, error) {
+
+	key, err := utils.LoadSigningKey(path)
+	if err == nil {
+		return key, false, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	if key, err = utils.GenerateSigningKey(); err != nil {
+		return nil, false, err
+	}
+	if err := utils.WriteSigningKey(path, key); err != nil {
+		return nil, false, err
+	}
+	logger.Warn("generated a new license signing key, configure its public key in the clients", "path", path,
+		"keyId", utils.SigningKeyID(key.Public().(ed25519.PublicKey)))
+	return key, true, nil
+}
+
+/*
+ * NewSigningKeyInfo 获取私钥对应的公钥信息
+ *
+ * @params: key ed25519.PrivateKey - 私钥
+ * @returns:SigningKeyInfo - 公钥信息
+ */
+func NewSigningKeyInfo(key ed25519.PrivateKey) SigningKeyInfo {
+
+	pub := key.Public().(ed25519.PublicKey)
+	return SigningKeyInfo{
+		KeyID:     utils.SigningKeyID(pub),
+		Algorithm: "ed25519",
+		PublicKey: utils.EncodePublicKey(pub),
+	}
+}
+
+/*
+ * CurrentSigningKey 获取当前签名私钥的公钥信息
+ *
+ * @returns:SigningKeyInfo - 公钥信息
+ * 			error - 未配置签名私钥时返回 ErrNoSigningKey
+ */
+func CurrentSigningKey() (SigningKeyInfo, error) {
+
+	signingMutex.RLock()
+	defer signingMutex.RUnlock()
+
+	if signingKey == nil {
+		return SigningKeyInfo{}, ErrNoSigningKey
+	}
+	return NewSigningKeyInfo(signingKey), nil
+}
+
+/*
+ * signLicenseMsg 使用当前私钥对授权信息签名，签名覆盖 authorized 对象序列化后的全部字节，
+ * 客户端按许可文件中 authorized 的原始字节验证；未配置私钥时不签名
+ *
+ * @params: msg *LicenseMsg - 许可文件内容，签名写入 KeyID 和 Signature
+ * @returns:error - 序列化失败时返回错误
+ */
+func signLicenseMsg(msg *LicenseMsg) error {
+
+	signingMutex.RLock()
+	key := signingKey
+	signingMutex.RUnlock()
+	if key == nil {
+		return nil
+	}
+
+	authorized, err := json.Marshal(msg.Authorized)
+	if err != nil {
+		return err
+	}
+	msg.KeyID = utils.SigningKeyID(key.Public().(ed25519.PublicKey))
+	msg.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, authorized))
+	return nil
+}
+
+/*
+ * VerifyLicenseFileSignature 使用机器特征码反混淆许可文件，并用当前公钥验证其中的签名
+ *
+ * @params: content []byte - 许可文件内容
+ * 			signatureCode string - 签发时使用的机器特征码
+ * @returns:string - 检查结果，Signature* 之一
+ * 			string - 说明
+ * 			error - 特征码不匹配或文件损坏时返回错误
+ */
+func VerifyLicenseFileSignature(content []byte, signatureCode string) (string, string, error) {
+
+	plain, err := utils.DeobfuscationUtil(strings.TrimSpace(string(content)), signatureCode)
+	if err != nil {
+		return "", "", err
+	}
+	var msg struct {
+		Authorized json.RawMessage `json:"authorized"`
+		KeyID      string          `json:"keyId"`
+		Signature  string          `json:"signature"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(plain)).Decode(&msg); err != nil {
+		return "", "", errors.New("license content could not be decoded, the machine code does not match this license")
+	}
+	status, detail := checkLicenseSignature(msg.Authorized, msg.KeyID, msg.Signature)
+	return status, detail, nil
+}
+
+/*
+ * checkLicenseSignature 使用当前公钥验证许可文件的签名
+ *
+ * @params: authorized []byte - 许可文件中 authorized 对象的原始字节
+ * 			keyID string - 许可文件中的密钥ID
+ * 			signature string - 许可文件中的签名
+ * @returns:string - 检查结果，Signature* 之一
+ * 			string - 说明
+ */
+func checkLicenseSignature(authorized []byte, keyID string, signature string) (string, string) {
+
+	if signature == "" {
+		return SignatureUnsigned, "the license carries no signature; it was issued before license signing was enabled, renew it to sign it"
+	}
+
+	signingMutex.RLock()
+	pub := verifyKey
+	signingMutex.RUnlock()
+	if pub == nil {
+		return SignatureUnknownKey, "no signing key is configured"
+	}
+	if keyID != utils.SigningKeyID(pub) {
+		return SignatureUnknownKey, "signed by key " + keyID + ", the current key is " + utils.SigningKeyID(pub)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !ed25519.Verify(pub, authorized, sig) {
+		return SignatureInvalid, "the Ed25519 signature does not match the authorized section, the license was modified"
+	}
+	return SignatureValid, "Ed25519 signature over the authorized section verified with the current signing key"
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"server/store"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTemplate 模板定义无效
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// ErrUnknownTemplate 模板或模板版本不存在
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// ErrTemplateRetired 模板已停用，不能再用于签发
+var ErrTemplateRetired = errors.New("template is retired")
+
+/*
+ * TemplateModule 将模板的模块和功能合并为许可证的模块字段，重复项只保留一次
+ *
+ * @params: template *store.Template - 模板
+ * @returns:string - 模块字段
+ */
+func TemplateModule(template *store.Template) string {
+
+	var features []string
+	seen := make(map[string]bool)
+	for _, feature := range append(SplitFeatures(template.Module), template.Features...) {
+		if key := strings.ToLower(feature); !seen[key] {
+			seen[key] = true
+			features = append(features, feature)
+		}
+	}
+	return JoinFeatures(features)
+}
+
+/*
+ * normalizeTemplate 校验模板并去除首尾空白；模板指定了项目且产品目录非空时按目录校验类型和功能
+ *
+ * @params: template *store.Template - 模板
+ * @returns:error - 定义无效时返回包装了 ErrInvalidTemplate 的错误
+ */
+func normalizeTemplate(template *store.Template) error {
+
+	template.Name = strings.TrimSpace(template.Name)
+	template.Type = strings.TrimSpace(template.Type)
+	template.Project = strings.TrimSpace(template.Project)
+	template.Module = strings.TrimSpace(template.Module)
+	if !catalogID.MatchString(template.Name) {
+		return fmt.Errorf("%w: name %q must be 1-64 letters, digits, '.', '_' or '-'", ErrInvalidTemplate, template.Name)
+	}
+	for i, feature := range template.Features {
+		feature = strings.TrimSpace(feature)
+		if feature == "" || len(SplitFeatures(feature)) != 1 {
+			return fmt.Errorf("%w: feature %q must be a single word without ',' or ';'", ErrInvalidTemplate, feature)
+		}
+		template.Features[i] = feature
+	}
+
+	if s := store.Default(); template.Project != "" && s != nil && s.HasProducts() {
+		_, err := ResolveIssueRequest(IssueRequest{
+			Type:         template.Type,
+			Expiration:   time.Now().UTC(),
+			AllowedUsers: template.AllowedUsers,
+			Project:      template.Project,
+			Module:       TemplateModule(template),
+		}, time.Now())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+	}
+	return nil
+}
+
+/*
+ * SaveTemplate 校验并保存模板的新版本
+ *
+ * @params: template *store.Template - 模板内容
+ * 			actor string - 操作者，记录在模板版本中
+ * 			create bool - 为 true 时创建新模板，为 false 时为已有模板追加版本
+ * @returns:*store.Template - 保存后的模板版本
+ * 			error - 定义无效、模板已存在或不存在、写入失败时返回错误
+ */
+func SaveTemplate(template *store.Template, actor string, create bool) (*store.Template, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	if err := normalizeTemplate(template); err != nil {
+		return nil, err
+	}
+
+	template.Retired = false
+	template.Created = time.Now().UTC()
+	template.CreatedBy = actor
+	saved, err := s.AddTemplateVersion(template, create)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnknownTemplate
+	}
+	return saved, err
+}
+
+/*
+ * RetireTemplate 停用模板：追加一个标记为停用的版本，历史版本保留，供已签发的许可证追溯
+ *
+ * @params: name string - 模板名称
+ * 			actor string - 操作者
+ * @returns:*store.Template - 停用版本
+ * 			error - 模板不存在、已停用或写入失败时返回错误
+ */
+func RetireTemplate(name string, actor string) (*store.Template, error) {
+
+	s := store.Default()
+	if s == nil {
+		return nil, ErrStoreUnavailable
+	}
+	latest, err := s.GetTemplate(name, 0)
+	if err != nil {
+		return nil, ErrUnknownTemplate
+	}
+	if latest.Retired {
+		return nil, ErrTemplateRetired
+	}
+
+	latest.Retired = true
+	latest.Created = time.Now().UTC()
+	latest.CreatedBy = actor
+	return s.AddTemplateVersion(latest, false)
+}
+
+/*
+ * ApplyTemplate 用模板填充签发参数中未指定的字段，请求中已指定的字段优先
+ * TemplateVersion 为 0 时使用最新版本，并在返回的参数中记录实际使用的版本号
+ *
+ * @params: req IssueRequest - 签发参数，Template 为空时原样返回
+ * @returns:IssueRequest - 填充后的签发参数
+ * 			error - 模板或版本不存在、模板已停用时返回错误
+ */
+func ApplyTemplate(req IssueRequest) (IssueRequest, error) {
+
+	if req.Template == "" {
+		return req, nil
+	}
+	s := store.Default()
+	if s == nil {
+		return req, ErrStoreUnavailable
+	}
+
+	latest, err := s.GetTemplate(req.Template, 0)
+	if err != nil {
+		return req, fmt.Errorf("%w %q", ErrUnknownTemplate, req.Template)
+	}
+	if latest.Retired {
+		return req, fmt.Errorf("%w: %s", ErrTemplateRetired, req.Template)
+	}
+	template := latest
+	if req.TemplateVersion != 0 {
+		if template, err = s.GetTemplate(req.Template, req.TemplateVersion); err != nil || template.Retired {
+			return req, fmt.Errorf("%w %q version %d", ErrUnknownTemplate, req.Template, req.TemplateVersion)
+		}
+	}
+
+	req.TemplateVersion = template.Version
+	if req.Type == "" {
+		req.Type = template.Type
+	}
+	if req.Project == "" {
+		req.Project = template.Project
+	}
+	if req.Module == "" {
+		req.Module = TemplateModule(template)
+	}
+	if req.AllowedUsers == 0 {
+		req.AllowedUsers = template.AllowedUsers
+	}
+	if req.Expiration.IsZero() && req.DurationDays == 0 {
+		req.DurationDays = template.DurationDays
+	}
+	return req, nil
+}
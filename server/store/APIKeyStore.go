@@ -0,0 +1,120 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrDuplicateKey API 密钥重复
+var ErrDuplicateKey = errors.New("api key already exists")
+
+// APIKey 管理接口使用的 API 密钥，只保存密钥的 SHA-256 哈希值
+type APIKey struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Hash     string    `json:"-"`
+	Role     string    `json:"role"`
+	Scopes   []string  `json:"scopes,omitempty"`
+	Created  time.Time `json:"created"`
+	Disabled bool      `json:"disabled"`
+}
+
+// legacyScopeRoles 早期版本按权限范围（scopes）授权，按权限从高到低映射为角色
+var legacyScopeRoles = []struct {
+	scope string
+	role  string
+}{
+	{"admin", "admin"},
+	{"issue", "issuer"},
+	{"revoke", "support"},
+	{"read", "viewer"},
+}
+
+// migrateRole 为只有权限范围、没有角色的旧密钥补全角色
+func (k *APIKey) migrateRole() {
+	if k.Role != "" {
+		return
+	}
+	for _, legacy := range legacyScopeRoles {
+		for _, scope := range k.Scopes {
+			if scope == legacy.scope {
+				k.Role = legacy.role
+				k.Scopes = nil
+				return
+			}
+		}
+	}
+}
+
+/*
+ * SaveAPIKey 保存新的 API 密钥
+ * @params: key *APIKey - 密钥记录，Hash 必须唯一
+ * @returns: error - 哈希重复时返回 ErrDuplicateKey，写入失败时返回错误
+ */
+func (s *Store) SaveAPIKey(key *APIKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.APIKeys[key.Hash]; ok {
+		return ErrDuplicateKey
+	}
+	c := *key
+	s.data.APIKeys[key.Hash] = &c
+	return s.save()
+}
+
+/*
+ * FindAPIKey 根据密钥哈希查找 API 密钥
+ * @params: hash string - 密钥的 SHA-256 哈希值（十六进制）
+ * @returns: *APIKey - 密钥记录副本
+ *			error - 不存在时返回 ErrNotFound
+ */
+func (s *Store) FindAPIKey(hash string) (*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	key, ok := s.data.APIKeys[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *key
+	return &c, nil
+}
+
+/*
+ * DisableAPIKey 停用指定ID的 API 密钥
+ * @params: id string - 密钥ID
+ * @returns: error - 不存在时返回 ErrNotFound，写入失败时返回错误
+ */
+func (s *Store) DisableAPIKey(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range s.data.APIKeys {
+		if key.ID == id {
+			key.Disabled = true
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+/*
+ * ListAPIKeys 获取全部 API 密钥，按创建时间排列
+ * @returns: []*APIKey - 密钥记录副本列表
+ */
+func (s *Store) ListAPIKeys() []*APIKey {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*APIKey, 0, len(s.data.APIKeys))
+	for _, key := range s.data.APIKeys {
+		c := *key
+		list = append(list, &c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"time"
+)
+
+// Alert 复制检测产生的告警记录
+type Alert struct {
+	ID           string    `json:"id"`
+	LicenseID    string    `json:"licenseId"`
+	Reason       string    `json:"reason"`
+	Fingerprints []string  `json:"fingerprints"`
+	IPRanges     []string  `json:"ipRanges"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+	Suspended    bool      `json:"suspended"`
+	Resolved     bool      `json:"resolved"`
+}
+
+/*
+ * RaiseAlert 记录告警；同一许可证存在未处理的同类告警时更新该告警而不是新增
+ * @params: alert *Alert - 告警信息
+ * @returns: *Alert - 保存后的告警副本
+ *			bool - 是否为新增告警
+ *			error - 写入失败时返回错误
+ */
+func (s *Store) RaiseAlert(alert *Alert) (*Alert, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, existing := range s.data.Alerts {
+		if existing.LicenseID == alert.LicenseID && existing.Reason == alert.Reason && !existing.Resolved {
+			existing.Fingerprints = alert.Fingerprints
+			existing.IPRanges = alert.IPRanges
+			existing.LastSeen = alert.LastSeen
+			existing.Suspended = existing.Suspended || alert.Suspended
+			if err := s.save(); err != nil {
+				return nil, false, err
+			}
+			c := *existing
+			return &c, false, nil
+		}
+	}
+
+	c := *alert
+	s.data.Alerts = append(s.data.Alerts, &c)
+	if err := s.save(); err != nil {
+		return nil, false, err
+	}
+	result := c
+	return &result, true, nil
+}
+
+/*
+ * ResolveAlerts 将指定许可证的全部未处理告警标记为已处理
+ * @params: licenseID string - 许可证ID
+ * @returns: error - 写入失败时返回错误
+ */
+func (s *Store) ResolveAlerts(licenseID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, alert := range s.data.Alerts {
+		if alert.LicenseID == licenseID {
+			alert.Resolved = true
+		}
+	}
+	return s.save()
+}
+
+/*
+ * ListAlerts 获取告警记录，按产生时间倒序排列
+ * @params: includeResolved bool - 是否包含已处理的告警
+ * @returns: []*Alert - 告警副本列表
+ */
+func (s *Store) ListAlerts(includeResolved bool) []*Alert {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*Alert, 0, len(s.data.Alerts))
+	for i := len(s.data.Alerts) - 1; i >= 0; i-- {
+		alert := s.data.Alerts[i]
+		if alert.Resolved && !includeResolved {
+			continue
+		}
+		c := *alert
+		list = append(list, &c)
+	}
+	return list
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// minCompactLines 签到日志超过该行数且超过保留数据的两倍时压缩
+const minCompactLines = 10000
+
+// checkinEntry 签到日志中的一行，三个字段中只有一个不为空
+// Checkin 为一次签到；压缩后的日志先写出每个许可证的使用汇总（Usage），再写出保留的签到历史（History），
+// 回放时 History 只追加到签到历史，不再更新使用汇总
+type checkinEntry struct {
+	Checkin *Checkin      `json:"checkin,omitempty"`
+	Usage   *LicenseUsage `json:"usage,omitempty"`
+	History *Checkin      `json:"history,omitempty"`
+}
+
+// checkinLogPath 签到日志的路径：与存储文件同目录，例如 license-store.json 对应 license-store.checkins.jsonl
+func checkinLogPath(storePath string) string {
+	return strings.TrimSuffix(storePath, filepath.Ext(storePath)) + ".checkins.jsonl"
+}
+
+/*
+ * openCheckinLog 回放签到日志，恢复签到汇总和签到历史，调用方必须持有写锁或尚未共享存储实例
+ * 日志末尾不完整的行（追加时进程退出）被截断；日志不存在而存储文件中有旧版本保存的签到数据时，将其迁移到签到日志，
+ * 之后签到数据不再写入存储文件
+ * @returns: error - 读取、解析或迁移失败时返回错误
+ */
+func (s *Store) openCheckinLog() error {
+
+	path := checkinLogPath(s.path)
+	_, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		for id, usage := range s.data.Checkins {
+			if usage.Fingerprints == nil {
+				usage.Fingerprints = make(map[string]*FingerprintUsage)
+			}
+			trimFingerprints(usage)
+			s.usage[id] = usage
+		}
+		for id, history := range s.data.History {
+			s.history[id] = history
+		}
+		if len(s.usage) > 0 || len(s.history) > 0 {
+			if err := s.compactCheckins(); err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return err
+	default:
+		if err := s.replayCheckins(path); err != nil {
+			return err
+		}
+	}
+
+	if s.data.Checkins != nil || s.data.History != nil {
+		s.data.Checkins, s.data.History = nil, nil
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayCheckins 逐行回放签到日志
+func (s *Store) replayCheckins(path string) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	lines := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// 最后一行没有换行符，说明追加时被中断，丢弃该行
+				if err := os.Truncate(path, offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var entry checkinEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("check-in log %s: invalid entry at offset %d: %w", path, offset, err)
+		}
+		switch {
+		case entry.Checkin != nil:
+			s.applyCheckin(*entry.Checkin)
+		case entry.Usage != nil:
+			if entry.Usage.Fingerprints == nil {
+				entry.Usage.Fingerprints = make(map[string]*FingerprintUsage)
+			}
+			trimFingerprints(entry.Usage)
+			s.usage[entry.Usage.LicenseID] = entry.Usage
+		case entry.History != nil:
+			s.appendHistory(*entry.History)
+		}
+		offset += int64(len(line))
+		lines++
+	}
+
+	s.checkinLines = lines
+	s.checkinCompactAt = compactThreshold(s.retainedCheckins())
+	return nil
+}
+
+/*
+ * appendCheckin 向签到日志追加一行，调用方必须持有写锁
+ * 写入失败时把日志截断到写入前的长度，避免留下不完整的行
+ * @params: entry checkinEntry - 日志行
+ * @returns: error - 写入失败时返回错误
+ */
+func (s *Store) appendCheckin(entry checkinEntry) error {
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.checkinLog == nil {
+		if err := s.openCheckinAppend(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.checkinLog.Write(line); err != nil {
+		_ = s.checkinLog.Truncate(s.checkinSize)
+		return err
+	}
+	s.checkinSize += int64(len(line))
+	s.checkinLines++
+	return nil
+}
+
+// openCheckinAppend 以追加方式打开签到日志
+func (s *Store) openCheckinAppend() error {
+
+	f, err := os.OpenFile(checkinLogPath(s.path), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.checkinLog = f
+	s.checkinSize = info.Size()
+	return nil
+}
+
+/*
+ * compactCheckins 把当前的签到汇总和保留的签到历史写入临时文件后原子替换签到日志，调用方必须持有写锁
+ * @returns: error - 写入失败时返回错误，原日志保持不变
+ */
+func (s *Store) compactCheckins() error {
+
+	path := checkinLogPath(s.path)
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	lines := 0
+	for _, usage := range s.usage {
+		if err := encoder.Encode(checkinEntry{Usage: usage}); err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmpName)
+			return err
+		}
+		lines++
+	}
+	for _, history := range s.history {
+		for i := range history {
+			if err := encoder.Encode(checkinEntry{History: &history[i]}); err != nil {
+				_ = tmp.Close()
+				_ = os.Remove(tmpName)
+				return err
+			}
+			lines++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+
+	// 原来的文件句柄指向已被替换的旧日志，下一次追加时重新打开
+	if s.checkinLog != nil {
+		_ = s.checkinLog.Close()
+		s.checkinLog = nil
+	}
+	s.checkinLines = lines
+	s.checkinCompactAt = compactThreshold(lines)
+	return s.openCheckinAppend()
+}
+
+// retainedCheckins 压缩后日志的行数：每个许可证一行使用汇总加上保留的签到历史
+func (s *Store) retainedCheckins() int {
+	n := len(s.usage)
+	for _, history := range s.history {
+		n += len(history)
+	}
+	return n
+}
+
+// compactThreshold 日志超过保留数据的两倍（至少 minCompactLines 行）时压缩
+func compactThreshold(retained int) int {
+	if 2*retained > minCompactLines {
+		return 2 * retained
+	}
+	return minCompactLines
+}
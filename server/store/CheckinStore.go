@@ -0,0 +1,181 @@
+package store
+
+import (
+	"server/metrics"
+	"sort"
+	"time"
+)
+
+// maxHistoryPerLicense 每个许可证保留的签到历史条数上限
+const maxHistoryPerLicense = 500
+
+// maxFingerprintsPerLicense 每个许可证保留的机器指纹个数上限，超过时丢弃最久没有签到的指纹
+const maxFingerprintsPerLicense = 100
+
+// Checkin 客户端的一次签到上报
+type Checkin struct {
+	LicenseID   string    `json:"licenseId"`
+	Fingerprint string    `json:"fingerprint"`
+	AppVersion  string    `json:"appVersion"`
+	ActiveUsers uint      `json:"activeUsers"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	Time        time.Time `json:"time"`
+}
+
+// FingerprintUsage 某个机器指纹对同一许可证的使用情况
+type FingerprintUsage struct {
+	Fingerprint string    `json:"fingerprint"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	AppVersion  string    `json:"appVersion"`
+	ActiveUsers uint      `json:"activeUsers"`
+	RemoteAddr  string    `json:"remoteAddr"`
+}
+
+// LicenseUsage 单个许可证的签到汇总，Fingerprints 最多保留 maxFingerprintsPerLicense 个最近签到的指纹
+// SuspectedClone 由服务层按复制检测窗口和最近一次恢复时间计算后填写，存储中的值不作为依据
+type LicenseUsage struct {
+	LicenseID      string                       `json:"licenseId"`
+	LastSeen       time.Time                    `json:"lastSeen"`
+	Fingerprints   map[string]*FingerprintUsage `json:"fingerprints"`
+	SuspectedClone bool                         `json:"suspectedClone"`
+}
+
+/*
+ * RecordCheckin 记录一次签到并更新许可证的使用汇总
+ * 同一许可证出现多个不同机器指纹时标记为疑似复制；签到先追加到签到日志，追加成功后才更新内存中的数据，
+ * 追加失败时内存中的数据保持原状
+ * @params: checkin Checkin - 签到信息
+ * @returns: *LicenseUsage - 更新后的使用汇总副本
+ *			error - 写入失败时返回错误
+ */
+func (s *Store) RecordCheckin(checkin Checkin) (*LicenseUsage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.appendCheckin(checkinEntry{Checkin: &checkin}); err != nil {
+		metrics.StoreErrors.Inc("checkin")
+		return nil, err
+	}
+	usage := s.applyCheckin(checkin)
+
+	if s.checkinLines > s.checkinCompactAt {
+		if err := s.compactCheckins(); err != nil {
+			// 压缩失败不影响已经写入日志的签到，下一次签到时重试
+			metrics.StoreErrors.Inc("checkin")
+		}
+	}
+	return copyUsage(usage), nil
+}
+
+// applyCheckin 在内存中应用一次签到，调用方必须持有写锁
+func (s *Store) applyCheckin(checkin Checkin) *LicenseUsage {
+
+	usage, ok := s.usage[checkin.LicenseID]
+	if !ok {
+		usage = &LicenseUsage{
+			LicenseID:    checkin.LicenseID,
+			Fingerprints: make(map[string]*FingerprintUsage),
+		}
+		s.usage[checkin.LicenseID] = usage
+	}
+
+	fp, ok := usage.Fingerprints[checkin.Fingerprint]
+	if !ok {
+		fp = &FingerprintUsage{
+			Fingerprint: checkin.Fingerprint,
+			FirstSeen:   checkin.Time,
+		}
+		usage.Fingerprints[checkin.Fingerprint] = fp
+	}
+	fp.LastSeen = checkin.Time
+	fp.AppVersion = checkin.AppVersion
+	fp.ActiveUsers = checkin.ActiveUsers
+	fp.RemoteAddr = checkin.RemoteAddr
+
+	usage.LastSeen = checkin.Time
+	trimFingerprints(usage)
+
+	s.appendHistory(checkin)
+	return usage
+}
+
+// trimFingerprints 丢弃最久没有签到的指纹，直到不超过 maxFingerprintsPerLicense 个
+func trimFingerprints(usage *LicenseUsage) {
+	for len(usage.Fingerprints) > maxFingerprintsPerLicense {
+		delete(usage.Fingerprints, oldestFingerprint(usage.Fingerprints))
+	}
+}
+
+// oldestFingerprint 最后签到时间最早的指纹，时间相同时取指纹较小的一个，保证回放签到日志的结果一致
+func oldestFingerprint(fingerprints map[string]*FingerprintUsage) string {
+	oldest := ""
+	for key, fp := range fingerprints {
+		if oldest == "" {
+			oldest = key
+			continue
+		}
+		o := fingerprints[oldest]
+		if fp.LastSeen.Before(o.LastSeen) || (fp.LastSeen.Equal(o.LastSeen) && key < oldest) {
+			oldest = key
+		}
+	}
+	return oldest
+}
+
+// appendHistory 追加一条签到历史，只保留最近 maxHistoryPerLicense 条，调用方必须持有写锁
+func (s *Store) appendHistory(checkin Checkin) {
+
+	history := append(s.history[checkin.LicenseID], checkin)
+	if len(history) > maxHistoryPerLicense {
+		history = history[len(history)-maxHistoryPerLicense:]
+	}
+	s.history[checkin.LicenseID] = history
+}
+
+/*
+ * ListCheckins 获取全部许可证的签到汇总，按最后签到时间倒序排列
+ * @returns: []*LicenseUsage - 签到汇总副本列表
+ */
+func (s *Store) ListCheckins() []*LicenseUsage {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*LicenseUsage, 0, len(s.usage))
+	for _, usage := range s.usage {
+		list = append(list, copyUsage(usage))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen.After(list[j].LastSeen)
+	})
+	return list
+}
+
+func copyUsage(usage *LicenseUsage) *LicenseUsage {
+	c := *usage
+	c.Fingerprints = make(map[string]*FingerprintUsage, len(usage.Fingerprints))
+	for k, v := range usage.Fingerprints {
+		fp := *v
+		c.Fingerprints[k] = &fp
+	}
+	return &c
+}
+
+/*
+ * CheckinHistory 获取指定许可证在某时间之后的签到历史，按时间先后排列
+ * @params: licenseID string - 许可证ID
+ *			since time.Time - 起始时间，零值表示全部历史
+ * @returns: []Checkin - 签到历史副本
+ */
+func (s *Store) CheckinHistory(licenseID string, since time.Time) []Checkin {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]Checkin, 0)
+	for _, checkin := range s.history[licenseID] {
+		if !checkin.Time.Before(since) {
+			list = append(list, checkin)
+		}
+	}
+	return list
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrDuplicateCustomer 客户的外部 CRM ID 重复
+var ErrDuplicateCustomer = errors.New("customer with this external id already exists")
+
+// ErrCustomerInUse 客户仍关联着许可证，不能删除
+var ErrCustomerInUse = errors.New("customer has licenses")
+
+// Customer 客户（组织），许可证通过 CustomerID 关联到客户
+type Customer struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Contact    Contact   `json:"contact"`
+	ExternalID string    `json:"externalId,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+}
+
+// Contact 客户的联系人
+type Contact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// externalIDTaken 判断外部 CRM ID 是否已被其他客户使用，调用方必须持有锁
+func (s *Store) externalIDTaken(customer *Customer) bool {
+	if customer.ExternalID == "" {
+		return false
+	}
+	for _, c := range s.data.Customers {
+		if c.ID != customer.ID && strings.EqualFold(c.ExternalID, customer.ExternalID) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * SaveCustomer 保存客户，ID 相同时覆盖并保留创建时间
+ * @params: customer *Customer - 客户记录
+ * @returns: error - 外部 CRM ID 重复时返回 ErrDuplicateCustomer，写入失败时返回错误
+ */
+func (s *Store) SaveCustomer(customer *Customer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.externalIDTaken(customer) {
+		return ErrDuplicateCustomer
+	}
+	c := *customer
+	if existing, ok := s.data.Customers[customer.ID]; ok {
+		c.Created = existing.Created
+	}
+	s.data.Customers[customer.ID] = &c
+	return s.save()
+}
+
+/*
+ * GetCustomer 根据ID获取客户
+ * @params: id string - 客户ID
+ * @returns: *Customer - 客户记录副本
+ *			error - 不存在时返回 ErrNotFound
+ */
+func (s *Store) GetCustomer(id string) (*Customer, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	customer, ok := s.data.Customers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *customer
+	return &c, nil
+}
+
+/*
+ * DeleteCustomer 删除客户，仍有许可证关联该客户时拒绝删除
+ * @params: id string - 客户ID
+ * @returns: error - 不存在时返回 ErrNotFound，有关联许可证时返回 ErrCustomerInUse，写入失败时返回错误
+ */
+func (s *Store) DeleteCustomer(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.Customers[id]; !ok {
+		return ErrNotFound
+	}
+	for _, record := range s.data.Licenses {
+		if record.CustomerID == id {
+			return ErrCustomerInUse
+		}
+	}
+	delete(s.data.Customers, id)
+	return s.save()
+}
+
+/*
+ * ListCustomers 获取全部客户，按名称排列
+ * @returns: []*Customer - 客户记录副本列表
+ */
+func (s *Store) ListCustomers() []*Customer {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*Customer, 0, len(s.data.Customers))
+	for _, customer := range s.data.Customers {
+		c := *customer
+		list = append(list, &c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name == list[j].Name {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
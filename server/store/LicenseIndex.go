@@ -0,0 +1,328 @@
+package store
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rebuildThreshold 一次修改的许可证超过该数量时重建索引，而不是逐条插入有序列表
+const rebuildThreshold = 64
+
+// licenseSortKeys 可排序的字段及其排序键，排序键按字符串比较，排序键相同的记录再按ID排列
+var licenseSortKeys = map[string]func(*LicenseRecord) string{
+	"date":       func(r *LicenseRecord) string { return timeKey(r.Date) },
+	"expiration": func(r *LicenseRecord) string { return timeKey(r.ExpirationDate) },
+	"id":         func(r *LicenseRecord) string { return r.ID },
+	"project":    func(r *LicenseRecord) string { return r.Project },
+	"type":       func(r *LicenseRecord) string { return r.Type },
+	"customer":   func(r *LicenseRecord) string { return r.CustomerID },
+	"licensee":   func(r *LicenseRecord) string { return strings.ToLower(r.Licensee) },
+	"status":     func(r *LicenseRecord) string { return r.EffectiveStatus() },
+	"usersNum":   func(r *LicenseRecord) string { return fmt.Sprintf("%010d", r.AllowedUsers) },
+}
+
+// timeKey 把时间转换为可按字符串比较的排序键
+func timeKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000")
+}
+
+// LicenseSortFields 获取可排序的字段名称
+func LicenseSortFields() []string {
+	fields := make([]string, 0, len(licenseSortKeys))
+	for field := range licenseSortKeys {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+/*
+ * LicenseSortKey 计算许可证记录在指定排序字段上的排序键，用于生成分页游标
+ * @params: field string - 排序字段
+ *			r *LicenseRecord - 许可证记录
+ * @returns: string - 排序键
+ *			bool - 排序字段是否存在
+ */
+func LicenseSortKey(field string, r *LicenseRecord) (string, bool) {
+	key, ok := licenseSortKeys[field]
+	if !ok {
+		return "", false
+	}
+	return key(r), true
+}
+
+// indexEntry 有序索引中的一项
+type indexEntry struct {
+	key string
+	id  string
+}
+
+// before 判断 e 是否排在 (key, id) 之前
+func (e indexEntry) before(key string, id string) bool {
+	if e.key != key {
+		return e.key < key
+	}
+	return e.id < id
+}
+
+// licenseIndex 许可证的内存索引：每个排序字段一个按 (排序键, ID) 升序排列的列表，以及按客户和状态分组的ID集合
+// 索引只在打开存储时构建，之后随每次修改增量更新，调用方必须持有存储的锁
+type licenseIndex struct {
+	sorted     map[string][]indexEntry
+	byCustomer map[string]map[string]struct{}
+	byStatus   map[string]map[string]struct{}
+}
+
+// buildLicenseIndex 为全部许可证记录构建索引
+func buildLicenseIndex(licenses map[string]*LicenseRecord) *licenseIndex {
+	idx := &licenseIndex{
+		sorted:     make(map[string][]indexEntry, len(licenseSortKeys)),
+		byCustomer: make(map[string]map[string]struct{}),
+		byStatus:   make(map[string]map[string]struct{}),
+	}
+	for field, key := range licenseSortKeys {
+		entries := make([]indexEntry, 0, len(licenses))
+		for id, record := range licenses {
+			entries = append(entries, indexEntry{key: key(record), id: id})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].before(entries[j].key, entries[j].id)
+		})
+		idx.sorted[field] = entries
+	}
+	for id, record := range licenses {
+		addToSet(idx.byCustomer, record.CustomerID, id)
+		addToSet(idx.byStatus, record.EffectiveStatus(), id)
+	}
+	return idx
+}
+
+/*
+ * update 把一条许可证记录的修改应用到索引
+ * @params: previous *LicenseRecord - 修改前的记录，新增时为 nil
+ *			current *LicenseRecord - 修改后的记录，删除时为 nil
+ */
+func (idx *licenseIndex) update(previous *LicenseRecord, current *LicenseRecord) {
+	for field, key := range licenseSortKeys {
+		entries := idx.sorted[field]
+		if previous != nil {
+			entries = removeEntry(entries, indexEntry{key: key(previous), id: previous.ID})
+		}
+		if current != nil {
+			entries = insertEntry(entries, indexEntry{key: key(current), id: current.ID})
+		}
+		idx.sorted[field] = entries
+	}
+	if previous != nil {
+		removeFromSet(idx.byCustomer, previous.CustomerID, previous.ID)
+		removeFromSet(idx.byStatus, previous.EffectiveStatus(), previous.ID)
+	}
+	if current != nil {
+		addToSet(idx.byCustomer, current.CustomerID, current.ID)
+		addToSet(idx.byStatus, current.EffectiveStatus(), current.ID)
+	}
+}
+
+// search 返回第一个不排在 (key, id) 之前的位置
+func search(entries []indexEntry, key string, id string) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return !entries[i].before(key, id)
+	})
+}
+
+func insertEntry(entries []indexEntry, e indexEntry) []indexEntry {
+	i := search(entries, e.key, e.id)
+	entries = append(entries, indexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = e
+	return entries
+}
+
+func removeEntry(entries []indexEntry, e indexEntry) []indexEntry {
+	i := search(entries, e.key, e.id)
+	if i < len(entries) && entries[i] == e {
+		entries = append(entries[:i], entries[i+1:]...)
+	}
+	return entries
+}
+
+func addToSet(sets map[string]map[string]struct{}, value string, id string) {
+	set, ok := sets[value]
+	if !ok {
+		set = make(map[string]struct{})
+		sets[value] = set
+	}
+	set[id] = struct{}{}
+}
+
+func removeFromSet(sets map[string]map[string]struct{}, value string, id string) {
+	set := sets[value]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(sets, value)
+	}
+}
+
+// reindexLicenses 在修改多条许可证记录后更新索引，数量较多时直接重建，调用方必须持有写锁
+func (s *Store) reindexLicenses(previous map[string]*LicenseRecord) {
+	if len(previous) > rebuildThreshold {
+		s.index = buildLicenseIndex(s.data.Licenses)
+		return
+	}
+	for id, record := range previous {
+		s.index.update(record, s.data.Licenses[id])
+	}
+}
+
+// IndexQuery 按索引查询许可证的参数
+type IndexQuery struct {
+	Sort       string // 排序字段，见 LicenseSortFields
+	Descending bool
+	// CustomerID、Status 为空时不过滤，不为空时只在该客户或状态的许可证中查找
+	CustomerID string
+	Status     string
+	// Match 其余的过滤条件，在读锁内调用，不能修改记录；为 nil 时不过滤
+	Match func(*LicenseRecord) bool
+	// AfterKey、AfterID 上一页最后一条记录的排序键和ID，After 为 false 时从第一条开始
+	After    bool
+	AfterKey string
+	AfterID  string
+	Limit    int
+}
+
+// IndexPage 按索引查询得到的一页许可证
+type IndexPage struct {
+	Licenses []*LicenseRecord
+	Total    int  // 满足条件的许可证总数
+	More     bool // 之后是否还有满足条件的许可证
+}
+
+/*
+ * QueryLicenses 按有序索引过滤、排序并分页获取许可证，不对全部记录排序
+ * 指定客户或状态时先取对应的ID集合：集合较小时只对集合中的记录排序，否则沿排序字段的索引从游标位置向后查找；
+ * Total 只在有 Match 条件时需要逐条检查候选记录
+ * @params: q IndexQuery - 查询参数
+ * @returns: *IndexPage - 一页许可证记录副本
+ *			error - 排序字段不存在时返回错误
+ */
+func (s *Store) QueryLicenses(q IndexQuery) (*IndexPage, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	key, ok := licenseSortKeys[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", q.Sort)
+	}
+	entries := s.index.sorted[q.Sort]
+
+	// candidates 为 nil 表示不限制候选记录
+	var candidates map[string]struct{}
+	for _, set := range []map[string]struct{}{s.customerSet(q.CustomerID), s.statusSet(q.Status)} {
+		if set != nil && (candidates == nil || len(set) < len(candidates)) {
+			candidates = set
+		}
+	}
+	match := func(record *LicenseRecord) bool {
+		return (q.CustomerID == "" || record.CustomerID == q.CustomerID) &&
+			(q.Status == "" || record.EffectiveStatus() == q.Status) &&
+			(q.Match == nil || q.Match(record))
+	}
+
+	page := &IndexPage{Licenses: make([]*LicenseRecord, 0)}
+	page.Total = s.countLicenses(q, candidates, match)
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	// 估算两种方式需要检查的记录数：沿索引查找平均每 len(entries)/len(candidates) 条才有一条属于候选集合
+	if candidates != nil {
+		m := len(candidates)
+		if m*bits.Len(uint(m)) < q.Limit*len(entries)/m {
+			entries = make([]indexEntry, 0, m)
+			for id := range candidates {
+				entries = append(entries, indexEntry{key: key(s.data.Licenses[id]), id: id})
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].before(entries[j].key, entries[j].id)
+			})
+		}
+	}
+
+	// 升序时从第一条排在游标之后的记录开始，降序时从最后一条排在游标之前的记录开始向前
+	next, step, end := 0, 1, len(entries)
+	if q.Descending {
+		next, step, end = len(entries)-1, -1, -1
+	}
+	if q.After {
+		i := search(entries, q.AfterKey, q.AfterID)
+		if q.Descending {
+			next = i - 1
+		} else {
+			if i < len(entries) && entries[i].key == q.AfterKey && entries[i].id == q.AfterID {
+				i++
+			}
+			next = i
+		}
+	}
+	for ; next != end; next += step {
+		record := s.data.Licenses[entries[next].id]
+		if !match(record) {
+			continue
+		}
+		if len(page.Licenses) == q.Limit {
+			page.More = true
+			break
+		}
+		c := *record
+		page.Licenses = append(page.Licenses, &c)
+	}
+	return page, nil
+}
+
+// countLicenses 统计满足条件的许可证数量，没有 Match 条件且最多一个索引条件时直接由ID集合得出
+func (s *Store) countLicenses(q IndexQuery, candidates map[string]struct{}, match func(*LicenseRecord) bool) int {
+	switch {
+	case candidates == nil && q.Match == nil:
+		return len(s.data.Licenses)
+	case candidates == nil:
+		n := 0
+		for _, record := range s.data.Licenses {
+			if match(record) {
+				n++
+			}
+		}
+		return n
+	case q.Match == nil && (q.CustomerID == "" || q.Status == ""):
+		return len(candidates)
+	}
+	n := 0
+	for id := range candidates {
+		if match(s.data.Licenses[id]) {
+			n++
+		}
+	}
+	return n
+}
+
+// customerSet 客户的许可证ID集合，客户ID为空时返回 nil，客户没有许可证时返回空集合
+func (s *Store) customerSet(customerID string) map[string]struct{} {
+	return indexSet(s.index.byCustomer, customerID)
+}
+
+// statusSet 状态的许可证ID集合，状态为空时返回 nil，该状态没有许可证时返回空集合
+func (s *Store) statusSet(status string) map[string]struct{} {
+	return indexSet(s.index.byStatus, status)
+}
+
+func indexSet(sets map[string]map[string]struct{}, value string) map[string]struct{} {
+	if value == "" {
+		return nil
+	}
+	if set, ok := sets[value]; ok {
+		return set
+	}
+	return map[string]struct{}{}
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	// StatusActive 许可证正常
+	StatusActive = "active"
+	// StatusSuspended 许可证已被暂停（例如检测到复制使用），会出现在吊销列表中
+	StatusSuspended = "suspended"
+	// StatusRevoked 许可证已被永久吊销，不能恢复或续期，会出现在吊销列表中
+	StatusRevoked = "revoked"
+)
+
+// ErrLicenseInactive 许可证已被暂停或吊销，UpdateActiveLicense 拒绝修改
+var ErrLicenseInactive = errors.New("license is not active")
+
+// ErrLicenseRevoked 许可证已被吊销，SetLicenseStatus 拒绝修改其状态
+var ErrLicenseRevoked = errors.New("license is revoked")
+
+// LicenseRecord 已签发许可证的持久化记录
+type LicenseRecord struct {
+	ID              string    `json:"id"`
+	LicenseID       string    `json:"license"`
+	Date            time.Time `json:"date"`
+	SignatureCode   string    `json:"signatureCode"`
+	Type            string    `json:"type"`
+	ExpirationDate  time.Time `json:"expiration"`
+	AllowedUsers    uint      `json:"usersNum"`
+	Project         string    `json:"project"`
+	Module          string    `json:"module"`
+	Version         string    `json:"version,omitempty"`
+	Template        string    `json:"template,omitempty"`
+	TemplateVersion int       `json:"templateVersion,omitempty"`
+	CustomerID      string    `json:"customer,omitempty"`
+	Licensee        string    `json:"licensee,omitempty"`
+	Notes           string    `json:"notes,omitempty"`
+	Status          string    `json:"status"`
+	StatusReason    string    `json:"statusReason,omitempty"`
+	StatusChanged   time.Time `json:"statusChanged,omitempty"`
+	// Reinstated 最近一次从非正常状态恢复的时间，复制检测只统计此后的签到
+	Reinstated time.Time `json:"reinstated,omitempty"`
+}
+
+/*
+ * SaveLicense 保存许可证记录，ID 相同时覆盖
+ * @params: record *LicenseRecord - 许可证记录
+ * @returns: error - 写入失败时返回错误
+ */
+func (s *Store) SaveLicense(record *LicenseRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous := s.data.Licenses[record.ID]
+	c := *record
+	s.data.Licenses[record.ID] = &c
+	if err := s.save(); err != nil {
+		if previous == nil {
+			delete(s.data.Licenses, record.ID)
+		} else {
+			s.data.Licenses[record.ID] = previous
+		}
+		return err
+	}
+	s.index.update(previous, &c)
+	return nil
+}
+
+/*
+ * SaveLicenses 在一次写入中保存多条许可证记录，写入失败时内存中的数据恢复原状，不会只保存其中一部分
+ * @params: records []*LicenseRecord - 许可证记录列表
+ * @returns: error - 写入失败时返回错误
+ */
+func (s *Store) SaveLicenses(records []*LicenseRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous := make(map[string]*LicenseRecord, len(records))
+	for _, record := range records {
+		if _, seen := previous[record.ID]; !seen {
+			previous[record.ID] = s.data.Licenses[record.ID]
+		}
+		c := *record
+		s.data.Licenses[record.ID] = &c
+	}
+
+	if err := s.save(); err != nil {
+		for id, record := range previous {
+			if record == nil {
+				delete(s.data.Licenses, id)
+			} else {
+				s.data.Licenses[id] = record
+			}
+		}
+		return err
+	}
+	s.reindexLicenses(previous)
+	return nil
+}
+
+/*
+ * GetLicense 根据许可证ID获取许可证记录
+ * @params: id string - 许可证ID
+ * @returns: *LicenseRecord - 许可证记录的副本
+ *			error - 记录不存在时返回 ErrNotFound
+ */
+func (s *Store) GetLicense(id string) (*LicenseRecord, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, ok := s.data.Licenses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *record
+	return &c, nil
+}
+
+// Active 许可证是否处于正常状态，旧版本保存的记录没有状态，视为正常
+func (r *LicenseRecord) Active() bool {
+	return r.Status == "" || r.Status == StatusActive
+}
+
+// EffectiveStatus 许可证状态，旧版本保存的记录没有状态时为 StatusActive
+func (r *LicenseRecord) EffectiveStatus() string {
+	if r.Status == "" {
+		return StatusActive
+	}
+	return r.Status
+}
+
+/*
+ * UpdateLicense 在写锁内读取并修改许可证记录（比较并更新），避免先读取再整体覆盖时丢失并发的修改
+ * update 修改的是记录的副本，返回错误时不做任何修改；写入失败时内存中的记录保持原状
+ * @params: id string - 许可证ID
+ *			update func(*LicenseRecord) error - 修改记录副本的函数，在写锁内调用
+ * @returns: *LicenseRecord - 更新后的许可证记录副本
+ *			error - 记录不存在时返回 ErrNotFound，update 返回错误或写入失败时返回该错误
+ */
+func (s *Store) UpdateLicense(id string, update func(*LicenseRecord) error) (*LicenseRecord, error) {
+	return s.updateLicense(id, false, update)
+}
+
+/*
+ * UpdateActiveLicense 与 UpdateLicense 相同，但拒绝修改已被暂停或吊销的许可证
+ * @params: id string - 许可证ID
+ *			update func(*LicenseRecord) error - 修改记录副本的函数，在写锁内调用
+ * @returns: *LicenseRecord - 更新后的许可证记录副本
+ *			error - 记录不存在时返回 ErrNotFound，许可证不是正常状态时返回 ErrLicenseInactive
+ */
+func (s *Store) UpdateActiveLicense(id string, update func(*LicenseRecord) error) (*LicenseRecord, error) {
+	return s.updateLicense(id, true, update)
+}
+
+func (s *Store) updateLicense(id string, activeOnly bool, update func(*LicenseRecord) error) (*LicenseRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, ok := s.data.Licenses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if activeOnly && !previous.Active() {
+		return nil, ErrLicenseInactive
+	}
+
+	record := *previous
+	if err := update(&record); err != nil {
+		return nil, err
+	}
+	s.data.Licenses[id] = &record
+	if err := s.save(); err != nil {
+		s.data.Licenses[id] = previous
+		return nil, err
+	}
+	s.index.update(previous, &record)
+	c := record
+	return &c, nil
+}
+
+/*
+ * SetLicenseStatus 修改许可证状态，从非正常状态恢复为 StatusActive 时同时记录恢复时间；吊销是最终状态，之后不能再修改
+ * @params: id string - 许可证ID
+ *			status string - 新状态，StatusActive、StatusSuspended 或 StatusRevoked
+ *			reason string - 状态变更原因
+ * @returns: *LicenseRecord - 更新后的许可证记录副本
+ *			error - 记录不存在时返回 ErrNotFound，许可证已被吊销时返回 ErrLicenseRevoked
+ */
+func (s *Store) SetLicenseStatus(id string, status string, reason string) (*LicenseRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.data.Licenses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if record.Status == StatusRevoked {
+		return nil, ErrLicenseRevoked
+	}
+	previous := *record
+	record.Status = status
+	record.StatusReason = reason
+	record.StatusChanged = time.Now().UTC()
+	if status == StatusActive && previous.Status != "" && previous.Status != StatusActive {
+		record.Reinstated = record.StatusChanged
+	}
+
+	if err := s.save(); err != nil {
+		*record = previous
+		return nil, err
+	}
+	s.index.update(&previous, record)
+	c := *record
+	return &c, nil
+}
+
+/*
+ * SetLicenseNotes 修改许可证备注
+ * @params: id string - 许可证ID
+ *			notes string - 新备注
+ * @returns: *LicenseRecord - 更新后的许可证记录副本
+ *			error - 记录不存在时返回 ErrNotFound
+ */
+func (s *Store) SetLicenseNotes(id string, notes string) (*LicenseRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.data.Licenses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	previous := record.Notes
+	record.Notes = notes
+
+	if err := s.save(); err != nil {
+		record.Notes = previous
+		return nil, err
+	}
+	c := *record
+	return &c, nil
+}
+
+/*
+ * ListRevokedLicenses 获取所有非正常状态的许可证，按状态变更时间排列
+ * @returns: []*LicenseRecord - 许可证记录副本列表
+ */
+func (s *Store) ListRevokedLicenses() []*LicenseRecord {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*LicenseRecord, 0)
+	for _, record := range s.data.Licenses {
+		if record.Status != "" && record.Status != StatusActive {
+			c := *record
+			list = append(list, &c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StatusChanged.Before(list[j].StatusChanged)
+	})
+	return list
+}
+
+/*
+ * ListLicenses 获取全部许可证，按签发时间排列
+ * @returns: []*LicenseRecord - 许可证记录副本列表
+ */
+func (s *Store) ListLicenses() []*LicenseRecord {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*LicenseRecord, 0, len(s.data.Licenses))
+	for _, e := range s.index.sorted["date"] {
+		c := *s.data.Licenses[e.id]
+		list = append(list, &c)
+	}
+	return list
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrDuplicateProduct 产品ID重复
+var ErrDuplicateProduct = errors.New("product already exists")
+
+// Product 产品目录中的产品，ID 即许可证中的项目名称（project）
+type Product struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Versions    []string  `json:"versions,omitempty"`
+	Features    []string  `json:"features"`
+	Editions    []Edition `json:"editions"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Edition 产品版本（授权类型），ID 即许可证中的类型（type），签发时未指定的授权信息取版本的默认值
+type Edition struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name,omitempty"`
+	Features     []string `json:"features"`
+	DurationDays uint     `json:"durationDays"`
+	Seats        uint     `json:"seats"`
+}
+
+// Edition 根据ID查找产品版本
+func (p *Product) Edition(id string) (*Edition, bool) {
+	for i := range p.Editions {
+		if p.Editions[i].ID == id {
+			return &p.Editions[i], true
+		}
+	}
+	return nil, false
+}
+
+// copy 深拷贝产品记录，避免调用方修改存储中的切片
+func (p *Product) copy() *Product {
+	c := *p
+	c.Versions = append([]string(nil), p.Versions...)
+	c.Features = append([]string(nil), p.Features...)
+	c.Editions = make([]Edition, len(p.Editions))
+	for i, edition := range p.Editions {
+		edition.Features = append([]string(nil), edition.Features...)
+		c.Editions[i] = edition
+	}
+	return &c
+}
+
+/*
+ * CreateProduct 保存新的产品
+ * @params: product *Product - 产品记录，ID 必须唯一
+ * @returns: error - ID 重复时返回 ErrDuplicateProduct，写入失败时返回错误
+ */
+func (s *Store) CreateProduct(product *Product) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.Products[product.ID]; ok {
+		return ErrDuplicateProduct
+	}
+	s.data.Products[product.ID] = product.copy()
+	return s.save()
+}
+
+/*
+ * UpdateProduct 覆盖已有的产品，创建时间保持不变
+ * @params: product *Product - 产品记录
+ * @returns: error - 不存在时返回 ErrNotFound，写入失败时返回错误
+ */
+func (s *Store) UpdateProduct(product *Product) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, ok := s.data.Products[product.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	c := product.copy()
+	c.Created = existing.Created
+	s.data.Products[product.ID] = c
+	return s.save()
+}
+
+/*
+ * GetProduct 根据ID获取产品
+ * @params: id string - 产品ID
+ * @returns: *Product - 产品记录副本
+ *			error - 不存在时返回 ErrNotFound
+ */
+func (s *Store) GetProduct(id string) (*Product, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	product, ok := s.data.Products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return product.copy(), nil
+}
+
+/*
+ * DeleteProduct 删除产品，已签发的许可证不受影响
+ * @params: id string - 产品ID
+ * @returns: error - 不存在时返回 ErrNotFound，写入失败时返回错误
+ */
+func (s *Store) DeleteProduct(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.Products[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.data.Products, id)
+	return s.save()
+}
+
+/*
+ * ListProducts 获取全部产品，按ID排列
+ * @returns: []*Product - 产品记录副本列表
+ */
+func (s *Store) ListProducts() []*Product {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*Product, 0, len(s.data.Products))
+	for _, product := range s.data.Products {
+		list = append(list, product.copy())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// HasProducts 判断产品目录是否非空
+func (s *Store) HasProducts() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.data.Products) > 0
+}
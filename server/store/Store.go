@@ -0,0 +1,176 @@
+/*
+ * Package store 提供许可证服务端的持久化存储
+ * Store - 以 JSON 文件形式保存许可证记录、告警记录、API 密钥、产品目录、签发模板和客户，客户端签到追加到单独的签到日志，所有操作均为并发安全
+ * 许可证记录在内存中按每个排序字段以及客户、状态建立索引，查询一页时不需要对全部记录排序
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/metrics"
+	"sync"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("record not found")
+
+// data 存储文件中保存的全部数据
+type data struct {
+	Licenses map[string]*LicenseRecord `json:"licenses"`
+	// Checkins 和 History 只用于读取旧版本保存在存储文件中的签到数据，打开时迁移到签到日志
+	Checkins  map[string]*LicenseUsage `json:"checkins,omitempty"`
+	History   map[string][]Checkin     `json:"history,omitempty"`
+	Alerts    []*Alert                 `json:"alerts"`
+	APIKeys   map[string]*APIKey       `json:"apiKeys"`
+	Products  map[string]*Product      `json:"products"`
+	Templates map[string][]*Template   `json:"templates"`
+	Customers map[string]*Customer     `json:"customers"`
+}
+
+// Store 基于 JSON 文件的存储
+type Store struct {
+	path  string
+	lock  *os.File // <path>.lock 上的排他锁，见 acquireLock
+	mutex sync.RWMutex
+	data  data
+	index *licenseIndex
+
+	// usage 和 history 是回放签到日志得到的签到汇总和签到历史
+	usage            map[string]*LicenseUsage
+	history          map[string][]Checkin
+	checkinLog       *os.File
+	checkinSize      int64
+	checkinLines     int
+	checkinCompactAt int
+}
+
+var (
+	defaultMutex sync.RWMutex
+	defaultStore *Store
+)
+
+/*
+ * Open 打开指定路径的存储文件，文件不存在时创建空存储，并回放同目录下的签到日志
+ * 打开期间持有 <path>.lock 上的排他锁，运行中的服务器和离线的 licensectl 不能同时打开同一个存储
+ * @params: path string - 存储文件路径
+ * @returns: *Store - 存储实例
+ *			error - 已被其他进程打开时返回 ErrLocked，读取或解析失败时返回错误
+ */
+func Open(path string) (*Store, error) {
+	lock, err := acquireLock(path)
+	if err != nil {
+		metrics.StoreErrors.Inc("open")
+		return nil, err
+	}
+
+	s := &Store{
+		path:             path,
+		lock:             lock,
+		usage:            make(map[string]*LicenseUsage),
+		history:          make(map[string][]Checkin),
+		checkinCompactAt: minCompactLines,
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		_ = lock.Close()
+		metrics.StoreErrors.Inc("open")
+		return nil, err
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &s.data); err != nil {
+			_ = lock.Close()
+			metrics.StoreErrors.Inc("open")
+			return nil, err
+		}
+	}
+
+	if s.data.Licenses == nil {
+		s.data.Licenses = make(map[string]*LicenseRecord)
+	}
+	s.index = buildLicenseIndex(s.data.Licenses)
+	if s.data.APIKeys == nil {
+		s.data.APIKeys = make(map[string]*APIKey)
+	}
+	// 密钥哈希只作为映射的键保存，避免通过接口响应泄露
+	for hash, key := range s.data.APIKeys {
+		key.Hash = hash
+		key.migrateRole()
+	}
+	if s.data.Products == nil {
+		s.data.Products = make(map[string]*Product)
+	}
+	if s.data.Templates == nil {
+		s.data.Templates = make(map[string][]*Template)
+	}
+	if s.data.Customers == nil {
+		s.data.Customers = make(map[string]*Customer)
+	}
+	if err := s.openCheckinLog(); err != nil {
+		_ = lock.Close()
+		metrics.StoreErrors.Inc("open")
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetDefault 设置全局默认存储
+func SetDefault(s *Store) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	defaultStore = s
+}
+
+// Default 获取全局默认存储，未设置时返回 nil
+func Default() *Store {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+
+	return defaultStore
+}
+
+/*
+ * save 将数据写入临时文件后原子替换存储文件，调用方必须持有写锁
+ * @returns: error - 写入失败时返回错误
+ */
+func (s *Store) save() error {
+	if err := s.write(); err != nil {
+		metrics.StoreErrors.Inc("save")
+		return err
+	}
+	return nil
+}
+
+func (s *Store) write() error {
+	content, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked 存储已被另一个进程（运行中的服务器或离线的 licensectl）打开
+var ErrLocked = errors.New("license store is in use by another process")
+
+/*
+ * acquireLock 对存储文件旁的 <path>.lock 加排他锁，存储、签到日志和审计日志同一时间只允许一个进程写入
+ * @params: path string - 存储文件路径
+ * @returns: *os.File - 锁文件，进程退出前保持打开
+ *			error - 已被其他进程锁定时返回 ErrLocked
+ */
+func acquireLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package store
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile 以非阻塞方式对文件加排他锁，进程退出时操作系统自动释放，不会留下失效的锁
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return ErrLocked
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package store
+
+import "os"
+
+// lockFile 该平台不支持 flock，不加锁，由使用者保证服务器运行时不离线修改存储
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrDuplicateTemplate 模板名称重复
+var ErrDuplicateTemplate = errors.New("template already exists")
+
+// Template 签发模板的一个版本，修改模板时追加新版本，旧版本保留供已签发的许可证追溯
+type Template struct {
+	Name         string    `json:"name"`
+	Version      int       `json:"version"`
+	Description  string    `json:"description,omitempty"`
+	Type         string    `json:"type,omitempty"`
+	DurationDays uint      `json:"durationDays,omitempty"`
+	AllowedUsers uint      `json:"usersNum,omitempty"`
+	Project      string    `json:"project,omitempty"`
+	Module       string    `json:"module,omitempty"`
+	Features     []string  `json:"features,omitempty"`
+	Retired      bool      `json:"retired,omitempty"`
+	Created      time.Time `json:"created"`
+	CreatedBy    string    `json:"createdBy,omitempty"`
+}
+
+// copy 深拷贝模板，避免调用方修改存储中的切片
+func (t *Template) copy() *Template {
+	c := *t
+	c.Features = append([]string(nil), t.Features...)
+	return &c
+}
+
+/*
+ * AddTemplateVersion 为模板追加新版本，版本号自动递增
+ * @params: template *Template - 模板内容，Version 被忽略
+ *			create bool - 为 true 时要求模板不存在，为 false 时要求模板已存在
+ * @returns: *Template - 保存后的模板副本
+ *			error - create 为 true 且模板已存在时返回 ErrDuplicateTemplate，
+ *				create 为 false 且模板不存在时返回 ErrNotFound，写入失败时返回错误
+ */
+func (s *Store) AddTemplateVersion(template *Template, create bool) (*Template, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	versions := s.data.Templates[template.Name]
+	switch {
+	case create && len(versions) > 0:
+		return nil, ErrDuplicateTemplate
+	case !create && len(versions) == 0:
+		return nil, ErrNotFound
+	}
+
+	c := template.copy()
+	c.Version = len(versions) + 1
+	s.data.Templates[template.Name] = append(versions, c)
+	if err := s.save(); err != nil {
+		s.data.Templates[template.Name] = versions
+		return nil, err
+	}
+	return c.copy(), nil
+}
+
+/*
+ * GetTemplate 获取模板的指定版本
+ * @params: name string - 模板名称
+ *			version int - 版本号，0 表示最新版本
+ * @returns: *Template - 模板副本
+ *			error - 模板或版本不存在时返回 ErrNotFound
+ */
+func (s *Store) GetTemplate(name string, version int) (*Template, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	versions := s.data.Templates[name]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, ErrNotFound
+	}
+	return versions[version-1].copy(), nil
+}
+
+/*
+ * TemplateVersions 获取模板的全部版本，按版本号排列
+ * @params: name string - 模板名称
+ * @returns: []*Template - 模板副本列表
+ *			error - 模板不存在时返回 ErrNotFound
+ */
+func (s *Store) TemplateVersions(name string) ([]*Template, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	versions, ok := s.data.Templates[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	list := make([]*Template, 0, len(versions))
+	for _, t := range versions {
+		list = append(list, t.copy())
+	}
+	return list, nil
+}
+
+/*
+ * ListTemplates 获取每个模板的最新版本，按名称排列
+ * @returns: []*Template - 模板副本列表
+ */
+func (s *Store) ListTemplates() []*Template {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]*Template, 0, len(s.data.Templates))
+	for _, versions := range s.data.Templates {
+		if len(versions) > 0 {
+			list = append(list, versions[len(versions)-1].copy())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
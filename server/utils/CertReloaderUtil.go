@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync"
+)
+
+// CertReloader 持有当前使用的服务端证书和客户端 CA，支持在运行期间重新加载
+type CertReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+}
+
+/*
+ * NewCertReloader 加载证书并创建证书重载器
+ * @params: certFile string - 服务端证书文件（PEM）
+ *			keyFile string - 服务端私钥文件（PEM）
+ *			clientCAFile string - 校验客户端证书的 CA 文件（PEM），为空时不启用双向 TLS
+ *			requireClientCert bool - 启用双向 TLS 时是否要求所有客户端提供证书
+ * @returns: *CertReloader - 证书重载器
+ *			error - 证书无法加载时返回错误
+ */
+func NewCertReloader(certFile string, keyFile string, clientCAFile string, requireClientCert bool) (*CertReloader, error) {
+	c := &CertReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		clientAuth:   tls.NoClientCert,
+	}
+	if clientCAFile != "" {
+		c.clientAuth = tls.VerifyClientCertIfGiven
+		if requireClientCert {
+			c.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload 重新读取证书、私钥和客户端 CA，读取失败时继续使用原有证书
+func (c *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if c.clientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.clientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in client CA file " + c.clientCAFile)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.cert = &cert
+	c.clientCA = pool
+	return nil
+}
+
+/*
+ * TLSConfig 返回服务端使用的 TLS 配置，每次握手都会读取最新加载的证书和客户端 CA
+ * 握手使用的配置由返回的配置克隆而来，只替换证书和客户端校验相关的字段，因此 ALPN（NextProtos，HTTP/2 依赖它）
+ * 以及会话票据等其他设置保持不变；返回的配置预先声明 h2 和 http/1.1，与 http.Server 为 TLS 添加的协议一致
+ * @returns: *tls.Config - TLS 配置
+ */
+func (c *CertReloader) TLSConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c.mutex.RLock()
+		defer c.mutex.RUnlock()
+
+		// 克隆的配置没有设置会话票据密钥，握手时沿用 base 自动轮换的密钥，会话可以跨连接恢复
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.Certificates = []tls.Certificate{*c.cert}
+		config.ClientAuth = c.clientAuth
+		config.ClientCAs = c.clientCA
+		return config, nil
+	}
+	return base
+}
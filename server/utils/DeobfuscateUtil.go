@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+/* DeobfuscationUtil 对 ObfuscationUtil 生成的密文进行逆向操作，得到原始明文数据
+ * @params: obfuscatedStr: 待解密的密文字符串
+ * 			signatureCode: 用于加密的机器特征码，必须非空
+ * @return: []byte: 明文数据，有效内容之后为填充字节
+ * 			error: 特征码为空或密文不是合法的 base64 时返回错误对象；否则为 nil
+ */
+func DeobfuscationUtil(obfuscatedStr string, signatureCode string) ([]byte, error) {
+
+	if signatureCode == "" {
+		return nil, errors.New("signature code is empty")
+	}
+
+	scBytes := []byte(signatureCode)
+
+	obfBytes, err := base64.URLEncoding.DecodeString(obfuscatedStr)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, len(obfBytes))
+	for i := 0; i < len(obfBytes); i++ {
+		output[i] = obfBytes[i] ^ scBytes[i%len(scBytes)]
+	}
+	return output, nil
+}
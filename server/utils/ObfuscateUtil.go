@@ -1,12 +1,15 @@
 package utils
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"errors"
-	"math/rand"
-	"unicode"
 )
 
+// paddingAlphabet 填充字节的取值范围。填充不经过异或，不包含任何特征码信息；
+// 若填充为 0，明文结尾之后的密文就是特征码本身，可以直接从文件中读出
+const paddingAlphabet = "01ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
 func ObfuscationUtil(input []byte, signatureCode string) (string, error) {
 
 	const targetLen = 4096
@@ -15,16 +18,18 @@ func ObfuscationUtil(input []byte, signatureCode string) (string, error) {
 		return "", errors.New("signature code is empty")
 	}
 
+	padding := make([]byte, targetLen)
+	if _, err := rand.Read(padding); err != nil {
+		return "", err
+	}
+
 	scBytes := []byte(signatureCode)
 	ciphertext := make([]byte, targetLen)
 	for i := 0; i < targetLen; i++ {
 		if i < len(input) {
 			ciphertext[i] = input[i] ^ scBytes[i%len(scBytes)]
 		} else {
-			randByte := byte(rand.Intn(256))
-			for randByte != '0' && randByte != '1' && !unicode.IsLetter(rune(randByte)) {
-				randByte = byte(rand.Intn(256))
-			}
+			ciphertext[i] = paddingAlphabet[int(padding[i])%len(paddingAlphabet)]
 		}
 	}
 
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+/*
+ * RandomToken 使用 crypto/rand 生成由指定字符组成的随机字符串，用作签到凭据等不可预测的令牌
+ * 随机字节超出字符表整数倍的部分会被丢弃，每个字符出现的概率相同
+ * @params: alphabet string - 字符表，长度为 1 到 256
+ *			length int - 令牌长度
+ * @returns: string - 随机令牌
+ *			error - 字符表无效或随机数生成失败时返回错误
+ */
+func RandomToken(alphabet string, length int) (string, error) {
+	if len(alphabet) == 0 || len(alphabet) > 256 {
+		return "", errors.New("random token alphabet must contain 1 to 256 characters")
+	}
+	limit := 256 - 256%len(alphabet)
+
+	token := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(token) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(token) < length {
+				token = append(token, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+	return string(token), nil
+}
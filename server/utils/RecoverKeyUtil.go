@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// maxSignatureCodeLen 恢复特征码时尝试的最大长度，客户端生成的机器码为 32 位
+const maxSignatureCodeLen = 64
+
+// maxHeaderLen 查找特征码字段的范围：ID、许可证编码、签发日期和特征码字段都在许可文件的前 512 字节中
+const maxHeaderLen = 512
+
+var (
+	// 许可文件内容固定以授权信息开头，特征码字段之后是类型字段
+	licensePrefix    = []byte(`{"authorized":{"id":"`)
+	signatureField   = []byte(`"signatureCode":"`)
+	signatureTrailer = []byte(`","type":"`)
+)
+
+/* RecoverSignatureCode 在不知道机器特征码的情况下，从 ObfuscationUtil 生成的许可文件中恢复特征码
+ * 混淆使用特征码循环异或，而明文的开头是固定内容，且明文中包含特征码本身，
+ * 因此可以按已知明文逐位推出特征码，再用完整解码的结果确认
+ * @params: obfuscatedStr: 许可文件内容
+ * @return: string: 签发时使用的机器特征码
+ * 			error: 内容不是合法的 base64 或不是由服务端生成的许可文件时返回错误对象；否则为 nil
+ */
+func RecoverSignatureCode(obfuscatedStr string) (string, error) {
+
+	obfBytes, err := base64.URLEncoding.DecodeString(obfuscatedStr)
+	if err != nil {
+		return "", err
+	}
+
+	// 特征码字段紧跟在ID、许可证编码和签发日期之后，只需在文件开头查找；
+	// 早期许可文件的填充字节为 0，去掉之后即为明文的长度
+	cipher := bytes.TrimRight(obfBytes, "\x00")
+	if len(cipher) < len(licensePrefix) {
+		return "", errors.New("license file is empty or truncated")
+	}
+	if len(cipher) > maxHeaderLen {
+		cipher = cipher[:maxHeaderLen]
+	}
+	n := len(cipher)
+
+	for keyLen := 1; keyLen <= maxSignatureCodeLen; keyLen++ {
+		base := newKeyGuess(cipher, keyLen)
+		if !base.assign(0, licensePrefix) {
+			continue
+		}
+		for start := len(licensePrefix) + len(signatureField); start+keyLen+len(signatureTrailer) <= n; start++ {
+			guess := base.clone()
+			if !guess.assign(start-len(signatureField), signatureField) ||
+				!guess.assign(start+keyLen, signatureTrailer) ||
+				!guess.propagate(start) {
+				continue
+			}
+			if key, ok := guess.verify(obfuscatedStr); ok {
+				return key, nil
+			}
+		}
+	}
+	return "", errors.New("signature code could not be recovered, the file was not generated by this license server")
+}
+
+// keyGuess 指定长度的特征码中已经推出的字节
+type keyGuess struct {
+	cipher []byte
+	key    []byte
+	known  []bool
+}
+
+func newKeyGuess(cipher []byte, keyLen int) *keyGuess {
+	return &keyGuess{cipher: cipher, key: make([]byte, keyLen), known: make([]bool, keyLen)}
+}
+
+func (g *keyGuess) clone() *keyGuess {
+	c := &keyGuess{cipher: g.cipher, key: make([]byte, len(g.key)), known: make([]bool, len(g.known))}
+	copy(c.key, g.key)
+	copy(c.known, g.known)
+	return c
+}
+
+// set 记录特征码第 i 位的值，与已知值冲突时返回 false
+func (g *keyGuess) set(i int, b byte) bool {
+	if g.known[i] {
+		return g.key[i] == b
+	}
+	g.key[i], g.known[i] = b, true
+	return true
+}
+
+// assign 已知从 pos 开始的明文为 plain，推出对应位置的特征码字节
+func (g *keyGuess) assign(pos int, plain []byte) bool {
+	for i, p := range plain {
+		if pos+i >= len(g.cipher) || !g.set((pos+i)%len(g.key), g.cipher[pos+i]^p) {
+			return false
+		}
+	}
+	return true
+}
+
+// propagate 特征码本身出现在 start 处的明文中：第 t 位明文等于 key[t]，由此互相推出其余字节，全部推出时返回 true
+func (g *keyGuess) propagate(start int) bool {
+	keyLen := len(g.key)
+	for changed := true; changed; {
+		changed = false
+		for t := 0; t < keyLen; t++ {
+			c, k := g.cipher[start+t], (start+t)%keyLen
+			switch {
+			case g.known[t] && g.known[k]:
+				if g.key[t]^g.key[k] != c {
+					return false
+				}
+			case g.known[t]:
+				g.set(k, c^g.key[t])
+				changed = true
+			case g.known[k]:
+				g.set(t, c^g.key[k])
+				changed = true
+			}
+		}
+	}
+	for _, ok := range g.known {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// verify 使用推出的特征码完整解码，确认明文中的特征码与之相同
+func (g *keyGuess) verify(obfuscatedStr string) (string, bool) {
+	key := string(g.key)
+	plain, err := DeobfuscationUtil(obfuscatedStr, key)
+	if err != nil {
+		return "", false
+	}
+	var msg struct {
+		Authorized struct {
+			SignatureCode string `json:"signatureCode"`
+		} `json:"authorized"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(plain)).Decode(&msg); err != nil {
+		return "", false
+	}
+	return key, msg.Authorized.SignatureCode == key
+}
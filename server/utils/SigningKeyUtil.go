@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+/*
+ * GenerateSigningKey 生成用于签名许可文件的 Ed25519 密钥对
+ * @returns: ed25519.PrivateKey - 私钥，公钥可通过 Public() 获得
+ *			error - 随机数生成失败时返回错误
+ */
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	return priv, err
+}
+
+/*
+ * LoadSigningKey 读取 PEM 格式（PKCS#8，"PRIVATE KEY"）的 Ed25519 私钥文件
+ * @params: path string - 私钥文件路径
+ * @returns: ed25519.PrivateKey - 私钥
+ *			error - 文件不存在、格式错误或不是 Ed25519 私钥时返回错误
+ */
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New(path + ": not a PEM encoded private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New(path + ": not an Ed25519 private key")
+	}
+	return priv, nil
+}
+
+/*
+ * WriteSigningKey 以 PEM 格式（PKCS#8）写入私钥文件，权限为 0600，文件已存在时返回错误而不覆盖
+ * @params: path string - 私钥文件路径
+ *			key ed25519.PrivateKey - 私钥
+ * @returns: error - 文件已存在或写入失败时返回错误
+ */
+func WriteSigningKey(path string, key ed25519.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+/*
+ * EncodePublicKey 将公钥编码为标准 base64 字符串，客户端配置 verification.public_keys 使用该格式
+ * @params: key ed25519.PublicKey - 公钥
+ * @returns: string - base64 编码的 32 字节公钥
+ */
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+/*
+ * SigningKeyID 计算公钥的密钥ID：sha256(公钥) 的前 8 个字节（16 位十六进制），写入许可文件以便客户端选择公钥
+ * @params: key ed25519.PublicKey - 公钥
+ * @returns: string - 密钥ID
+ */
+func SigningKeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}